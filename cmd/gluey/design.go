@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// runDesignProgram runs a program against the design of the Gluey project in
+// the current directory. The program is built in a temporary module named
+// after name, which requires the project and Gluey. source is its main.go,
+// formatted with the module path of the project, and env is added to its
+// environment. Its output is written to stdout and stderr.
+func runDesignProgram(name, source string, env []string, stdout, stderr io.Writer) error {
+	// Check if design/app.go exists
+	designFile := filepath.Join("design", "app.go")
+	if _, err := os.Stat(designFile); os.IsNotExist(err) {
+		return fmt.Errorf("%s not found - make sure you're in a Gluey project directory", designFile)
+	}
+
+	// Read go.mod to get the module name
+	goModContent, err := os.ReadFile("go.mod")
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	modFile, err := modfile.Parse("go.mod", goModContent, nil)
+	if err != nil {
+		return fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+	moduleName := modFile.Module.Mod.Path
+
+	// Get current directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Create a temporary directory for the program
+	tmpDir, err := os.MkdirTemp("", "gluey-"+name+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	// Create a temporary main.go that imports and executes the design
+	mainFile := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(mainFile, []byte(fmt.Sprintf(source, moduleName)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s main.go: %w", name, err)
+	}
+
+	// Create a go.mod for the temp directory
+	tmpGoModContent := fmt.Sprintf(`module gluey-%s
+
+go %s
+
+require (
+	%s v0.0.0
+	github.com/gobijan/gluey v0.0.0
+)
+
+replace %s => %s
+replace github.com/gobijan/gluey => %s
+`,
+		name,
+		strings.TrimPrefix(modFile.Go.Version, "go"),
+		moduleName,
+		moduleName, cwd,
+		getGlueyPath())
+
+	tmpGoMod := filepath.Join(tmpDir, "go.mod")
+	if err := os.WriteFile(tmpGoMod, []byte(tmpGoModContent), 0644); err != nil {
+		return fmt.Errorf("failed to write temp go.mod: %w", err)
+	}
+
+	// Run go mod tidy in the temp directory to fetch dependencies
+	tidyCmd := exec.Command("go", "mod", "tidy")
+	tidyCmd.Dir = tmpDir
+	if output, err := tidyCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run go mod tidy: %w\nOutput: %s", err, output)
+	}
+
+	// Run the program
+	cmd := exec.Command("go", "run", mainFile)
+	cmd.Dir = tmpDir // Run in the temp directory
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// getGlueyPath returns the path to the Gluey module.
+func getGlueyPath() string {
+	// First, check if we're in the Gluey repo itself
+	if _, err := os.Stat(filepath.Join(".", "go.mod")); err == nil {
+		if content, err := os.ReadFile("go.mod"); err == nil {
+			if strings.Contains(string(content), "module github.com/gobijan/gluey") {
+				cwd, _ := os.Getwd()
+				return cwd
+			}
+		}
+	}
+
+	// Check parent directories (for examples)
+	for i := 1; i <= 3; i++ {
+		parentPath := strings.Repeat("../", i)
+		goModPath := filepath.Join(parentPath, "go.mod")
+		if _, err := os.Stat(goModPath); err == nil {
+			if content, err := os.ReadFile(goModPath); err == nil {
+				if strings.Contains(string(content), "module github.com/gobijan/gluey") {
+					abs, _ := filepath.Abs(parentPath)
+					return abs
+				}
+			}
+		}
+	}
+
+	// Default: assume it's available as a module
+	return ""
+}
@@ -3,11 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
-
-	"golang.org/x/mod/modfile"
 )
 
 const glueyVersion = "0.1.0"
@@ -16,41 +12,28 @@ const glueyVersion = "0.1.0"
 func runGenerateImpl() error {
 	fmt.Println("🔨 Generating interfaces and contracts from DSL...")
 
-	// Check if design/app.go exists
-	designFile := filepath.Join("design", "app.go")
-	if _, err := os.Stat(designFile); os.IsNotExist(err) {
-		return fmt.Errorf("%s not found - make sure you're in a Gluey project directory", designFile)
-	}
-
-	// Read go.mod to get the module name
-	goModContent, err := os.ReadFile("go.mod")
-	if err != nil {
-		return fmt.Errorf("failed to read go.mod: %w", err)
-	}
-
-	modFile, err := modfile.Parse("go.mod", goModContent, nil)
-	if err != nil {
-		return fmt.Errorf("failed to parse go.mod: %w", err)
-	}
-	moduleName := modFile.Module.Mod.Path
-
 	// Get current directory
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	// Create a temporary directory for the generator
-	tmpDir, err := os.MkdirTemp("", "gluey-gen-*")
+	// Run the generator
+	err = runDesignProgram("generator", generatorProgram, []string{
+		"GLUEY_OUTPUT=" + cwd,
+		"GLUEY_VERSION=" + glueyVersion,
+		"GLUEY_COMMAND=gluey gen " + strings.Join(os.Args[2:], " "),
+	}, os.Stdout, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return fmt.Errorf("generation failed: %w", err)
 	}
-	defer func() {
-		_ = os.RemoveAll(tmpDir)
-	}()
 
-	// Create a temporary main.go that imports and executes the design
-	mainContent := fmt.Sprintf(`package main
+	return nil
+}
+
+// generatorProgram is the main.go of runDesignProgram that generates the
+// interfaces of the design.
+const generatorProgram = `package main
 
 import (
 	"fmt"
@@ -74,6 +57,9 @@ func main() {
 	if expr.Root == nil {
 		log.Fatal("No WebApp found in design")
 	}
+	if err := codegen.Validate(expr.Root); err != nil {
+		log.Fatal("invalid design:", err)
+	}
 	
 	// Get output directory from environment
 	outDir := os.Getenv("GLUEY_OUTPUT")
@@ -92,90 +78,4 @@ func main() {
 	fmt.Println("✅ Interface generation complete!")
 	fmt.Println("\nGenerated files in", filepath.Join(outDir, "gen"))
 }
-`, moduleName)
-
-	mainFile := filepath.Join(tmpDir, "main.go")
-	if err := os.WriteFile(mainFile, []byte(mainContent), 0644); err != nil {
-		return fmt.Errorf("failed to write generator main.go: %w", err)
-	}
-
-	// Create a go.mod for the temp directory
-	tmpGoModContent := fmt.Sprintf(`module gluey-generator
-
-go %s
-
-require (
-	%s v0.0.0
-	github.com/gobijan/gluey v0.0.0
-)
-
-replace %s => %s
-replace github.com/gobijan/gluey => %s
-`,
-		strings.TrimPrefix(modFile.Go.Version, "go"),
-		moduleName,
-		moduleName, cwd,
-		getGlueyPath())
-
-	tmpGoMod := filepath.Join(tmpDir, "go.mod")
-	if err := os.WriteFile(tmpGoMod, []byte(tmpGoModContent), 0644); err != nil {
-		return fmt.Errorf("failed to write temp go.mod: %w", err)
-	}
-
-	// Run go mod tidy in the temp directory to fetch dependencies
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = tmpDir
-	if output, err := tidyCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run go mod tidy: %w\nOutput: %s", err, output)
-	}
-
-	// Run the generator
-	cmd := exec.Command("go", "run", mainFile)
-	cmd.Dir = tmpDir // Run in the temp directory
-	// Pass environment variables
-	cmd.Env = append(os.Environ(),
-		"GLUEY_OUTPUT="+cwd,
-		"GLUEY_VERSION="+glueyVersion,
-		"GLUEY_COMMAND=gluey gen "+strings.Join(os.Args[2:], " "))
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Println(string(output))
-		return fmt.Errorf("generation failed: %w", err)
-	}
-	if len(output) > 0 {
-		fmt.Println(string(output))
-	}
-
-	return nil
-}
-
-// getGlueyPath returns the path to the Gluey module.
-func getGlueyPath() string {
-	// First, check if we're in the Gluey repo itself
-	if _, err := os.Stat(filepath.Join(".", "go.mod")); err == nil {
-		if content, err := os.ReadFile("go.mod"); err == nil {
-			if strings.Contains(string(content), "module github.com/gobijan/gluey") {
-				cwd, _ := os.Getwd()
-				return cwd
-			}
-		}
-	}
-
-	// Check parent directories (for examples)
-	for i := 1; i <= 3; i++ {
-		parentPath := strings.Repeat("../", i)
-		goModPath := filepath.Join(parentPath, "go.mod")
-		if _, err := os.Stat(goModPath); err == nil {
-			if content, err := os.ReadFile(goModPath); err == nil {
-				if strings.Contains(string(content), "module github.com/gobijan/gluey") {
-					abs, _ := filepath.Abs(parentPath)
-					return abs
-				}
-			}
-		}
-	}
-
-	// Default: assume it's available as a module
-	return ""
-}
+`
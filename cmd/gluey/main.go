@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,6 +24,8 @@ func main() {
 		runGenerate()
 	case "example":
 		runExampleCommand()
+	case "routes":
+		runRoutesCommand()
 	case "new":
 		if len(os.Args) < 3 {
 			fmt.Println("Error: 'new' command requires a project name")
@@ -51,6 +55,9 @@ Commands:
                 Options: --local  Use local gluey source (for development)
   gen           Generate interfaces and contracts from DSL (alias: generate)
   example       Generate example implementation (only creates new files)
+  routes        Print the routes of the app
+                Options: --json            Print the routes as JSON
+                         --grep <pattern>  Only print matching routes
   version       Show version information
   help          Show this help message
 
@@ -59,6 +66,7 @@ Examples:
   gluey new myapp --local  # Create project using local gluey source
   gluey gen            # Generate interfaces from design/app.go
   gluey example        # Generate example controllers and views
+  gluey routes --grep posts  # Print the routes of posts
   gluey version        # Show version
 
 For more information, visit: https://gluey.dev`)
@@ -78,6 +86,17 @@ func runExampleCommand() {
 	}
 }
 
+func runRoutesCommand() {
+	err := runRoutes(os.Args[2:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func runNew(projectName string) {
 	// Check if we're in local development mode
 	localMode := false
@@ -285,15 +304,17 @@ func main() {
 		// Users: controllers.NewUsers(),
 		// Session: controllers.NewSession(),
 		// Pages: controllers.NewPagesController(),
+		// With Use("CSRF"), render rejected forms with the app's layout:
+		// CSRFFailure: controllers.NewBaseController().Forbidden,
 	}
 	
-	// Setup routes
+	// Setup routes wrapped with the middleware stack
 	mux := http.NewServeMux()
-	genhttp.MountRoutes(mux, ctrls)
+	handler := genhttp.MountRoutes(mux, ctrls)
 	
 	// Start server
 	fmt.Println("🚀 Server starting on http://localhost:8000")
-	log.Fatal(http.ListenAndServe(":8000", mux))
+	log.Fatal(http.ListenAndServe(":8000", handler))
 }
 `
 
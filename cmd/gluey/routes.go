@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// runRoutes prints the route table of the design to stdout.
+func runRoutes(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("routes", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "print the routes as JSON")
+	grep := flags.String("grep", "", "only print routes matching `pattern`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// The filter is applied by the program, check it before building it
+	if _, err := regexp.Compile(*grep); err != nil {
+		return fmt.Errorf("invalid --grep pattern %q: %w", *grep, err)
+	}
+
+	// Run the program, passing its output through
+	format := "text"
+	if *jsonOutput {
+		format = "json"
+	}
+	err := runDesignProgram("routes", routesProgram, []string{
+		"GLUEY_ROUTES_FORMAT=" + format,
+		"GLUEY_ROUTES_GREP=" + *grep,
+	}, stdout, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("routes failed: %w", err)
+	}
+
+	return nil
+}
+
+// routesProgram is the main.go of runDesignProgram that executes the design
+// and prints its routes, keeping stdout free of anything else for --json.
+const routesProgram = `package main
+
+import (
+	"fmt"
+	"os"
+
+	_ "%s/design"
+	"github.com/gobijan/gluey/codegen"
+	"github.com/gobijan/gluey/eval"
+	"github.com/gobijan/gluey/expr"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Execute the DSL
+	if err := eval.RunDSL(); err != nil {
+		return fmt.Errorf("DSL execution failed: %%w", err)
+	}
+
+	// Check we have an app
+	if expr.Root == nil {
+		return fmt.Errorf("no WebApp found in design")
+	}
+
+	table, err := codegen.NewRouteTable(expr.Root)
+	if err != nil {
+		return fmt.Errorf("invalid design: %%w", err)
+	}
+	if pattern := os.Getenv("GLUEY_ROUTES_GREP"); pattern != "" {
+		if table, err = table.Filter(pattern); err != nil {
+			return err
+		}
+	}
+	if os.Getenv("GLUEY_ROUTES_FORMAT") == "json" {
+		return table.WriteJSON(os.Stdout)
+	}
+	return table.WriteText(os.Stdout)
+}
+`
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shopDesign declares no forms, so no route binds one.
+const shopDesign = `package design
+
+import . "github.com/gobijan/gluey/dsl"
+
+var _ = WebApp("shop", func() {
+	Resource("products", func() {
+		Actions("index", "new", "create", "edit", "update")
+	})
+	Page("about", "/about")
+})
+`
+
+func TestRunRoutesWithoutForms(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the build of the routes program in short mode")
+	}
+
+	// The project lives in the Gluey repo, where getGlueyPath finds it
+	project, err := os.MkdirTemp(".", "testproject-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(project) })
+	for name, content := range map[string]string{
+		"go.mod":        "module shop\n\ngo 1.23\n",
+		"design/app.go": shopDesign,
+	} {
+		path := filepath.Join(project, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "off")
+
+	var out strings.Builder
+	if err := runRoutes(nil, &out); err != nil {
+		t.Fatalf("runRoutes() failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], "FORM") {
+		t.Fatalf("runRoutes() printed:\n%s", out.String())
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if fields[len(fields)-1] != "-" {
+			t.Errorf("route %q should bind no form", line)
+		}
+	}
+	if !strings.Contains(out.String(), "POST    /products") {
+		t.Errorf("runRoutes() should print the create route, got:\n%s", out.String())
+	}
+}
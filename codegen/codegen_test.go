@@ -1,13 +1,23 @@
 package codegen_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/gobijan/gluey/codegen"
 	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
+	"golang.org/x/mod/modfile"
 )
 
 func TestInterfaceGenerator(t *testing.T) {
@@ -115,7 +125,8 @@ func TestInterfaceGenerator(t *testing.T) {
 func TestExampleGenerator(t *testing.T) {
 	// Create a test app
 	app := &expr.AppExpr{
-		Name: "testapp",
+		Name:    "testapp",
+		Layouts: []*expr.LayoutExpr{{Name: "admin"}},
 		Resources: []*expr.ResourceExpr{
 			{
 				Name:    "posts",
@@ -154,9 +165,12 @@ func TestExampleGenerator(t *testing.T) {
 		"app/controllers/base.go",
 		"app/controllers/posts.go",
 		"app/controllers/pages.go",
+		"app/views/views.go",
 		"app/views/layouts/application.html",
+		"app/views/layouts/admin.html",
 		"app/views/shared/_errors.html",
 		"app/views/shared/_flash.html",
+		"app/views/shared/forbidden.html",
 		"app/views/posts/index.html",
 		"app/views/posts/show.html",
 		"app/views/posts/new.html",
@@ -169,6 +183,21 @@ func TestExampleGenerator(t *testing.T) {
 		}
 	}
 
+	// CSRF failures render the forbidden page with a 403
+	base, err := os.ReadFile("app/controllers/base.go")
+	if err != nil {
+		t.Fatalf("Failed to read base controller: %v", err)
+	}
+	for _, want := range []string{
+		"func (c *BaseController) Forbidden(w http.ResponseWriter, r *http.Request, err error) {",
+		"\tc.render(w, r, http.StatusForbidden, \"shared/forbidden\", map[string]interface{}{\n",
+		"\tw.WriteHeader(status)\n",
+	} {
+		if !strings.Contains(string(base), want) {
+			t.Errorf("base controller should contain %q, got:\n%s", want, base)
+		}
+	}
+
 	// Test overwrite protection - run again
 	err = gen.Generate()
 	if err != nil {
@@ -286,6 +315,11 @@ func TestViewsGenerator(t *testing.T) {
 	if !strings.Contains(layout, "{{.Title}}") {
 		t.Error("Layout should contain title placeholder")
 	}
+	for _, block := range []string{`{{block "title" .}}`, `{{block "head" .}}`, `{{block "scripts" .}}`} {
+		if !strings.Contains(layout, block) {
+			t.Errorf("Layout should contain the %s block", block)
+		}
+	}
 
 	// Test resource views generation
 	views, err := gen.GenerateResourceViews(app.Resources[0])
@@ -308,4 +342,1849 @@ func TestViewsGenerator(t *testing.T) {
 	if !strings.Contains(indexView, "{{range .Posts}}") {
 		t.Error("Index view should iterate over posts")
 	}
+
+	// Every form carries the CSRF token, and the method it stands for
+	for name, want := range map[string]string{
+		"index.html": `{{button_to "Delete" (printf "/posts/%v" .ID) "DELETE" $.CSRFToken`,
+		"show.html":  `{{button_to "Delete" (printf "/posts/%v" .ID) "DELETE" $.CSRFToken`,
+		"new.html":   "{{csrf_field .CSRFToken}}",
+		"edit.html":  `<input type="hidden" name="_method" value="PATCH">`,
+	} {
+		if !strings.Contains(views[name], want) {
+			t.Errorf("%s should contain %s", name, want)
+		}
+	}
+	for name, view := range views {
+		if _, err := template.New(name).Funcs(runtime.DefaultFuncMap()).Parse(view); err != nil {
+			t.Errorf("%s does not parse: %v", name, err)
+		}
+	}
+
+	// The flash partial shows every message of each level
+	flash := template.Must(template.New("_flash.html").Parse(gen.GenerateFlash()))
+	var buf strings.Builder
+	if err := flash.Execute(&buf, runtime.Flash{"success": {"Saved", "Mailed"}, "error": {"<b>Oops</b>"}}); err != nil {
+		t.Fatalf("flash partial failed: %v", err)
+	}
+	for _, want := range []string{
+		`<div class="flash success">Saved</div>`,
+		`<div class="flash success">Mailed</div>`,
+		`<div class="flash error">&lt;b&gt;Oops&lt;/b&gt;</div>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("flash partial = %s, want it to contain %s", buf.String(), want)
+		}
+	}
+}
+
+func TestViewsGeneratorFormFields(t *testing.T) {
+	form := &expr.FormExpr{
+		Name: "PostForm",
+		Attributes: []*expr.AttributeExpr{
+			{Name: "title", Type: expr.String, Validations: []expr.Validation{&expr.RequiredValidation{}}},
+			{
+				Name: "status", Type: expr.String, DefaultValue: "draft",
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"draft", "published"}}},
+			},
+			{
+				Name: "priority", Type: expr.Int, DefaultValue: 2,
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"1", "2", "3"}}},
+			},
+			{Name: "featured", Type: expr.Boolean},
+			{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}},
+			{Name: "settings", Type: &expr.MapType{KeyType: expr.String, ElemType: expr.String}},
+		},
+	}
+	resource := &expr.ResourceExpr{
+		Name:          "posts",
+		Actions:       []string{"new"},
+		Forms:         map[string]*expr.FormExpr{"PostForm": form},
+		ActionConfigs: map[string]*expr.ActionConfig{"create": {Action: "create", FormName: "PostForm"}},
+	}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{resource}}
+
+	views, err := codegen.NewViewsGenerator(app).GenerateResourceViews(resource)
+	if err != nil {
+		t.Fatalf("GenerateResourceViews() failed: %v", err)
+	}
+
+	newView := views["new.html"]
+	expected := []string{
+		`value="{{.Form.Title}}" required>`,
+		`<option value="draft"{{if eq (or .Form.Status "draft") "draft"}} selected{{end}}>Draft</option>`,
+		`<option value="published"{{if eq (or .Form.Status "draft") "published"}} selected{{end}}>Published</option>`,
+		`<select id="priority" name="priority">`,
+		`<option value="3"{{if eq (print (or .Form.Priority 2)) "3"}} selected{{end}}>3</option>`,
+		`<input type="hidden" name="featured" value="false">`,
+		`{{if .Form.Featured}} checked{{end}}`,
+		`{{range .Form.Tags}}<input type="text" name="tags" value="{{.}}">`,
+		`<input type="text" id="tags" name="tags" value="">`,
+		`{{range $key, $value := .Form.Settings}}<label>{{$key}} <input type="text" name="settings[{{$key}}]" value="{{$value}}"></label>`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(newView, want) {
+			t.Errorf("new view should contain %q, got:\n%s", want, newView)
+		}
+	}
+}
+
+func TestTypesGeneratorDecoders(t *testing.T) {
+	app := &expr.AppExpr{
+		Name: "testapp",
+		Forms: []*expr.FormExpr{
+			{
+				Name: "PostForm",
+				Attributes: []*expr.AttributeExpr{
+					{Name: "title", Type: expr.String},
+					{Name: "status", Type: expr.String, DefaultValue: "draft"},
+					{Name: "views", Type: expr.Int64},
+					{Name: "published", Type: expr.Boolean, DefaultValue: true},
+					{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}},
+					{Name: "settings", Type: &expr.MapType{KeyType: expr.String, ElemType: expr.Int}},
+				},
+			},
+		},
+		Resources: []*expr.ResourceExpr{
+			{
+				Name: "posts",
+				ActionConfigs: map[string]*expr.ActionConfig{
+					"index": {
+						Action: "index",
+						Params: []*expr.ParamExpr{
+							{Name: "page", Type: expr.Int, Default: 1},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	content, err := codegen.NewTypesGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	expected := []string{
+		"func (f *PostForm) FromValues(values url.Values) error",
+		"func (f *PostForm) Bind(r *http.Request) error",
+		"\tvalues, err := runtime.RequestValues(r)\n",
+		"func (f *PostForm) Patch(r *http.Request) error",
+		"\tif d.Has(\"title\") {\n\t\tf.Title = submitted.Title\n\t}\n",
+		`f.Title = d.String("title", "")`,
+		`f.Status = d.String("status", "draft")`,
+		`f.Views = d.Int64("views", 0)`,
+		`f.Published = d.Bool("published", true)`,
+		`f.Tags = runtime.DecodeSlice(d, "tags", runtime.ParseString, nil)`,
+		`f.Settings = runtime.DecodeMap(d, "settings", runtime.ParseString, runtime.ParseInt, nil)`,
+		"func (f *PostsIndexParams) Bind(r *http.Request) error",
+		`f.Page = d.Int("page", 1)`,
+		"func NewPostForm() *PostForm {",
+		"\t\tStatus: \"draft\",",
+		"\t\tPublished: true,",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated types should contain %q", want)
+		}
+	}
+}
+
+func TestTypesGeneratorValidations(t *testing.T) {
+	app := &expr.AppExpr{
+		Name: "testapp",
+		Forms: []*expr.FormExpr{
+			{
+				Name: "PostForm",
+				Attributes: []*expr.AttributeExpr{
+					{
+						Name: "status",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.EnumValidation{Values: []string{"draft", "published", "archived"}},
+						},
+					},
+					{
+						Name: "slug",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.PatternValidation{Pattern: "^[a-z0-9-]+$"},
+						},
+					},
+					{
+						Name: "rating",
+						Type: expr.Int,
+						Validations: []expr.Validation{
+							&expr.MinValidation{Min: 1},
+							&expr.MaxValidation{Max: 5},
+						},
+					},
+					{
+						Name: "priority",
+						Type: expr.Int,
+						Validations: []expr.Validation{
+							&expr.EnumValidation{Values: []string{"1", "2", "3"}},
+						},
+					},
+					{
+						Name: "published_on",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.FormatValidation{Format: expr.FormatDate},
+						},
+					},
+					{
+						Name: "published_at",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.FormatValidation{Format: expr.FormatDateTime},
+						},
+					},
+					{
+						Name: "token",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.FormatValidation{Format: expr.FormatUUID},
+						},
+					},
+					{
+						Name: "featured",
+						Type: expr.Boolean,
+						Validations: []expr.Validation{
+							&expr.RequiredValidation{},
+						},
+					},
+					{
+						Name: "state",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.EnumValidation{Values: []string{"in progress", "done", "a,b", "it's"}},
+						},
+					},
+					{
+						Name: "quote",
+						Type: expr.String,
+						Validations: []expr.Validation{
+							&expr.EnumValidation{Values: []string{"`raw`", "plain"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	content, err := codegen.NewTypesGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	expected := []string{
+		`v.OneOf("status", f.Status, "draft", "published", "archived")`,
+		"postFormSlugPattern = regexp.MustCompile(`^[a-z0-9-]+$`)",
+		`v.Matches("slug", f.Slug, postFormSlugPattern)`,
+		`v.Range("rating", float64(f.Rating), 1, 5)`,
+		`v.Date("published_on", f.PublishedOn)`,
+		`v.DateTime("published_at", f.PublishedAt)`,
+		`v.UUID("token", f.Token)`,
+		`v.Check("featured", f.Featured, "is required")`,
+		`validate:"oneof=draft published archived"`,
+		`validate:"min=1,max=5"`,
+		"\t\"fmt\"\n",
+		"if f.Priority != 0 {\n\t\tv.OneOf(\"priority\", fmt.Sprint(f.Priority), \"1\", \"2\", \"3\")",
+		`validate:"oneof=1 2 3"`,
+		"State string `form:\"state\" json:\"state,omitempty\" validate:\"oneof='in progress' done 'a,b' 'it''s'\"`",
+		"Quote string `form:\"quote\" json:\"quote,omitempty\"`",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated types should contain %q", want)
+		}
+	}
+
+	// The generated struct tag accepts the values of the generated Validate
+	tag := regexp.MustCompile("State string `([^`]*)`").FindStringSubmatch(content)
+	if tag == nil {
+		t.Fatal("generated types should declare the State field")
+	}
+	state := reflect.StructOf([]reflect.StructField{{Name: "State", Type: reflect.TypeOf(""), Tag: reflect.StructTag(tag[1])}})
+	for _, value := range []string{"in progress", "a,b", "it's"} {
+		form := reflect.New(state)
+		form.Elem().Field(0).SetString(value)
+		if errs := runtime.ValidateStruct(form.Interface()); errs.HasErrors() {
+			t.Errorf("the state tag should accept %q, got %v", value, errs)
+		}
+	}
+
+	if strings.Count(content, "regexp.MustCompile") != 1 {
+		t.Error("patterns should be compiled once at package level")
+	}
+
+	// Invalid patterns fail generation
+	app.Forms[0].Attributes[1].Validations = []expr.Validation{
+		&expr.PatternValidation{Pattern: "^(?=.*[a-z]).*$"},
+	}
+	if _, err := codegen.NewTypesGenerator(app).Generate(); err == nil {
+		t.Error("Generate() should fail for patterns that do not compile")
+	}
+}
+
+func TestTypesGeneratorCustomValidations(t *testing.T) {
+	app := &expr.AppExpr{
+		Name: "testapp",
+		Forms: []*expr.FormExpr{
+			{
+				Name: "RegisterForm",
+				Attributes: []*expr.AttributeExpr{
+					{Name: "email", Type: expr.String, Validations: []expr.Validation{&expr.CustomValidation{Rule: "unique"}}},
+					{Name: "password", Type: expr.String},
+					{
+						Name: "password_confirmation", Type: expr.String,
+						Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleMatches, Args: []string{"password"}}},
+					},
+					{Name: "terms", Type: expr.Boolean, Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleAccepted}}},
+				},
+			},
+			{
+				Name:       "LoginForm",
+				Attributes: []*expr.AttributeExpr{{Name: "email", Type: expr.String}},
+			},
+		},
+	}
+
+	content, err := codegen.NewTypesGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	expected := []string{
+		"type RegisterFormValidator interface {",
+		"ValidateEmailUnique(f *RegisterForm) error",
+		"func SetRegisterFormValidator(v RegisterFormValidator) {",
+		`return &runtime.MissingValidatorError{Name: "RegisterFormValidator"}`,
+		`v.Custom("email", registerFormValidator.ValidateEmailUnique(f))`,
+		`v.Match("password", f.Password, "password_confirmation", f.PasswordConfirmation)`,
+		`v.Check("terms", f.Terms, "must be accepted")`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated types should contain %q", want)
+		}
+	}
+	if strings.Contains(content, "LoginFormValidator") {
+		t.Error("forms without hooks should not get a validator interface")
+	}
+}
+
+func TestTypesGeneratorModels(t *testing.T) {
+	form := &expr.FormExpr{
+		Name: "PostForm",
+		Attributes: []*expr.AttributeExpr{
+			{Name: "title", Type: expr.String},
+			{Name: "views", Type: expr.Int},
+			{Name: "notify", Type: expr.Boolean},
+			{Name: "author_id", Type: expr.Int64},
+		},
+	}
+	resource := &expr.ResourceExpr{
+		Name:          "posts",
+		Actions:       []string{"index", "show", "new", "edit"},
+		Forms:         map[string]*expr.FormExpr{"PostForm": form},
+		ActionConfigs: map[string]*expr.ActionConfig{},
+	}
+	resource.Model = &expr.ModelExpr{
+		Name:     "Post",
+		Resource: resource,
+		Fields: []*expr.AttributeExpr{
+			{Name: "title", Type: expr.String},
+			{Name: "views", Type: expr.Int64},
+			{Name: "author_id", Type: expr.Int64},
+		},
+	}
+	resource.Model.Prepare()
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{resource}}
+
+	gen := codegen.NewTypesGenerator(app)
+	if !gen.HasModels() {
+		t.Fatal("HasModels() should be true")
+	}
+	content, err := gen.GenerateModels()
+	if err != nil {
+		t.Fatalf("GenerateModels() failed: %v", err)
+	}
+
+	expected := []string{
+		"type Post struct {",
+		"ID int64 `json:\"id\" db:\"id\"`",
+		"type PostRepository interface {",
+		"List(ctx context.Context) ([]*Post, int, error)",
+		"Get(ctx context.Context, id int64) (*Post, error)",
+		"Delete(ctx context.Context, id int64) error",
+		"func (f *PostForm) ToPost() *Post {",
+		"m.Title = f.Title",
+		// Only model fields spell id as ID
+		"AuthorID int64 `json:\"author_id\" db:\"author_id\"`",
+		"m.AuthorID = f.AuthorId",
+		"f.AuthorId = m.AuthorID",
+		"func (f *PostForm) FromPost(m *Post) {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated models should contain %q, got:\n%s", want, content)
+		}
+	}
+	// views differs in type and notify is not a model field
+	for _, unwanted := range []string{"m.Views = f.Views", "m.Notify"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("generated models should not contain %q", unwanted)
+		}
+	}
+
+	views, err := codegen.NewViewsGenerator(app).GenerateResourceViews(resource)
+	if err != nil {
+		t.Fatalf("GenerateResourceViews() failed: %v", err)
+	}
+	for _, want := range []string{"<th>Title</th>", "<td>{{.Title}}</td>", `href="/posts/{{.ID}}"`} {
+		if !strings.Contains(views["index.html"], want) {
+			t.Errorf("index view should contain %q", want)
+		}
+	}
+	if !strings.Contains(views["show.html"], "<dd>{{.Views}}</dd>") {
+		t.Error("show view should list model fields")
+	}
+}
+
+func TestExampleGeneratorMemoryRepository(t *testing.T) {
+	posts := &expr.ResourceExpr{
+		Name:             "posts",
+		Actions:          []string{"index", "show", "new", "create", "edit", "update", "destroy"},
+		Forms:            map[string]*expr.FormExpr{},
+		ActionConfigs:    map[string]*expr.ActionConfig{},
+		Pagination:       map[string]int{"index": 10},
+		SearchableFields: map[string][]string{"index": {"title"}},
+		FilterableFields: map[string][]string{"index": {"views"}},
+	}
+	posts.Forms["PostForm"] = &expr.FormExpr{Name: "PostForm", Attributes: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+	posts.ActionConfigs["create"] = &expr.ActionConfig{Action: "create", FormName: "PostForm"}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{
+		{Name: "title", Type: expr.String},
+		{Name: "views", Type: expr.Int},
+	}}
+	tags := &expr.ResourceExpr{Name: "tags", Actions: []string{"index", "show"}}
+	tags.Model = &expr.ModelExpr{Name: "Tag", Resource: tags, Fields: []*expr.AttributeExpr{
+		{Name: "slug", Type: expr.String, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+	}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, tags}}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	gen := codegen.NewExampleGenerator(app)
+	gen.OutputDir = t.TempDir()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(gen.OutputDir, name))
+		if err != nil {
+			t.Fatalf("expected %s to be generated: %v", name, err)
+		}
+		return string(content)
+	}
+
+	repo := read("app/repositories/posts.go")
+	for _, want := range []string{
+		"var _ types.PostRepository = (*PostMemoryRepository)(nil)",
+		"func (r *PostMemoryRepository) List(ctx context.Context, params *types.PostsIndexParams) ([]*types.Post, int, error) {",
+		"m.ID = r.lastID + 1",
+		"page := runtime.NewPage(params.Page, params.PerPage, 10)",
+		"!strings.Contains(strings.ToLower(m.Title), q)",
+		`params.Views != "" && fmt.Sprint(m.Views) != params.Views`,
+	} {
+		if !strings.Contains(repo, want) {
+			t.Errorf("posts repository should contain %q, got:\n%s", want, repo)
+		}
+	}
+
+	tagRepo := read("app/repositories/tags.go")
+	if !strings.Contains(tagRepo, "func (r *TagMemoryRepository) List(ctx context.Context) ([]*types.Tag, int, error) {") {
+		t.Error("tags repository should list without params")
+	}
+	if strings.Contains(tagRepo, "lastID") || !strings.Contains(tagRepo, "m.Slug = runtime.NewID()") {
+		t.Errorf("string primary keys should be generated at random, got:\n%s", tagRepo)
+	}
+
+	controller := read("app/controllers/posts.go")
+	for _, want := range []string{
+		"return NewPostsWithRepository(repositories.NewPostMemoryRepository())",
+		"posts, total, err := c.repo.List(r.Context(), params)",
+		"post := form.ToPost()",
+		`c.Redirect(w, r, "/posts/"+strconv.FormatInt(post.ID, 10))`,
+		`return strconv.ParseInt(r.PathValue("id"), 10, 64)`,
+	} {
+		if !strings.Contains(controller, want) {
+			t.Errorf("posts controller should contain %q, got:\n%s", want, controller)
+		}
+	}
+	if !strings.Contains(read("app/controllers/tags.go"), `return r.PathValue("id"), nil`) {
+		t.Error("tags controller should use the path value as ID")
+	}
+}
+
+func TestSQLGenerator(t *testing.T) {
+	posts := &expr.ResourceExpr{
+		Name:             "posts",
+		Actions:          []string{"index", "show"},
+		ActionConfigs:    map[string]*expr.ActionConfig{},
+		Pagination:       map[string]int{"index": 10},
+		SearchableFields: map[string][]string{"index": {"title", "body"}},
+		FilterableFields: map[string][]string{"index": {"status", "views"}},
+	}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{
+		{Name: "title", Type: expr.String, Validations: []expr.Validation{
+			&expr.RequiredValidation{},
+			&expr.MaxLengthValidation{Max: 200},
+		}},
+		{Name: "body", Type: expr.String},
+		{Name: "status", Type: expr.String, Validations: []expr.Validation{
+			&expr.EnumValidation{Values: []string{"draft", "published"}},
+		}},
+		{Name: "views", Type: expr.Int},
+		{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}},
+	}}
+	tags := &expr.ResourceExpr{Name: "tags", Actions: []string{"index", "show"}}
+	tags.Model = &expr.ModelExpr{Name: "Tag", Resource: tags, Fields: []*expr.AttributeExpr{
+		{Name: "slug", Type: expr.String, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+	}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, tags}}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	gen := codegen.NewSQLGenerator(app)
+
+	postgres := gen.CreateTable(posts, runtime.Postgres)
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "posts" (`,
+		`"id" BIGSERIAL PRIMARY KEY`,
+		`"title" VARCHAR(200) NOT NULL`,
+		`"body" TEXT,`,
+		`"tags" JSONB`,
+		`CHECK ("status" IN ('draft', 'published', ''))`,
+	} {
+		if !strings.Contains(postgres, want) {
+			t.Errorf("Postgres table should contain %q, got:\n%s", want, postgres)
+		}
+	}
+	sqlite := gen.CreateTable(posts, runtime.SQLite)
+	for _, want := range []string{`"id" INTEGER PRIMARY KEY AUTOINCREMENT`, `"tags" TEXT`} {
+		if !strings.Contains(sqlite, want) {
+			t.Errorf("SQLite table should contain %q, got:\n%s", want, sqlite)
+		}
+	}
+	if table := gen.CreateTable(tags, runtime.SQLite); !strings.Contains(table, `"slug" TEXT NOT NULL PRIMARY KEY`) {
+		t.Errorf("tags table should use the string primary key, got:\n%s", table)
+	}
+
+	migrations, err := gen.GenerateMigrations()
+	if err != nil {
+		t.Fatalf("GenerateMigrations() failed: %v", err)
+	}
+	for _, want := range []string{"package sqlstore", "runtime.Postgres: {", "runtime.SQLite: {",
+		"func Migrate(ctx context.Context, db runtime.DBTX, dialect runtime.Dialect) error {"} {
+		if !strings.Contains(migrations, want) {
+			t.Errorf("migrations should contain %q", want)
+		}
+	}
+
+	repo, err := gen.GenerateRepository(posts)
+	if err != nil {
+		t.Fatalf("GenerateRepository() failed: %v", err)
+	}
+	for _, want := range []string{
+		"var _ types.PostRepository = (*PostRepository)(nil)",
+		"where.Search([]string{`\"title\"`, `\"body\"`}, params.Q)",
+		"where.Equal(`\"status\"`, params.Status)",
+		"v, err := strconv.Atoi(params.Views)",
+		`" LIMIT " + strconv.Itoa(page.PerPage) + " OFFSET " + strconv.Itoa(page.Offset())`,
+		"VALUES (?, ?, ?, ?, ?) RETURNING \"id\"`",
+		"runtime.JSON(m.Tags)",
+		"runtime.JSON(&m.Tags)",
+		"return nil, runtime.ErrNotFound",
+		"return runtime.CheckAffected(r.db.ExecContext(ctx, r.dialect.Rebind(query), id))",
+	} {
+		if !strings.Contains(repo, want) {
+			t.Errorf("posts repository should contain %q, got:\n%s", want, repo)
+		}
+	}
+
+	tagRepo, err := gen.GenerateRepository(tags)
+	if err != nil {
+		t.Fatalf("GenerateRepository() failed: %v", err)
+	}
+	if !strings.Contains(tagRepo, "func (r *TagRepository) List(ctx context.Context) ([]*types.Tag, int, error) {") {
+		t.Error("tags repository should list without params")
+	}
+	if strings.Contains(tagRepo, "RETURNING") {
+		t.Error("string primary keys should not be generated by the database")
+	}
+	if !strings.Contains(tagRepo, "\tif m.Slug == \"\" {\n\t\tm.Slug = runtime.NewID()\n\t}\n") {
+		t.Errorf("tags repository should generate empty slugs, got:\n%s", tagRepo)
+	}
+
+	// Nested resources are listed per parent
+	comments := &expr.ResourceExpr{Name: "comments", Parent: posts}
+	comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "body", Type: expr.String}}}
+	comments.Prepare()
+	commentRepo, err := gen.GenerateRepository(comments)
+	if err != nil {
+		t.Fatalf("GenerateRepository() failed: %v", err)
+	}
+	for _, want := range []string{
+		"func (r *CommentRepository) List(ctx context.Context, postID int64) ([]*types.Comment, int, error) {",
+		"where.Equal(`\"post_id\"`, postID)",
+		"FROM \"post_comments\"",
+	} {
+		if !strings.Contains(commentRepo, want) {
+			t.Errorf("comments repository should contain %q, got:\n%s", want, commentRepo)
+		}
+	}
+}
+
+// generateRouters returns the router written by the interface generator
+// and the one of the router generator for app.
+func generateRouters(t *testing.T, app *expr.AppExpr) (router, legacy string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := codegen.NewInterfaceGenerator(app, dir).Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "http/router.go"))
+	if err != nil {
+		t.Fatalf("Failed to read router: %v", err)
+	}
+	legacy, err = codegen.NewRouterGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("RouterGenerator.Generate() failed: %v", err)
+	}
+	return string(content), legacy
+}
+
+// runGenerated generates the interfaces of app, and its example app with
+// example, into a module using this one and runs program as its main
+// package, with input encoded as JSON on stdin. The JSON output of the
+// program is decoded into output.
+func runGenerated(t *testing.T, app *expr.AppExpr, example bool, program string, input, output any) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping the build of generated code in short mode")
+	}
+
+	dir := t.TempDir()
+	if err := codegen.NewInterfaceGenerator(app, filepath.Join(dir, "gen")).Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if example {
+		gen := codegen.NewExampleGenerator(app)
+		gen.OutputDir = dir
+		if err := gen.Generate(); err != nil {
+			t.Fatalf("ExampleGenerator.Generate() failed: %v", err)
+		}
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod := "module " + app.Name + "\n\ngo " + mod.Go.Version + "\n\nrequire github.com/gobijan/gluey v0.0.0\n\nreplace github.com/gobijan/gluey => " + root + "\n"
+	for _, req := range mod.Require {
+		goMod += "\nrequire " + req.Mod.Path + " " + req.Mod.Version + "\n"
+	}
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{
+		"go.mod":  []byte(goMod),
+		"go.sum":  goSum,
+		"main.go": []byte(program),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off", "GOPROXY=off")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			t.Fatalf("running the generated code failed: %v\n%s", err, exitErr.Stderr)
+		}
+		t.Fatalf("running the generated code failed: %v", err)
+	}
+	if err := json.Unmarshal(out, output); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+}
+
+func TestRouterAuth(t *testing.T) {
+	app := &expr.AppExpr{
+		Name: "testapp",
+		Resources: []*expr.ResourceExpr{
+			{
+				Name:    "posts",
+				Actions: []string{"index", "update", "destroy"},
+				AuthRequirements: map[string][]string{
+					"update":  {"authenticated"},
+					"destroy": {"authenticated", "admin"},
+				},
+			},
+		},
+		Pages: []*expr.PageExpr{
+			{
+				Name:             "dashboard",
+				Routes:           []expr.RouteExpr{{Method: "GET", Path: "/dashboard"}},
+				AuthRequirements: []string{"authenticated"},
+			},
+		},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			"Authorizer runtime.Authorizer",
+			"AuthPolicy runtime.AuthPolicy",
+			"if c.Authorizer == nil {",
+			"return runtime.RequireAuth(c.Authorizer, c.AuthPolicy, resource, action, requirements, next)",
+			`auth("posts", "destroy", c.Posts.Destroy, "authenticated", "admin")`,
+			`auth("pages", "dashboard", c.Pages.Dashboard, "authenticated")`,
+			`mux.HandleFunc("DELETE /posts/{id}", auth("posts", "destroy", c.Posts.Destroy, "authenticated", "admin"))`,
+			`mux.HandleFunc("PATCH /posts/{id}", auth("posts", "update", c.Posts.Update, "authenticated"))`,
+			"return runtime.MethodOverride(mux)",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+	}
+	if !strings.Contains(router, `mux.HandleFunc("GET /posts", c.Posts.Index)`) {
+		t.Error("routes without requirements should not be wrapped")
+	}
+	if !strings.Contains(router, `mux.HandleFunc("PUT /posts/{id}", auth("posts", "update", c.Posts.Update, "authenticated"))`) {
+		t.Error("every route of an action should be wrapped")
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:             "testapp",
+		Resources:        []*expr.ResourceExpr{{Name: "posts", Actions: []string{"index"}}},
+		Middleware:       []string{"RequestID", "logger", "RateLimiter"},
+		CustomMiddleware: []string{"RateLimiter"},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			"Middleware map[string]runtime.Middleware",
+			"func MountRoutes(mux *http.ServeMux, c Controllers) http.Handler {",
+			`return runtime.Chain(mux, runtime.ResolveMiddleware(c.Middleware, "RequestID", "logger", "RateLimiter")...)`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+	}
+
+	app.CustomMiddleware = nil
+	err := codegen.NewInterfaceGenerator(app, t.TempDir()).Generate()
+	if err == nil || !strings.Contains(err.Error(), `unknown middleware "RateLimiter"`) {
+		t.Errorf("Generate() error = %v, want unknown middleware error", err)
+	}
+	if _, err := codegen.NewRouterGenerator(app).Generate(); err == nil {
+		t.Error("RouterGenerator.Generate() should fail for unknown middleware")
+	}
+
+	app.Middleware = nil
+	plain, err := codegen.NewRouterGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("RouterGenerator.Generate() failed: %v", err)
+	}
+	if !strings.Contains(plain, "\treturn mux\n") || strings.Contains(plain, "runtime") {
+		t.Errorf("router without middleware should return the mux, got:\n%s", plain)
+	}
+}
+
+func TestRouterMethodOverrideMiddleware(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:       "testapp",
+		Resources:  []*expr.ResourceExpr{{Name: "posts", Actions: []string{"index", "destroy"}}},
+		Middleware: []string{"Logger", "method_override"},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		want := `return runtime.Chain(mux, runtime.ResolveMiddleware(c.Middleware, "Logger", "method_override")...)`
+		if !strings.Contains(code, want) {
+			t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+		}
+		if strings.Contains(code, "runtime.MethodOverride(") {
+			t.Errorf("%s should not wrap the mux in a second method override, got:\n%s", name, code)
+		}
+	}
+}
+
+func TestRouterRouteMiddleware(t *testing.T) {
+	users := &expr.ResourceExpr{
+		Name:             "users",
+		Actions:          []string{"index", "destroy"},
+		Middleware:       []string{"Gzip"},
+		AuthRequirements: map[string][]string{"destroy": {"admin"}},
+	}
+	users.ActionConfigs = map[string]*expr.ActionConfig{
+		"destroy": {Action: "destroy", Resource: users, Middleware: []string{"AuditLog"}},
+	}
+	app := &expr.AppExpr{
+		Name:             "testapp",
+		Resources:        []*expr.ResourceExpr{users},
+		Pages:            []*expr.PageExpr{{Name: "about", Middleware: []string{"Cache"}, Routes: []expr.RouteExpr{{Method: "GET", Path: "/about"}}}},
+		CustomMiddleware: []string{"AuditLog", "Cache"},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			"Middleware map[string]runtime.Middleware",
+			"use := func(next http.HandlerFunc, middleware ...string) http.HandlerFunc {",
+			`use(c.Users.Index, "Gzip")`,
+			`auth("users", "destroy", use(c.Users.Destroy, "Gzip", "AuditLog"), "admin")`,
+			`use(c.Pages.About, "Cache")`,
+			"\treturn runtime.MethodOverride(mux)\n",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+	}
+
+	app.CustomMiddleware = []string{"Cache"}
+	err := codegen.NewInterfaceGenerator(app, t.TempDir()).Generate()
+	if err == nil || !strings.Contains(err.Error(), `unknown middleware "AuditLog" in Use of action users#destroy`) {
+		t.Errorf("Generate() error = %v, want unknown middleware error", err)
+	}
+}
+
+func TestRouterLayouts(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:          "testapp",
+		Layouts:       []*expr.LayoutExpr{{Name: "admin"}},
+		DefaultLayout: "application",
+		Resources: []*expr.ResourceExpr{
+			{Name: "users", Actions: []string{"index", "destroy"}, Layout: "admin", AuthRequirements: map[string][]string{"destroy": {"admin"}}},
+			{Name: "posts", Actions: []string{"index"}},
+		},
+		Pages: []*expr.PageExpr{{Name: "dashboard", Layout: "admin", Routes: []expr.RouteExpr{{Method: "GET", Path: "/dashboard"}}}},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			`runtime.UseLayout("admin", c.Users.Index)`,
+			`runtime.UseLayout("admin", auth("users", "destroy", c.Users.Destroy, "admin"))`,
+			`runtime.UseLayout("admin", c.Pages.Dashboard)`,
+			`"GET /posts", c.Posts.Index)`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+	}
+
+	// Another app default applies to every route without a layout
+	app.DefaultLayout = "main"
+	code, err := codegen.NewRouterGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("RouterGenerator.Generate() failed: %v", err)
+	}
+	if !strings.Contains(code, `runtime.UseLayout("main", c.Posts.Index)`) {
+		t.Errorf("router should use the main layout for posts, got:\n%s", code)
+	}
+
+	names := codegen.NewViewsGenerator(app).LayoutNames()
+	if want := []string{"application", "main", "admin"}; !slices.Equal(names, want) {
+		t.Errorf("LayoutNames() = %v, want %v", names, want)
+	}
+}
+
+func TestRouterSessions(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:       "testapp",
+		Resources:  []*expr.ResourceExpr{{Name: "posts", Actions: []string{"index"}}},
+		Middleware: []string{"Logger"},
+	}
+
+	tests := []struct {
+		store string
+		want  []string
+	}{
+		{
+			store: "memory",
+			want:  []string{"c.Sessions = session.NewManager(session.NewMemoryStore())"},
+		},
+		{
+			store: "filesystem",
+			want:  []string{`store, err := session.NewFilesystemStore("tmp/sessions")`, "c.Sessions = session.NewManager(store)"},
+		},
+		{
+			store: "cookie",
+			want:  []string{`panic("Controllers.Sessions is required by the cookie session store")`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			app.SessionStore = tt.store
+			router, legacy := generateRouters(t, app)
+			for name, code := range map[string]string{"interface router": router, "router": legacy} {
+				want := append([]string{
+					`"github.com/gobijan/gluey/runtime/session"`,
+					"Sessions *session.Manager",
+					`return runtime.Chain(c.Sessions.Handler(mux), runtime.ResolveMiddleware(c.Middleware, "Logger")...)`,
+				}, tt.want...)
+				for _, w := range want {
+					if !strings.Contains(code, w) {
+						t.Errorf("%s should contain %q, got:\n%s", name, w, code)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRouterSkipCSRF(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:       "testapp",
+		Middleware: []string{"Recover", "csrf"},
+		Pages: []*expr.PageExpr{
+			{Name: "contact", Routes: []expr.RouteExpr{{Method: "POST", Path: "/contact"}}},
+			{Name: "stripe", SkipCSRF: true, Routes: []expr.RouteExpr{{Method: "POST", Path: "/webhooks/stripe"}}},
+		},
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			"\tCSRFFailure func(w http.ResponseWriter, r *http.Request, err error)\n",
+			`middleware := map[string]runtime.Middleware{"csrf": runtime.CSRFProtection{OnFailure: c.CSRFFailure}.Handler}`,
+			`handler := runtime.Chain(mux, runtime.ResolveMiddleware(middleware, "Recover", "csrf")...)`,
+			`return runtime.SkipCSRF(mux, handler, "POST /webhooks/stripe")`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+		if strings.Contains(code, `"POST /contact")`) {
+			t.Errorf("only SkipCSRF pages should be exempt in the %s", name)
+		}
+	}
+	if want := `mux.HandleFunc("POST /webhooks/stripe", c.Pages.StripePost)`; !strings.Contains(router, want) {
+		t.Errorf("interface router should contain %q, got:\n%s", want, router)
+	}
+
+	app.Middleware = []string{"Recover"}
+	legacy, err := codegen.NewRouterGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("RouterGenerator.Generate() failed: %v", err)
+	}
+	if strings.Contains(legacy, "SkipCSRF") || strings.Contains(legacy, "CSRFFailure") {
+		t.Errorf("router without CSRF middleware should not exempt routes, got:\n%s", legacy)
+	}
+}
+
+func TestRouterFormats(t *testing.T) {
+	posts := &expr.ResourceExpr{
+		Name:             "posts",
+		Formats:          []string{"html", "json"},
+		AuthRequirements: map[string][]string{"destroy": {"admin"}},
+		Pagination:       map[string]int{"index": 20},
+	}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{
+		{Name: "title", Type: expr.String, Validations: []expr.Validation{&expr.RequiredValidation{}}},
+	}}
+	posts.Forms = map[string]*expr.FormExpr{"NewPostsForm": {Name: "NewPostsForm", Attributes: []*expr.AttributeExpr{
+		{Name: "title", Type: expr.String, Validations: []expr.Validation{&expr.RequiredValidation{}}},
+	}}}
+	app := &expr.AppExpr{
+		Name:      "testapp",
+		Resources: []*expr.ResourceExpr{posts, {Name: "tags", Actions: []string{"index"}}},
+	}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			`"GET /posts", runtime.UseFormats([]string{"html", "json"}, c.Posts.Index))`,
+			`"GET /posts.json", runtime.UseFormats([]string{"html", "json"}, c.Posts.Index))`,
+			`"POST /posts.json", runtime.UseFormats([]string{"html", "json"}, c.Posts.Create))`,
+			`"GET /posts/{id}", runtime.UseFormats([]string{"html", "json"}, c.Posts.Show))`,
+			`runtime.UseFormats([]string{"html", "json"}, auth("posts", "destroy", c.Posts.Destroy, "admin"))`,
+			`"GET /tags", c.Tags.Index)`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+		for _, unwanted := range []string{"/posts/new.json", "/posts/{id}.json", "/tags.json"} {
+			if strings.Contains(code, unwanted) {
+				t.Errorf("%s should not mount %s", name, unwanted)
+			}
+		}
+	}
+
+	gen := codegen.NewExampleGenerator(app)
+	gen.OutputDir = t.TempDir()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	controller, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/posts.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	for _, want := range []string{
+		`c.Respond(w, r, "posts/index"`,
+		// API clients get the records without the view chrome
+		"\tpage := runtime.NewPage(params.Page, params.PerPage, 20)\n",
+		"\t}, map[string]interface{}{\n\t\t\"posts\": posts,\n\t\t\"total\": total,\n\t\t\"page\": page.Number,\n\t\t\"per_page\": page.PerPage,\n\t})\n",
+		`c.Respond(w, r, "posts/show"`,
+		"\t}, post)\n",
+		"runtime.WriteProblem(w, runtime.ValidationProblem(errs))",
+		"runtime.WriteJSON(w, http.StatusCreated, post)",
+		"w.WriteHeader(http.StatusNoContent)",
+	} {
+		if !strings.Contains(string(controller), want) {
+			t.Errorf("posts controller should contain %q, got:\n%s", want, controller)
+		}
+	}
+	tags, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/tags.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	if strings.Contains(string(tags), "Respond") || strings.Contains(string(tags), "WantsJSON") {
+		t.Errorf("HTML-only tags controller should not answer JSON, got:\n%s", tags)
+	}
+}
+
+// routerProgram serves requests with the router generated in
+// TestRouterServes, whose controllers answer with what they were called for.
+const routerProgram = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/gobijan/gluey/runtime"
+
+	genhttp "testapp/gen/http"
+)
+
+// recorder answers every action with its name and the layout, format and
+// id of the request.
+type recorder string
+
+func (c recorder) answer(w http.ResponseWriter, r *http.Request, action string) {
+	fmt.Fprintf(w, "%s.%s layout=%s format=%s id=%s", c, action,
+		runtime.LayoutFrom(r.Context()), runtime.FormatFrom(r.Context()), r.PathValue("id"))
+}
+
+func (c recorder) Index(w http.ResponseWriter, r *http.Request)     { c.answer(w, r, "Index") }
+func (c recorder) Show(w http.ResponseWriter, r *http.Request)      { c.answer(w, r, "Show") }
+func (c recorder) New(w http.ResponseWriter, r *http.Request)       { c.answer(w, r, "New") }
+func (c recorder) Create(w http.ResponseWriter, r *http.Request)    { c.answer(w, r, "Create") }
+func (c recorder) Edit(w http.ResponseWriter, r *http.Request)      { c.answer(w, r, "Edit") }
+func (c recorder) Update(w http.ResponseWriter, r *http.Request)    { c.answer(w, r, "Update") }
+func (c recorder) Destroy(w http.ResponseWriter, r *http.Request)   { c.answer(w, r, "Destroy") }
+func (c recorder) Dashboard(w http.ResponseWriter, r *http.Request) { c.answer(w, r, "Dashboard") }
+
+func main() {
+	handler := genhttp.MountRoutes(http.NewServeMux(), genhttp.Controllers{
+		Posts: recorder("Posts"),
+		Tags:  recorder("Tags"),
+		Pages: recorder("Pages"),
+		// Everybody is authenticated with X-User, and only admin is an admin
+		Authorizer: runtime.AuthorizerFunc(func(r *http.Request, requirement, resource, action string) error {
+			switch user := r.Header.Get("X-User"); {
+			case user == "":
+				return runtime.ErrUnauthenticated
+			case requirement == "admin" && user != "admin":
+				return runtime.ErrForbidden
+			}
+			return nil
+		}),
+	})
+	serve(handler)
+}
+` + serveFunc
+
+// serveFunc serves the requests read as JSON from stdin with a handler and
+// prints the responses as JSON. Programs run by runGenerated append it.
+const serveFunc = `
+func serve(handler http.Handler) {
+	var requests []struct {
+		Method string
+		Path   string
+		Header map[string]string
+		Body   string
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&requests); err != nil {
+		log.Fatal(err)
+	}
+	responses := []map[string]any{}
+	for _, req := range requests {
+		r := httptest.NewRequest(req.Method, req.Path, strings.NewReader(req.Body))
+		for name, value := range req.Header {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		responses = append(responses, map[string]any{
+			"status": w.Code,
+			"type":   w.Header().Get("Content-Type"),
+			"body":   strings.TrimSpace(w.Body.String()),
+		})
+	}
+	json.NewEncoder(os.Stdout).Encode(responses)
+}
+`
+
+// TestRouterServes compiles a generated router and checks that requests
+// reach the controllers through its auth checks, layouts, formats and
+// method override.
+func TestRouterServes(t *testing.T) {
+	posts := &expr.ResourceExpr{
+		Name:             "posts",
+		Formats:          []string{"html", "json"},
+		Layout:           "admin",
+		AuthRequirements: map[string][]string{"update": {"authenticated"}, "destroy": {"admin"}},
+	}
+	app := &expr.AppExpr{
+		Name:          "testapp",
+		Layouts:       []*expr.LayoutExpr{{Name: "admin"}},
+		DefaultLayout: "main",
+		Resources:     []*expr.ResourceExpr{posts, {Name: "tags", Actions: []string{"index"}}},
+		Pages: []*expr.PageExpr{{
+			Name:             "dashboard",
+			Layout:           "admin",
+			AuthRequirements: []string{"authenticated"},
+			Routes:           []expr.RouteExpr{{Method: "GET", Path: "/dashboard"}},
+		}},
+	}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	form := map[string]string{"Content-Type": "application/x-www-form-urlencoded", "X-User": "admin"}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		body   string
+		status int
+		want   string
+	}{
+		{"layout", "GET", "/posts", nil, "", 200, "Posts.Index layout=admin format=html id="},
+		{"default layout", "GET", "/tags", nil, "", 200, "Tags.Index layout=main format=html id="},
+		{"json suffix", "GET", "/posts.json", nil, "", 200, "Posts.Index layout=admin format=json id="},
+		{"json member suffix", "GET", "/posts/7.json", nil, "", 200, "Posts.Show layout=admin format=json id=7"},
+		{"accept json", "GET", "/posts/7", map[string]string{"Accept": "application/json"}, "", 200, "Posts.Show layout=admin format=json id=7"},
+		{"page auth", "GET", "/dashboard", nil, "", 401, "text/plain; charset=utf-8"},
+		{"page", "GET", "/dashboard", map[string]string{"X-User": "bob"}, "", 200, "Pages.Dashboard layout=admin format=html id="},
+		{"json auth", "DELETE", "/posts/7", map[string]string{"Accept": "application/json"}, "", 401, "application/problem+json"},
+		{"override auth", "POST", "/posts/7", map[string]string{"Content-Type": form["Content-Type"], "X-User": "bob"}, "_method=DELETE", 403, "text/plain; charset=utf-8"},
+		{"override field", "POST", "/posts/7", form, "_method=DELETE", 200, "Posts.Destroy layout=admin format=html id=7"},
+		{"override header", "POST", "/posts/7", map[string]string{"X-HTTP-Method-Override": "PATCH", "X-User": "bob"}, "", 200, "Posts.Update layout=admin format=html id=7"},
+	}
+	type request struct {
+		Method string
+		Path   string
+		Header map[string]string
+		Body   string
+	}
+	var requests []request
+	for _, tt := range tests {
+		requests = append(requests, request{tt.method, tt.path, tt.header, tt.body})
+	}
+	var responses []struct {
+		Status int    `json:"status"`
+		Type   string `json:"type"`
+		Body   string `json:"body"`
+	}
+	runGenerated(t, app, false, routerProgram, requests, &responses)
+	if len(responses) != len(tests) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(tests))
+	}
+
+	for i, tt := range tests {
+		got := responses[i]
+		// Failed requests are told apart by their content type
+		answer := got.Body
+		if tt.status != 200 {
+			answer = got.Type
+		}
+		if got.Status != tt.status || answer != tt.want {
+			t.Errorf("%s: %s %s = %d %q, want %d %q", tt.name, tt.method, tt.path, got.Status, answer, tt.status, tt.want)
+		}
+	}
+}
+
+func TestNestedResourceRoutes(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts"}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+	comments := &expr.ResourceExpr{Name: "comments", Parent: posts}
+	likes := &expr.ResourceExpr{Name: "likes", Parent: comments, Actions: []string{"index", "create"}}
+	app := &expr.AppExpr{
+		Name:      "testapp",
+		Resources: []*expr.ResourceExpr{posts, comments, likes, {Name: "comments", Actions: []string{"index"}}},
+	}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	tmpDir := t.TempDir()
+	if err := codegen.NewInterfaceGenerator(app, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	router, err := os.ReadFile(filepath.Join(tmpDir, "http/router.go"))
+	if err != nil {
+		t.Fatalf("Failed to read router: %v", err)
+	}
+	for _, want := range []string{
+		"\tComments interfaces.CommentsController\n",
+		"\tPostComments interfaces.PostCommentsController\n",
+		"\tPostCommentLikes interfaces.PostCommentLikesController\n",
+		`"GET /comments", c.Comments.Index)`,
+		`"GET /posts/{post_id}/comments/{id}/edit", c.PostComments.Edit)`,
+		`"POST /posts/{post_id}/comments", c.PostComments.Create)`,
+		`"GET /posts/{post_id}/comments/{comment_id}/likes", c.PostCommentLikes.Index)`,
+	} {
+		if !strings.Contains(string(router), want) {
+			t.Errorf("router should contain %q, got:\n%s", want, router)
+		}
+	}
+
+	controller, err := os.ReadFile(filepath.Join(tmpDir, "interfaces/post_comment_likes_controller.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller interface: %v", err)
+	}
+	for _, want := range []string{
+		"type PostCommentLikesController interface {",
+		"func PostCommentLikesPostID(r *http.Request) (int64, error) {",
+		"func PostCommentLikesParentID(r *http.Request) (string, error) {\n\treturn r.PathValue(\"comment_id\"), nil\n}",
+		"func PostCommentLikesPath(postID, commentID string) string {",
+		`return "/posts/" + url.PathEscape(postID) + "/comments/" + url.PathEscape(commentID) + "/likes"`,
+	} {
+		if !strings.Contains(string(controller), want) {
+			t.Errorf("likes controller interface should contain %q, got:\n%s", want, controller)
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(tmpDir, "interfaces/comments_controller.go")); err != nil || strings.Contains(string(content), "ParentID") {
+		t.Errorf("top-level comments controller should have no accessors, got %v:\n%s", err, content)
+	}
+
+	gen := codegen.NewExampleGenerator(app)
+	gen.OutputDir = t.TempDir()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	example, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/post_comments.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	for _, want := range []string{
+		"func NewPostComments() interfaces.PostCommentsController {",
+		`c.Render(w, r, "post_comments/index"`,
+		`"Path": interfaces.PostCommentsPath(r.PathValue("post_id")),`,
+		`c.Redirect(w, r, interfaces.PostCommentsPath(r.PathValue("post_id")))`,
+	} {
+		if !strings.Contains(string(example), want) {
+			t.Errorf("post comments controller should contain %q, got:\n%s", want, example)
+		}
+	}
+	view, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/views/post_comments/index.html"))
+	if err != nil {
+		t.Fatalf("Failed to read view: %v", err)
+	}
+	if !strings.Contains(string(view), `<a href="{{$.Path}}/new"`) {
+		t.Errorf("nested index view should link to the Path of the request, got:\n%s", view)
+	}
+}
+
+// nestedProgram serves requests with the example app generated in
+// TestNestedResourceScoping.
+const nestedProgram = `package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"testapp/app/controllers"
+	genhttp "testapp/gen/http"
+)
+
+func main() {
+	serve(genhttp.MountRoutes(http.NewServeMux(), genhttp.Controllers{
+		Posts:        controllers.NewPosts(),
+		PostComments: controllers.NewPostComments(),
+		Notes:        controllers.NewNotes(),
+	}))
+}
+` + serveFunc
+
+// TestNestedResourceScoping compiles an example app and checks that nested
+// records are only reached through their own parent, which must exist.
+func TestNestedResourceScoping(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts", Formats: []string{"html", "json"}}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+	comments := &expr.ResourceExpr{Name: "comments", Parent: posts, Formats: []string{"html", "json"}}
+	comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "body", Type: expr.String}}}
+	notes := &expr.ResourceExpr{Name: "notes", BelongsTo: "post", Formats: []string{"html", "json"}}
+	notes.Model = &expr.ModelExpr{Name: "Note", Resource: notes, Fields: []*expr.AttributeExpr{{Name: "text", Type: expr.String}}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, comments, notes}}
+	app.Prepare()
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	json := map[string]string{"Accept": "application/json"}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{"create post", "POST", "/posts", 201, `"id":1`},
+		{"create other post", "POST", "/posts", 201, `"id":2`},
+		{"create comment", "POST", "/posts/1/comments", 201, `"post_id":1`},
+		{"create comment of missing post", "POST", "/posts/9/comments", 404, ""},
+		{"list comments", "GET", "/posts/1/comments", 200, `"total":1`},
+		{"list comments of other post", "GET", "/posts/2/comments", 200, `"total":0`},
+		{"list comments of missing post", "GET", "/posts/9/comments", 404, ""},
+		{"show comment", "GET", "/posts/1/comments/1", 200, `"id":1`},
+		{"show comment of other post", "GET", "/posts/2/comments/1", 404, ""},
+		{"delete comment of other post", "DELETE", "/posts/2/comments/1", 404, ""},
+		{"comment survives", "GET", "/posts/1/comments/1", 200, `"id":1`},
+		{"create note", "POST", "/posts/1/notes", 201, `"post_id":1`},
+		{"create note of missing post", "POST", "/posts/9/notes", 404, ""},
+		{"list notes", "GET", "/posts/1/notes", 200, `"total":1`},
+		{"list notes of other post", "GET", "/posts/2/notes", 200, `"total":0`},
+		{"list notes of missing post", "GET", "/posts/9/notes", 404, ""},
+		{"show shallow note", "GET", "/notes/1", 200, `"post_id":1`},
+	}
+	type request struct {
+		Method string
+		Path   string
+		Header map[string]string
+	}
+	var requests []request
+	for _, tt := range tests {
+		requests = append(requests, request{tt.method, tt.path, json})
+	}
+	var responses []struct {
+		Status int    `json:"status"`
+		Body   string `json:"body"`
+	}
+	runGenerated(t, app, true, nestedProgram, requests, &responses)
+	if len(responses) != len(tests) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(tests))
+	}
+
+	for i, tt := range tests {
+		got := responses[i]
+		if got.Status != tt.status || !strings.Contains(got.Body, tt.want) {
+			t.Errorf("%s: %s %s = %d %q, want %d with %q", tt.name, tt.method, tt.path, got.Status, got.Body, tt.status, tt.want)
+		}
+	}
+}
+
+// formsProgram serves requests with the example app generated in
+// TestExampleFormBinding.
+const formsProgram = `package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"testapp/app/controllers"
+	genhttp "testapp/gen/http"
+)
+
+func main() {
+	serve(genhttp.MountRoutes(http.NewServeMux(), genhttp.Controllers{
+		Posts:   controllers.NewPosts(),
+		Reviews: controllers.NewReviews(),
+	}))
+}
+` + serveFunc
+
+// TestExampleFormBinding compiles an example app and checks that JSON
+// bodies are answered in JSON, that updates keep the fields they omit, and
+// that records with a string primary key get one on create.
+func TestExampleFormBinding(t *testing.T) {
+	fields := func() []*expr.AttributeExpr {
+		return []*expr.AttributeExpr{{Name: "title", Type: expr.String}, {Name: "body", Type: expr.String}}
+	}
+	posts := &expr.ResourceExpr{Name: "posts", Formats: []string{"html", "json"}}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: fields()}
+	posts.Forms = map[string]*expr.FormExpr{
+		"NewPostsForm":  {Name: "NewPostsForm", Attributes: fields()},
+		"EditPostsForm": {Name: "EditPostsForm", Attributes: fields()},
+	}
+	reviews := &expr.ResourceExpr{Name: "reviews", Formats: []string{"html", "json"}}
+	reviews.Model = &expr.ModelExpr{Name: "Review", Resource: reviews, Fields: []*expr.AttributeExpr{
+		{Name: "id", Type: expr.String, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+		{Name: "text", Type: expr.String},
+	}}
+	reviews.Forms = map[string]*expr.FormExpr{
+		"NewReviewsForm": {Name: "NewReviewsForm", Attributes: []*expr.AttributeExpr{{Name: "text", Type: expr.String}}},
+	}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, reviews}}
+	app.Prepare()
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	jsonBody := map[string]string{"Content-Type": "application/json"}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		body   string
+		status int
+		want   string
+	}{
+		{"create without accept", "POST", "/posts", jsonBody, `{"title":"Hello","body":"World"}`, 201, `"title":"Hello"`},
+		{"partial update", "PATCH", "/posts/1", jsonBody, `{"title":"Hi"}`, 200, `"body":"World"`},
+		{"show", "GET", "/posts/1", map[string]string{"Accept": "application/json"}, "", 200, `"title":"Hi"`},
+		{"create from form", "POST", "/posts", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "title=Form", 303, ""},
+		{"clear from form", "PATCH", "/posts/2", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "title=&body=", 303, ""},
+		{"show cleared", "GET", "/posts/2.json", nil, "", 200, `"title":""`},
+		{"create with string key", "POST", "/reviews", jsonBody, `{"text":"Great"}`, 201, `"text":"Great"`},
+		{"list string keys", "GET", "/reviews.json", nil, "", 200, `"total":1`},
+	}
+	type request struct {
+		Method string
+		Path   string
+		Header map[string]string
+		Body   string
+	}
+	var requests []request
+	for _, tt := range tests {
+		requests = append(requests, request{tt.method, tt.path, tt.header, tt.body})
+	}
+	var responses []struct {
+		Status int    `json:"status"`
+		Body   string `json:"body"`
+	}
+	runGenerated(t, app, true, formsProgram, requests, &responses)
+	if len(responses) != len(tests) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(tests))
+	}
+
+	for i, tt := range tests {
+		got := responses[i]
+		if got.Status != tt.status || !strings.Contains(got.Body, tt.want) {
+			t.Errorf("%s: %s %s = %d %q, want %d with %q", tt.name, tt.method, tt.path, got.Status, got.Body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestShallowResourceRoutes(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts"}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts}
+	comments := &expr.ResourceExpr{Name: "comments", BelongsTo: "post"}
+	comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "body", Type: expr.String}}}
+	notes := &expr.ResourceExpr{Name: "notes", BelongsTo: "post"}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, comments, notes}}
+	app.Prepare()
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	router, legacy := generateRouters(t, app)
+	for name, code := range map[string]string{"interface router": router, "router": legacy} {
+		for _, want := range []string{
+			`"GET /posts/{post_id}/comments", c.Comments.Index)`,
+			`"GET /posts/{post_id}/comments/new", c.Comments.New)`,
+			`"POST /posts/{post_id}/comments", c.Comments.Create)`,
+			`"GET /comments/{id}", c.Comments.Show)`,
+			`"GET /comments/{id}/edit", c.Comments.Edit)`,
+			`"DELETE /comments/{id}", c.Comments.Destroy)`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, code)
+			}
+		}
+	}
+	models, err := codegen.NewTypesGenerator(app).GenerateModels()
+	if err != nil {
+		t.Fatalf("GenerateModels() failed: %v", err)
+	}
+	if !strings.Contains(models, "PostID int64 `json:\"post_id\" db:\"post_id\"`") {
+		t.Errorf("Comment should have a post_id foreign key, got:\n%s", models)
+	}
+
+	gen := codegen.NewExampleGenerator(app)
+	gen.OutputDir = t.TempDir()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	controller, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/comments.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	for _, want := range []string{
+		"postID, err := interfaces.CommentsParentID(r)",
+		"comment.PostID = postID",
+		`c.Redirect(w, r, "/comments/"+strconv.FormatInt(comment.ID, 10))`,
+		`"Path": interfaces.CommentsPath(strconv.FormatInt(comment.PostID, 10)),`,
+		"c.Redirect(w, r, interfaces.CommentsPath(strconv.FormatInt(comment.PostID, 10)))",
+	} {
+		if !strings.Contains(string(controller), want) {
+			t.Errorf("comments controller should contain %q, got:\n%s", want, controller)
+		}
+	}
+	view, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/views/comments/show.html"))
+	if err != nil {
+		t.Fatalf("Failed to read view: %v", err)
+	}
+	for _, want := range []string{`<a href="/comments/{{.ID}}/edit"`, `<a href="{{$.Path}}">Back to List</a>`} {
+		if !strings.Contains(string(view), want) {
+			t.Errorf("shallow show view should contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// Without a model the member actions don't know the post, so they
+	// don't make one up
+	notesController, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/notes.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	if strings.Contains(string(notesController), `interfaces.NotesPath("`) {
+		t.Errorf("notes controller should not link to the notes of a sample post, got:\n%s", notesController)
+	}
+	for _, want := range []string{`"Path": "/",`, `c.Redirect(w, r, "/")`} {
+		if !strings.Contains(string(notesController), want) {
+			t.Errorf("notes controller should contain %q, got:\n%s", want, notesController)
+		}
+	}
+}
+
+func TestCustomActionRoutes(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts", Formats: []string{"html", "json"}}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+	posts.Forms = map[string]*expr.FormExpr{"PublishForm": {Name: "PublishForm", Attributes: []*expr.AttributeExpr{{Name: "note", Type: expr.String}}}}
+	posts.CustomActions = []string{"publish", "search"}
+	posts.ActionConfigs = map[string]*expr.ActionConfig{
+		"publish": {Action: "publish", Method: "POST", Member: true, FormName: "PublishForm", Resource: posts},
+		"search":  {Action: "search", Method: "GET", Params: []*expr.ParamExpr{{Name: "q", Type: expr.String}}, Resource: posts},
+	}
+	posts.AuthExcept = map[string][]string{"authenticated": {"index", "show", "search"}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts}}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	tmpDir := t.TempDir()
+	if err := codegen.NewInterfaceGenerator(app, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	router, err := os.ReadFile(filepath.Join(tmpDir, "http/router.go"))
+	if err != nil {
+		t.Fatalf("Failed to read router: %v", err)
+	}
+	// Routes are sorted by path, literals before wildcards
+	var last int
+	for _, want := range []string{
+		`"GET /posts/new"`,
+		`"GET /posts/search", runtime.UseFormats([]string{"html", "json"}, c.Posts.Search))`,
+		`"GET /posts/{id}"`,
+		`"GET /posts/{id}/edit"`,
+		`"POST /posts/{id}/publish", runtime.UseFormats([]string{"html", "json"}, auth("posts", "publish", c.Posts.Publish, "authenticated")))`,
+	} {
+		at := strings.Index(string(router), want)
+		if at < last {
+			t.Errorf("router should contain %q after the previous routes, got:\n%s", want, router)
+		}
+		last = at
+	}
+	legacy, err := codegen.NewRouterGenerator(app).Generate()
+	if err != nil {
+		t.Fatalf("RouterGenerator.Generate() failed: %v", err)
+	}
+	for _, want := range []string{`"POST /posts/{id}/publish"`, `"GET /posts/search"`} {
+		if !strings.Contains(legacy, want) {
+			t.Errorf("legacy router should contain %q, got:\n%s", want, legacy)
+		}
+	}
+
+	iface, err := os.ReadFile(filepath.Join(tmpDir, "interfaces/posts_controller.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller interface: %v", err)
+	}
+	for _, want := range []string{
+		"\t// Publish handles POST /posts/{id}/publish\n\tPublish(w http.ResponseWriter, r *http.Request)\n",
+		"\t// Search handles GET /posts/search\n\tSearch(w http.ResponseWriter, r *http.Request)\n",
+	} {
+		if !strings.Contains(string(iface), want) {
+			t.Errorf("controller interface should contain %q, got:\n%s", want, iface)
+		}
+	}
+	types, err := os.ReadFile(filepath.Join(tmpDir, "types/forms.go"))
+	if err != nil {
+		t.Fatalf("Failed to read types: %v", err)
+	}
+	if !strings.Contains(string(types), "type PostsSearchParams struct {") {
+		t.Errorf("types should declare the search params, got:\n%s", types)
+	}
+
+	gen := codegen.NewExampleGenerator(app)
+	gen.OutputDir = t.TempDir()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	controller, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/controllers/posts.go"))
+	if err != nil {
+		t.Fatalf("Failed to read controller: %v", err)
+	}
+	for _, want := range []string{
+		"func (c *postsController) Publish(w http.ResponseWriter, r *http.Request) {\n\tpost, ok := c.find(w, r)\n",
+		"\tform := types.NewPublishForm()\n",
+		"\t\tc.Flash(w, r, \"error\", errs.Error())\n",
+		"\t\truntime.WriteJSON(w, http.StatusOK, post)\n",
+		"\tc.Redirect(w, r, \"/posts/\"+strconv.FormatInt(post.ID, 10))\n",
+		"\tparams := &types.PostsSearchParams{}\n",
+		"\tc.Respond(w, r, \"posts/search\", map[string]interface{}{\n\t\t\"Title\": \"Search Posts\",\n\t\t\"Params\": params,\n",
+	} {
+		if !strings.Contains(string(controller), want) {
+			t.Errorf("controller should contain %q, got:\n%s", want, controller)
+		}
+	}
+
+	views := map[string][]string{
+		"search.html": {`<h1>Search Posts</h1>`, `<input type="text" id="q" name="q" value="{{.Params.Q}}">`},
+		"show.html":   {`{{button_to "Publish" (printf "/posts/%v/publish" .ID) "POST" $.CSRFToken "class=\"btn\""}}`},
+		"index.html":  {`<a href="/posts/search" class="btn">Search</a>`},
+	}
+	for name, wants := range views {
+		view, err := os.ReadFile(filepath.Join(gen.OutputDir, "app/views/posts", name))
+		if err != nil {
+			t.Fatalf("Failed to read view: %v", err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(view), want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, view)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(gen.OutputDir, "app/views/posts/publish.html")); err == nil {
+		t.Error("POST actions should have no view")
+	}
+}
+
+func TestRouteTable(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts"}
+	posts.Prepare()
+	about := &expr.PageExpr{Name: "about", Routes: []expr.RouteExpr{{Method: "GET", Path: "/about"}}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts}, Pages: []*expr.PageExpr{about}}
+
+	table, err := codegen.NewRouteTable(app)
+	if err != nil {
+		t.Fatalf("NewRouteTable() failed: %v", err)
+	}
+	var patterns []string
+	for _, route := range table.Routes {
+		patterns = append(patterns, route.Pattern())
+	}
+	want := []string{
+		"GET /about",
+		"GET /posts",
+		"POST /posts",
+		"GET /posts/new",
+		"GET /posts/{id}",
+		"PATCH /posts/{id}",
+		"PUT /posts/{id}",
+		"DELETE /posts/{id}",
+		"GET /posts/{id}/edit",
+		"GET /static/",
+	}
+	if !slices.Equal(patterns, want) {
+		t.Errorf("routes = %q, want %q", patterns, want)
+	}
+	if route := table.Routes[4]; route.Controller != "Posts.Show" || route.Source != `resource "posts" action "show"` {
+		t.Errorf("show route = %+v", route)
+	}
+
+	tests := []struct {
+		name string
+		page expr.RouteExpr
+		want string
+	}{
+		{
+			"duplicate",
+			expr.RouteExpr{Method: "GET", Path: "/posts/new"},
+			`duplicate route "GET /posts/new" of resource "posts" action "new" and "GET /posts/new" of page "clash"`,
+		},
+		{
+			"ambiguous",
+			expr.RouteExpr{Method: "GET", Path: "/{section}/edit"},
+			`ambiguous routes "GET /posts/{id}" of resource "posts" action "show" and "GET /{section}/edit" of page "clash": both match GET /posts/edit and neither is more specific`,
+		},
+		{
+			"invalid",
+			expr.RouteExpr{Method: "GET", Path: "/x{y}"},
+			`invalid route "GET /x{y}" of page "clash"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clash := &expr.PageExpr{Name: "clash", Routes: []expr.RouteExpr{tt.page}}
+			app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts}, Pages: []*expr.PageExpr{clash}}
+			if _, err := codegen.NewRouteTable(app); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewRouteTable() error = %v, want %q", err, tt.want)
+			}
+			dir := t.TempDir()
+			if err := codegen.NewInterfaceGenerator(app, filepath.Join(dir, "gen")).Generate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate() error = %v, want %q", err, tt.want)
+			}
+			example := codegen.NewExampleGenerator(app)
+			example.OutputDir = dir
+			if err := example.Generate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("example Generate() error = %v, want %q", err, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+				t.Errorf("generators should not write files for an invalid design, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestRouteTableOutput(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts", Actions: []string{"index", "create"}}
+	posts.ActionConfigs = map[string]*expr.ActionConfig{"create": {Action: "create", FormName: "PostForm"}}
+	posts.AuthRequirements = map[string][]string{"create": {"authenticated"}}
+	posts.Prepare()
+	app := &expr.AppExpr{
+		Name:      "testapp",
+		Resources: []*expr.ResourceExpr{posts},
+		Forms:     []*expr.FormExpr{{Name: "PostForm"}},
+	}
+	table, err := codegen.NewRouteTable(app)
+	if err != nil {
+		t.Fatalf("NewRouteTable() failed: %v", err)
+	}
+
+	var text strings.Builder
+	if err := table.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() failed: %v", err)
+	}
+	want := "METHOD  PATH      CONTROLLER    AUTH           MIDDLEWARE  FORM\n" +
+		"GET     /posts    Posts.Index   -              -           -\n" +
+		"POST    /posts    Posts.Create  authenticated  -           PostForm\n" +
+		"GET     /static/  -             -              -           -\n"
+	if text.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	filtered, err := table.Filter("postform|AUTHENTICATED")
+	if err != nil {
+		t.Fatalf("Filter() failed: %v", err)
+	}
+	var out strings.Builder
+	if err := filtered.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var routes []map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &routes); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(routes) != 1 || routes[0]["path"] != "/posts" || routes[0]["controller"] != "Posts.Create" || routes[0]["form"] != "PostForm" {
+		t.Errorf("filtered routes = %v, want the create route", routes)
+	}
+	if _, ok := routes[0]["Handler"]; ok {
+		t.Errorf("WriteJSON() should not write handlers, got %v", routes[0])
+	}
+
+	if _, err := table.Filter("("); err == nil {
+		t.Error("Filter() should reject invalid patterns")
+	}
+}
+
+// TestRouteTableForms checks that routes only list the forms their actions
+// bind, not the conventional names of forms that are not declared.
+func TestRouteTableForms(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts"}
+	posts.Forms = map[string]*expr.FormExpr{"PostForm": {Name: "PostForm"}}
+	posts.Prepare()
+	comments := &expr.ResourceExpr{Name: "comments", Actions: []string{"new", "create", "edit", "update"}}
+	comments.ActionConfigs = map[string]*expr.ActionConfig{"update": {Action: "update", FormName: "CommentForm"}}
+	comments.Prepare()
+	app := &expr.AppExpr{
+		Name:      "testapp",
+		Resources: []*expr.ResourceExpr{posts, comments},
+		Forms:     []*expr.FormExpr{{Name: "CommentForm"}, {Name: "EditPostsForm"}},
+	}
+	table, err := codegen.NewRouteTable(app)
+	if err != nil {
+		t.Fatalf("NewRouteTable() failed: %v", err)
+	}
+
+	forms := map[string]string{}
+	for _, route := range table.Routes {
+		forms[route.Pattern()] = route.Form
+	}
+	for pattern, want := range map[string]string{
+		"GET /posts/new":          "",
+		"POST /posts":             "",
+		"GET /posts/{id}/edit":    "EditPostsForm",
+		"PATCH /posts/{id}":       "EditPostsForm",
+		"GET /comments/new":       "",
+		"POST /comments":          "",
+		"GET /comments/{id}/edit": "CommentForm",
+		"PUT /comments/{id}":      "CommentForm",
+	} {
+		if got, ok := forms[pattern]; !ok || got != want {
+			t.Errorf("form of %s = %q, want %q", pattern, got, want)
+		}
+	}
 }
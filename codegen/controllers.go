@@ -60,7 +60,8 @@ func (g *ControllersGenerator) GenerateResource(resource *expr.ResourceExpr) (st
 	buf.WriteString(fmt.Sprintf("type %s interface {\n", controllerName))
 
 	// Generate method signatures for each action
-	for _, action := range resource.Actions {
+	actions := resource.ActionNames()
+	for i, action := range actions {
 		methodName := g.toMethodName(action)
 		signature := g.getMethodSignature(action)
 		comment := g.getMethodComment(action, resource.Name)
@@ -68,7 +69,7 @@ func (g *ControllersGenerator) GenerateResource(resource *expr.ResourceExpr) (st
 		buf.WriteString(fmt.Sprintf("\t// %s\n", comment))
 		buf.WriteString(fmt.Sprintf("\t%s%s\n", methodName, signature))
 
-		if action != resource.Actions[len(resource.Actions)-1] {
+		if i < len(actions)-1 {
 			buf.WriteString("\n")
 		}
 	}
@@ -123,7 +124,7 @@ func (g *ControllersGenerator) toControllerName(resourceName string) string {
 
 // toMethodName converts an action to a method name.
 func (g *ControllersGenerator) toMethodName(action string) string {
-	return ToTitle(action)
+	return ToCamelCase(action)
 }
 
 // toPageMethodName converts a page name and method to a method name.
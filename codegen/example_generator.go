@@ -1,9 +1,12 @@
 package codegen
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gobijan/gluey/expr"
@@ -26,6 +29,11 @@ func NewExampleGenerator(app *expr.AppExpr) *ExampleGenerator {
 
 // Generate generates example implementations.
 func (g *ExampleGenerator) Generate() error {
+	// Reject invalid designs before writing any file
+	if err := Validate(g.app); err != nil {
+		return err
+	}
+
 	// Create app directories if they don't exist
 	dirs := []string{
 		filepath.Join(g.OutputDir, "app/controllers"),
@@ -35,7 +43,12 @@ func (g *ExampleGenerator) Generate() error {
 
 	// Add view directories for each resource
 	for _, resource := range g.app.Resources {
-		dirs = append(dirs, filepath.Join(g.OutputDir, "app/views", resource.Name))
+		dirs = append(dirs, filepath.Join(g.OutputDir, "app/views", resource.QualifiedName()))
+	}
+
+	// Add the repositories directory if any resource has a model
+	if NewTypesGenerator(g.app).HasModels() {
+		dirs = append(dirs, filepath.Join(g.OutputDir, "app/repositories"))
 	}
 
 	for _, dir := range dirs {
@@ -49,6 +62,16 @@ func (g *ExampleGenerator) Generate() error {
 		return err
 	}
 
+	// Generate in-memory repositories for models (if don't exist)
+	for _, resource := range g.app.Resources {
+		if resource.Model == nil {
+			continue
+		}
+		if err := g.generateMemoryRepository(resource); err != nil {
+			return err
+		}
+	}
+
 	// Generate example controllers (if don't exist)
 	for _, resource := range g.app.Resources {
 		if err := g.generateResourceController(resource); err != nil {
@@ -71,6 +94,9 @@ func (g *ExampleGenerator) Generate() error {
 	fmt.Println("✅ Example files generated in app/")
 	fmt.Println("\nCreated:")
 	fmt.Println("  - app/controllers/ - Example controller implementations")
+	if NewTypesGenerator(g.app).HasModels() {
+		fmt.Println("  - app/repositories/ - In-memory repositories for your models")
+	}
 	fmt.Println("  - app/views/ - HTML templates")
 	fmt.Println("\nThese files are yours to modify. They won't be overwritten.")
 
@@ -94,99 +120,93 @@ func (g *ExampleGenerator) generateBaseController() error {
 	content := `package controllers
 
 import (
-	"html/template"
+	"bytes"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
+
+	"github.com/gobijan/gluey/runtime"
+
+	"` + g.app.Name + `/app/views"
 )
 
 // BaseController provides common functionality for all controllers.
 type BaseController struct {
-	templates *template.Template
+	views *runtime.TemplateEngine
 }
 
 // NewBaseController creates a new base controller.
 func NewBaseController() *BaseController {
-	// Load templates with custom functions
-	funcMap := template.FuncMap{
-		"title": strings.Title,
-		"upper": strings.ToUpper,
-		"lower": strings.ToLower,
-	}
-	
-	// Load all template files
-	tmpl := template.New("").Funcs(funcMap)
-	
-	// Load layout templates
-	layoutFiles, _ := filepath.Glob("app/views/layouts/*.html")
-	for _, file := range layoutFiles {
-		content, err := os.ReadFile(file)
-		if err == nil {
-			name := filepath.Base(file)
-			template.Must(tmpl.New(name).Parse(string(content)))
-		}
-	}
-	
-	// Load shared templates  
-	sharedFiles, _ := filepath.Glob("app/views/shared/*.html")
-	for _, file := range sharedFiles {
-		content, err := os.ReadFile(file)
-		if err == nil {
-			name := filepath.Base(file)
-			template.Must(tmpl.New(name).Parse(string(content)))
-		}
+	// Load all templates with the gluey helpers: from disk, reloading edits,
+	// when GLUEY_ENV is development, else those embedded in the binary
+	engine := runtime.NewTemplateEngine()
+	var err error
+	if os.Getenv("GLUEY_ENV") == "development" {
+		engine.Dev = true
+		err = engine.LoadTemplates("app/views")
+	} else {
+		err = engine.LoadFS(views.FS)
 	}
-	
-	// Load view templates
-	viewFiles, _ := filepath.Glob("app/views/*/*.html")
-	for _, file := range viewFiles {
-		if !strings.Contains(file, "/layouts/") && !strings.Contains(file, "/shared/") {
-			content, err := os.ReadFile(file)
-			if err == nil {
-				// Use relative path as template name (e.g., "posts/index.html")
-				name := strings.TrimPrefix(file, "app/views/")
-				template.Must(tmpl.New(name).Parse(string(content)))
-			}
-		}
+	if err != nil {
+		panic(err)
 	}
-	
+
 	return &BaseController{
-		templates: tmpl,
+		views: engine,
+	}
+}
+
+// Render renders a view inside the layout of the request: the one chosen
+// with runtime.WithLayout, else the Layout of the resource or page, else
+// the application layout.
+func (c *BaseController) Render(w http.ResponseWriter, r *http.Request, view string, data map[string]interface{}) {
+	c.render(w, r, http.StatusOK, view, data)
+}
+
+// Forbidden answers requests rejected by the CSRF middleware with the
+// shared/forbidden page, or a problem document for API clients. With the
+// CSRF middleware, pass it as Controllers.CSRFFailure.
+func (c *BaseController) Forbidden(w http.ResponseWriter, r *http.Request, err error) {
+	if runtime.NegotiateFormat(r, runtime.FormatHTML, runtime.FormatJSON) == runtime.FormatJSON {
+		runtime.WriteProblem(w, runtime.Problem{Status: http.StatusForbidden, Detail: err.Error()})
+		return
 	}
+	c.render(w, r, http.StatusForbidden, "shared/forbidden", map[string]interface{}{
+		"Title": "Forbidden",
+	})
 }
 
-// Render renders a template with the given data.
-func (c *BaseController) Render(w http.ResponseWriter, view string, data map[string]interface{}) {
+// render renders a view with the given status.
+func (c *BaseController) render(w http.ResponseWriter, r *http.Request, status int, view string, data map[string]interface{}) {
 	if data == nil {
 		data = make(map[string]interface{})
 	}
 	
 	// Add common data
 	data["AppName"] = "` + ToTitle(g.app.Name) + `"
+	data["Resources"] = []string{` + quoteAll(navResources(g.app)) + `}
+	data["CSRFToken"] = runtime.CSRFToken(r)
+	data["Flash"] = runtime.Flashes(w, r)
 	
-	// Render the view content first
-	var contentBuf strings.Builder
-	if c.templates != nil {
-		err := c.templates.ExecuteTemplate(&contentBuf, view, data)
-		if err != nil {
-			// If the specific view doesn't exist, just use empty content
-			contentBuf.Reset()
-		}
+	// Render fully first so that template errors get a clean 500
+	var buf bytes.Buffer
+	if err := c.views.RenderRequest(&buf, r, view, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	
-	// Add the rendered content to data
-	data["Content"] = template.HTML(contentBuf.String())
-	
-	// Execute the layout with the rendered content
-	if c.templates != nil {
-		err := c.templates.ExecuteTemplate(w, "application.html", data)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	} else {
-		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// Respond answers JSON requests of resources with the json format with
+// payload, the records without the view chrome, and renders view with data
+// for the others.
+func (c *BaseController) Respond(w http.ResponseWriter, r *http.Request, view string, data map[string]interface{}, payload interface{}) {
+	if runtime.WantsJSON(r) {
+		runtime.WriteJSON(w, http.StatusOK, payload)
+		return
 	}
+	c.Render(w, r, view, data)
 }
 
 // Redirect redirects to the given URL.
@@ -194,13 +214,9 @@ func (c *BaseController) Redirect(w http.ResponseWriter, r *http.Request, url st
 	http.Redirect(w, r, url, http.StatusSeeOther)
 }
 
-// Flash sets a flash message cookie.
-func (c *BaseController) Flash(w http.ResponseWriter, typ, message string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:  "flash_" + typ,
-		Value: message,
-		Path:  "/",
-	})
+// Flash adds a flash message shown by the next page.
+func (c *BaseController) Flash(w http.ResponseWriter, r *http.Request, level, message string) {
+	runtime.AddFlash(w, r, level, message)
 }
 `
 
@@ -208,22 +224,62 @@ func (c *BaseController) Flash(w http.ResponseWriter, typ, message string) {
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
+// navResources returns the resources linked from the layout navigation:
+// the top-level ones with an index page.
+func navResources(app *expr.AppExpr) []string {
+	var names []string
+	for _, resource := range app.Resources {
+		if resource.Parent == nil && !resource.Singular && resource.HasAction("index") {
+			names = append(names, resource.Name)
+		}
+	}
+	return names
+}
+
 // generateResourceController generates an example controller for a resource.
 func (g *ExampleGenerator) generateResourceController(resource *expr.ResourceExpr) error {
-	filename := filepath.Join(g.OutputDir, fmt.Sprintf("app/controllers/%s.go", resource.Name))
+	filename := filepath.Join(g.OutputDir, fmt.Sprintf("app/controllers/%s.go", resource.QualifiedName()))
 	if fileExists(filename) {
 		fmt.Printf("  Skipping %s (already exists)\n", filename)
 		return nil
 	}
 
+	if resource.Model != nil && !resource.Singular {
+		fmt.Printf("  Creating %s\n", filename)
+		return os.WriteFile(filename, []byte(g.modelController(resource)), 0644)
+	}
+
 	singular := toSingular(resource.Name)
-	controllerType := resource.Name + "Controller"
+	indexRender, indexPayload := respond(resource, fmt.Sprintf("map[string]interface{}{%q: %s}", resource.Name, resource.Name))
+	showRender, showPayload := respond(resource, singular)
+	controllerType := controllerType(resource)
+	title := resourceTitle(resource)
+	views := resource.QualifiedName()
+	pathData := strings.TrimSuffix(viewPathData(resource, pathExpr(resource, ""), "\n\t\t"), "\n")
+	memberPathData := strings.TrimSuffix(viewPathData(resource, collectionPathExpr(resource, ""), "\n\t\t"), "\n")
+
+	// Pass typed forms to the new and edit views when the design defines them
+	var typesImport, newFormData, editFormData string
+	if form := findForm(g.app, resource, resource.NewFormName()); form != nil {
+		newFormData = fmt.Sprintf("\n\t\t\"Form\": types.New%s(),", form.Name)
+	}
+	if form := findForm(g.app, resource, resource.EditFormName()); form != nil {
+		editFormData = fmt.Sprintf("\n\t\t\"Form\": &types.%s{}, // TODO: populate from the %s", form.Name, singular)
+	}
+	usesForms, usesTypes := g.customActionImports(resource)
+	if newFormData != "" || editFormData != "" || usesTypes {
+		typesImport = fmt.Sprintf("\n\t\"%s/gen/types\"", g.app.Name)
+	}
+	stdImports := "\"net/http\""
+	if usesForms {
+		stdImports = "\"errors\"\n\t\"net/http\"\n\n\t\"github.com/gobijan/gluey/runtime\"\n"
+	}
 
 	content := fmt.Sprintf(`package controllers
 
 import (
-	"net/http"
-	"%s/gen/interfaces"
+	%s
+	"%s/gen/interfaces"%s
 )
 
 // %s handles requests for %s resources.
@@ -246,10 +302,10 @@ func (c *%s) Index(w http.ResponseWriter, r *http.Request) {
 		{"ID": 2, "Name": "Sample %s 2"},
 	}
 	
-	c.Render(w, "%s/index", map[string]interface{}{
+	c.%s(w, r, "%s/index", map[string]interface{}{
 		"Title": "%s",
-		"%s": %s,
-	})
+		"%s": %s,%s
+	}%s)
 }
 
 // Show displays a single %s
@@ -262,16 +318,16 @@ func (c *%s) Show(w http.ResponseWriter, r *http.Request) {
 		"Name": "Sample %s",
 	}
 	
-	c.Render(w, "%s/show", map[string]interface{}{
+	c.%s(w, r, "%s/show", map[string]interface{}{
 		"Title": "%s Details",
-		"%s": %s,
-	})
+		"%s": %s,%s
+	}%s)
 }
 
 // New displays the form for creating a new %s
 func (c *%s) New(w http.ResponseWriter, r *http.Request) {
-	c.Render(w, "%s/new", map[string]interface{}{
-		"Title": "New %s",
+	c.Render(w, r, "%s/new", map[string]interface{}{
+		"Title": "New %s",%s%s
 	})
 }
 
@@ -279,8 +335,8 @@ func (c *%s) New(w http.ResponseWriter, r *http.Request) {
 func (c *%s) Create(w http.ResponseWriter, r *http.Request) {
 	// TODO: Parse form, validate, and save to database
 	
-	c.Flash(w, "success", "%s created successfully!")
-	c.Redirect(w, r, "/%s")
+	c.Flash(w, r, "success", "%s created successfully!")
+	c.Redirect(w, r, %s)
 }
 
 // Edit displays the form for editing a %s
@@ -293,9 +349,9 @@ func (c *%s) Edit(w http.ResponseWriter, r *http.Request) {
 		"Name": "Sample %s",
 	}
 	
-	c.Render(w, "%s/edit", map[string]interface{}{
+	c.Render(w, r, "%s/edit", map[string]interface{}{
 		"Title": "Edit %s",
-		"%s": %s,
+		"%s": %s,%s%s
 	})
 }
 
@@ -305,23 +361,23 @@ func (c *%s) Update(w http.ResponseWriter, r *http.Request) {
 	
 	// TODO: Parse form, validate, and update in database
 	
-	c.Flash(w, "success", "%s updated successfully!")
-	c.Redirect(w, r, "/%s/"+id)
+	c.Flash(w, r, "success", "%s updated successfully!")
+	c.Redirect(w, r, %s+id)
 }
 
 // Destroy handles deleting a %s
 func (c *%s) Destroy(w http.ResponseWriter, r *http.Request) {
 	// TODO: Delete from database
 	
-	c.Flash(w, "success", "%s deleted successfully!")
-	c.Redirect(w, r, "/%s")
+	c.Flash(w, r, "success", "%s deleted successfully!")
+	c.Redirect(w, r, %s)
 }
 `,
-		g.app.Name,
+		stdImports, g.app.Name, typesImport,
 		controllerType, resource.Name,
 		controllerType,
-		ToTitle(resource.Name), resource.Name,
-		ToTitle(resource.Name), ToTitle(resource.Name),
+		title, resource.Name,
+		title, title,
 		controllerType,
 		resource.Name,
 		controllerType,
@@ -329,47 +385,735 @@ func (c *%s) Destroy(w http.ResponseWriter, r *http.Request) {
 		resource.Name,
 		ToTitle(singular),
 		ToTitle(singular),
-		resource.Name,
+		indexRender, views,
 		ToTitle(resource.Name),
-		ToTitle(resource.Name), resource.Name,
+		ToTitle(resource.Name), resource.Name, pathData, indexPayload,
 		resource.Name,
 		controllerType,
 		singular,
 		singular,
 		ToTitle(singular),
-		resource.Name,
+		showRender, views,
 		ToTitle(singular),
-		ToTitle(singular), singular,
+		ToTitle(singular), singular, memberPathData, showPayload,
 		resource.Name,
 		controllerType,
-		resource.Name,
-		ToTitle(singular),
+		views,
+		ToTitle(singular), newFormData, pathData,
 		resource.Name,
 		controllerType,
 		ToTitle(singular),
-		resource.Name,
+		pathExpr(resource, ""),
 		resource.Name,
 		controllerType,
 		singular,
 		singular,
 		ToTitle(singular),
-		resource.Name,
+		views,
 		ToTitle(singular),
-		ToTitle(singular), singular,
+		ToTitle(singular), singular, editFormData, memberPathData,
 		resource.Name,
 		controllerType,
 		ToTitle(singular),
-		resource.Name,
+		memberPathExpr(resource, "/"),
 		resource.Name,
 		controllerType,
 		ToTitle(singular),
-		resource.Name,
+		collectionPathExpr(resource, ""),
 	)
+	content += g.customActions(resource)
 
 	fmt.Printf("  Creating %s\n", filename)
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
+// modelController returns an example controller for a resource with a
+// model. It serves every action from the model's repository, using the
+// in-memory repository unless another one is passed in.
+func (g *ExampleGenerator) modelController(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	pk := model.PrimaryKey()
+	types := NewTypesGenerator(g.app)
+	idType := types.goType(pk.Type)
+	idField := ToFieldName(pk.Name)
+	singular := toSingular(resource.Name)
+	title := ToTitle(resource.Name)
+	singularTitle := ToTitle(singular)
+	controllerType := controllerType(resource)
+	ctor := resourceTitle(resource)
+	views := resource.QualifiedName()
+	memberPathData := strings.TrimSuffix(viewPathData(resource, collectionPathExpr(resource, singular), "\n\t\t"), "\n")
+	isString := pk.Type.Kind() == expr.StringKind
+	fk := resource.ForeignKey()
+	isJSON := resource.HasFormat(expr.FormatJSON)
+
+	// Scoped controllers check that the parent of the path exists in the
+	// repository of its model
+	var parentModel, parentRepo string
+	if fk != nil && resource.Parent.Model != nil {
+		parentModel = resource.Parent.Model.Name
+		parentRepo = lowerFirst(ToCamelCase(resource.Parent.Name))
+	}
+
+	buf.WriteString("package controllers\n\n")
+	buf.WriteString("import (\n")
+	buf.WriteString("\t\"errors\"\n")
+	buf.WriteString("\t\"net/http\"\n")
+	if isString {
+		buf.WriteString("\t\"net/url\"\n")
+	}
+	if !isString || resource.Shallow && fk != nil && fk.Type.Kind() != expr.StringKind {
+		buf.WriteString("\t\"strconv\"\n")
+	}
+	buf.WriteString("\n\t\"github.com/gobijan/gluey/runtime\"\n\n")
+	buf.WriteString(fmt.Sprintf("\t\"%s/app/repositories\"\n", g.app.Name))
+	buf.WriteString(fmt.Sprintf("\t\"%s/gen/interfaces\"\n", g.app.Name))
+	buf.WriteString(fmt.Sprintf("\t\"%s/gen/types\"\n", g.app.Name))
+	buf.WriteString(")\n\n")
+
+	// Parents share their in-memory repository with their nested resources
+	memoryRepo := fmt.Sprintf("repositories.New%sMemoryRepository()", model.Name)
+	if hasScopedChildren(g.app, resource) {
+		memoryRepo = fmt.Sprintf("repositories.Shared%sMemoryRepository", model.Name)
+	}
+
+	if parentModel == "" {
+		buf.WriteString(fmt.Sprintf(`// %s handles requests for %s resources.
+type %s struct {
+	BaseController
+	repo types.%sRepository
+}
+
+// New%s creates a new %s controller backed by an in-memory repository.
+func New%s() interfaces.%sController {
+	return New%sWithRepository(%s)
+}
+
+// New%sWithRepository creates a new %s controller backed by repo.
+func New%sWithRepository(repo types.%sRepository) interfaces.%sController {
+	return &%s{
+		BaseController: *NewBaseController(),
+		repo:           repo,
+	}
+}
+
+`,
+			controllerType, resource.Name,
+			controllerType,
+			model.Name,
+			ctor, resource.Name,
+			ctor, ctor,
+			ctor, memoryRepo,
+			ctor, resource.Name,
+			ctor, model.Name, ctor,
+			controllerType,
+		))
+	} else {
+		buf.WriteString(fmt.Sprintf(`// %s handles requests for %s resources.
+type %s struct {
+	BaseController
+	repo types.%sRepository
+	// %s holds the %s the %s belong to.
+	%s types.%sRepository
+}
+
+// New%s creates a new %s controller backed by in-memory
+// repositories, sharing the %s of New%s.
+func New%s() interfaces.%sController {
+	return New%sWithRepository(%s, repositories.Shared%sMemoryRepository)
+}
+
+// New%sWithRepository creates a new %s controller backed by
+// repo, looking up the %s they belong to in %s.
+func New%sWithRepository(repo types.%sRepository, %s types.%sRepository) interfaces.%sController {
+	return &%s{
+		BaseController: *NewBaseController(),
+		repo:           repo,
+		%s: %s,
+	}
+}
+
+`,
+			controllerType, resource.Name,
+			controllerType,
+			model.Name,
+			parentRepo, resource.Parent.Name, resource.Name,
+			parentRepo, parentModel,
+			ctor, resource.Name,
+			resource.Parent.Name, resourceTitle(resource.Parent),
+			ctor, ctor,
+			ctor, memoryRepo, parentModel,
+			ctor, resource.Name,
+			resource.Parent.Name, parentRepo,
+			ctor, model.Name, parentRepo, parentModel, ctor,
+			controllerType,
+			parentRepo, parentRepo,
+		))
+	}
+
+	// Index
+	buf.WriteString(fmt.Sprintf("// Index displays a list of %s\n", resource.Name))
+	buf.WriteString(fmt.Sprintf("func (c *%s) Index(w http.ResponseWriter, r *http.Request) {\n", controllerType))
+	listArgs := "r.Context()"
+	if fk != nil {
+		parentID := pathArg(fk.Name)
+		buf.WriteString(fmt.Sprintf("\t%s, ok := c.findParent(w, r)\n", parentID))
+		buf.WriteString("\tif !ok {\n")
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n\n")
+		listArgs += ", " + parentID
+	}
+	params, hasParams := indexParamsName(resource)
+	if hasParams {
+		buf.WriteString(fmt.Sprintf("\tparams := &types.%s{}\n", params))
+		buf.WriteString("\tif err := params.Bind(r); err != nil {\n")
+		buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n")
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n\n")
+		listArgs += ", params"
+	}
+	buf.WriteString(fmt.Sprintf("\t%s, total, err := c.repo.List(%s)\n", resource.Name, listArgs))
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+	// API clients get the list and its pagination
+	payload := fmt.Sprintf("map[string]interface{}{\n\t\t%q: %s,\n\t\t\"total\": total,\n", resource.Name, resource.Name)
+	if perPage := resource.Pagination["index"]; perPage > 0 && isJSON {
+		buf.WriteString(fmt.Sprintf("\tpage := runtime.NewPage(params.%s, params.%s, %d)\n",
+			ToCamelCase(expr.ParamPage), ToCamelCase(expr.ParamPerPage), perPage))
+		payload += fmt.Sprintf("\t\t%q: page.Number,\n\t\t%q: page.PerPage,\n", expr.ParamPage, expr.ParamPerPage)
+	}
+	indexRender, indexPayload := respond(resource, payload+"\t}")
+	buf.WriteString(fmt.Sprintf("\tc.%s(w, r, \"%s/index\", map[string]interface{}{\n", indexRender, views))
+	buf.WriteString(fmt.Sprintf("\t\t\"Title\": \"%s\",\n", title))
+	buf.WriteString(fmt.Sprintf("\t\t\"%s\": %s,\n", title, resource.Name))
+	buf.WriteString("\t\t\"Total\": total,\n")
+	buf.WriteString(viewPathData(resource, pathExpr(resource, ""), "\t\t"))
+	if hasParams {
+		buf.WriteString("\t\t\"Params\": params,\n")
+	}
+	buf.WriteString(fmt.Sprintf("\t}%s)\n", indexPayload))
+	buf.WriteString("}\n\n")
+
+	// Show
+	showRender, showPayload := respond(resource, singular)
+	buf.WriteString(fmt.Sprintf(`// Show displays a single %s
+func (c *%s) Show(w http.ResponseWriter, r *http.Request) {
+	%s, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	c.%s(w, r, "%s/show", map[string]interface{}{
+		"Title": "%s Details",
+		"%s": %s,%s
+	}%s)
+}
+
+`,
+		singular,
+		controllerType,
+		singular,
+		showRender, views,
+		singularTitle,
+		singularTitle, singular, memberPathData, showPayload,
+	))
+
+	newForm := findForm(g.app, resource, resource.NewFormName())
+	editForm := findForm(g.app, resource, resource.EditFormName())
+
+	// New
+	buf.WriteString(fmt.Sprintf("// New displays the form for creating a new %s\n", singular))
+	buf.WriteString(fmt.Sprintf("func (c *%s) New(w http.ResponseWriter, r *http.Request) {\n", controllerType))
+	if fk != nil {
+		buf.WriteString("\tif _, ok := c.findParent(w, r); !ok {\n")
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n\n")
+	}
+	buf.WriteString(fmt.Sprintf("\tc.Render(w, r, \"%s/new\", map[string]interface{}{\n", views))
+	buf.WriteString(fmt.Sprintf("\t\t\"Title\": \"New %s\",\n", singularTitle))
+	buf.WriteString(viewPathData(resource, pathExpr(resource, ""), "\t\t"))
+	if newForm != nil {
+		buf.WriteString(fmt.Sprintf("\t\t\"Form\": types.New%s(),\n", newForm.Name))
+	}
+	buf.WriteString("\t})\n")
+	buf.WriteString("}\n\n")
+
+	// Create
+	buf.WriteString(fmt.Sprintf("// Create handles the creation of a new %s\n", singular))
+	buf.WriteString(fmt.Sprintf("func (c *%s) Create(w http.ResponseWriter, r *http.Request) {\n", controllerType))
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("\t%s, ok := c.findParent(w, r)\n", pathArg(fk.Name)))
+		buf.WriteString("\tif !ok {\n")
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n\n")
+	}
+	if newForm != nil {
+		buf.WriteString(fmt.Sprintf("\tform := types.New%s()\n", newForm.Name))
+		buf.WriteString(g.bindForm(resource, "Bind", "new", fmt.Sprintf("\t\t\"Title\": \"New %s\",\n", singularTitle)+
+			viewPathData(resource, pathExpr(resource, ""), "\t\t")))
+	}
+	if newForm != nil && len(types.mappedFields(newForm, model)) > 0 {
+		buf.WriteString(fmt.Sprintf("\t%s := form.To%s()\n", singular, model.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("\t%s := &types.%s{}\n", singular, model.Name))
+		buf.WriteString(fmt.Sprintf("\t// TODO: Copy the submitted fields to %s\n", singular))
+	}
+	if fk != nil {
+		// The foreign key comes from the path, not the form
+		buf.WriteString(fmt.Sprintf("\t%s.%s = %s\n", singular, ToFieldName(fk.Name), pathArg(fk.Name)))
+	}
+	buf.WriteString(fmt.Sprintf("\tif err := c.repo.Create(r.Context(), %s); err != nil {\n", singular))
+	buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+	if isJSON {
+		buf.WriteString("\tif runtime.WantsJSON(r) {\n")
+		buf.WriteString(fmt.Sprintf("\t\truntime.WriteJSON(w, http.StatusCreated, %s)\n", singular))
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\tc.Flash(w, r, \"success\", \"%s created successfully!\")\n", singularTitle))
+	buf.WriteString(fmt.Sprintf("\tc.Redirect(w, r, %s+%s)\n", memberPathExpr(resource, "/"), formatID(pk.Type, singular+"."+idField)))
+	buf.WriteString("}\n\n")
+
+	// Edit
+	buf.WriteString(fmt.Sprintf("// Edit displays the form for editing a %s\n", singular))
+	buf.WriteString(fmt.Sprintf("func (c *%s) Edit(w http.ResponseWriter, r *http.Request) {\n", controllerType))
+	buf.WriteString(fmt.Sprintf("\t%s, ok := c.find(w, r)\n", singular))
+	buf.WriteString("\tif !ok {\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+	if editForm != nil {
+		buf.WriteString(fmt.Sprintf("\tform := &types.%s{}\n", editForm.Name))
+		if len(types.mappedFields(editForm, model)) > 0 {
+			buf.WriteString(fmt.Sprintf("\tform.From%s(%s)\n\n", model.Name, singular))
+		} else {
+			buf.WriteString(fmt.Sprintf("\t// TODO: Populate form from the %s\n\n", singular))
+		}
+	}
+	buf.WriteString(fmt.Sprintf("\tc.Render(w, r, \"%s/edit\", map[string]interface{}{\n", views))
+	buf.WriteString(fmt.Sprintf("\t\t\"Title\": \"Edit %s\",\n", singularTitle))
+	buf.WriteString(fmt.Sprintf("\t\t\"%s\": %s,\n", singularTitle, singular))
+	buf.WriteString(viewPathData(resource, collectionPathExpr(resource, singular), "\t\t"))
+	if editForm != nil {
+		buf.WriteString("\t\t\"Form\": form,\n")
+	}
+	buf.WriteString("\t})\n")
+	buf.WriteString("}\n\n")
+
+	// Update
+	buf.WriteString(fmt.Sprintf("// Update handles updating a %s\n", singular))
+	buf.WriteString(fmt.Sprintf("func (c *%s) Update(w http.ResponseWriter, r *http.Request) {\n", controllerType))
+	buf.WriteString(fmt.Sprintf("\t%s, ok := c.find(w, r)\n", singular))
+	buf.WriteString("\tif !ok {\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+	if editForm != nil {
+		buf.WriteString(fmt.Sprintf("\tform := &types.%s{}\n", editForm.Name))
+		// Patch keeps the stored values of the fields missing from the
+		// request, as in a partial JSON update
+		if len(types.mappedFields(editForm, model)) > 0 {
+			buf.WriteString(fmt.Sprintf("\tform.From%s(%s)\n", model.Name, singular))
+		}
+		buf.WriteString(g.bindForm(resource, "Patch", "edit", fmt.Sprintf("\t\t\"Title\": \"Edit %s\",\n\t\t\"%s\": %s,\n",
+			singularTitle, singularTitle, singular)+viewPathData(resource, collectionPathExpr(resource, singular), "\t\t")))
+	}
+	if editForm != nil && len(types.mappedFields(editForm, model)) > 0 {
+		buf.WriteString(fmt.Sprintf("\tform.ApplyTo%s(%s)\n", model.Name, singular))
+	} else {
+		buf.WriteString(fmt.Sprintf("\t// TODO: Apply the submitted fields to %s\n", singular))
+	}
+	buf.WriteString(fmt.Sprintf("\tif err := c.repo.Update(r.Context(), %s); err != nil {\n", singular))
+	buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+	if isJSON {
+		buf.WriteString("\tif runtime.WantsJSON(r) {\n")
+		buf.WriteString(fmt.Sprintf("\t\truntime.WriteJSON(w, http.StatusOK, %s)\n", singular))
+		buf.WriteString("\t\treturn\n")
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\tc.Flash(w, r, \"success\", \"%s updated successfully!\")\n", singularTitle))
+	buf.WriteString(fmt.Sprintf("\tc.Redirect(w, r, %s+%s)\n", memberPathExpr(resource, "/"), formatID(pk.Type, singular+"."+idField)))
+	buf.WriteString("}\n\n")
+
+	// Destroy: nested resources load the record first, which find checks
+	// against the parent, and whose foreign key leads back to the collection
+	lookup := "\tid, err := c.parseID(r)\n\tif err != nil {\n\t\thttp.NotFound(w, r)\n\t\treturn\n\t}\n"
+	assign, id := " =", "id"
+	if fk != nil {
+		lookup = fmt.Sprintf("\t%s, ok := c.find(w, r)\n\tif !ok {\n\t\treturn\n\t}\n", singular)
+		assign, id = " :=", singular+"."+idField
+	}
+	destroyJSON := ""
+	if isJSON {
+		destroyJSON = "\tif runtime.WantsJSON(r) {\n\t\tw.WriteHeader(http.StatusNoContent)\n\t\treturn\n\t}\n"
+	}
+	buf.WriteString(fmt.Sprintf(`// Destroy handles deleting a %s
+func (c *%s) Destroy(w http.ResponseWriter, r *http.Request) {
+%s
+	err%s c.repo.Delete(r.Context(), %s)
+	if errors.Is(err, runtime.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+%s	c.Flash(w, r, "success", "%s deleted successfully!")
+	c.Redirect(w, r, %s)
+}
+
+`,
+		singular,
+		controllerType,
+		lookup, assign, id,
+		destroyJSON, singularTitle,
+		collectionPathExpr(resource, singular),
+	))
+	buf.WriteString(g.findRecord(resource))
+	if fk != nil {
+		buf.WriteString(g.findParent(resource, parentRepo))
+	}
+
+	// parseID
+	buf.WriteString(fmt.Sprintf("// parseID returns the %s %s from the request path.\n", singular, pk.Name))
+	buf.WriteString(fmt.Sprintf("func (c *%s) parseID(r *http.Request) (%s, error) {\n", controllerType, idType))
+	switch idType {
+	case "string":
+		buf.WriteString("\treturn r.PathValue(\"id\"), nil\n")
+	case "int":
+		buf.WriteString("\treturn strconv.Atoi(r.PathValue(\"id\"))\n")
+	case "int32":
+		buf.WriteString("\tid, err := strconv.ParseInt(r.PathValue(\"id\"), 10, 32)\n")
+		buf.WriteString("\treturn int32(id), err\n")
+	default:
+		buf.WriteString("\treturn strconv.ParseInt(r.PathValue(\"id\"), 10, 64)\n")
+	}
+	buf.WriteString("}\n")
+	buf.WriteString(g.customActions(resource))
+
+	return buf.String()
+}
+
+// findRecord returns the find method of a model controller, which loads the
+// record of the request path. Records of nested resources must belong to
+// the parent of the path, which the member routes of shallow ones lack.
+func (g *ExampleGenerator) findRecord(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	singular := toSingular(resource.Name)
+	fk := resource.ForeignKey()
+	if resource.Shallow {
+		fk = nil
+	}
+
+	buf.WriteString(fmt.Sprintf("// find loads the %s identified by the request path. It writes a 404 or\n", singular))
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("// 500 response and returns false if that fails or if the %s belongs to\n", singular))
+		buf.WriteString(fmt.Sprintf("// another %s.\n", ToSingular(resource.Parent.Name)))
+	} else {
+		buf.WriteString("// 500 response and returns false if that fails.\n")
+	}
+	buf.WriteString(fmt.Sprintf("func (c *%s) find(w http.ResponseWriter, r *http.Request) (*types.%s, bool) {\n",
+		controllerType(resource), resource.Model.Name))
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("\t%s, ok := c.findParent(w, r)\n", pathArg(fk.Name)))
+		buf.WriteString("\tif !ok {\n")
+		buf.WriteString("\t\treturn nil, false\n")
+		buf.WriteString("\t}\n\n")
+	}
+	buf.WriteString("\tid, err := c.parseID(r)\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\thttp.NotFound(w, r)\n")
+	buf.WriteString("\t\treturn nil, false\n")
+	buf.WriteString("\t}\n\n")
+	buf.WriteString(fmt.Sprintf("\t%s, err := c.repo.Get(r.Context(), id)\n", singular))
+	buf.WriteString("\tif errors.Is(err, runtime.ErrNotFound) {\n")
+	buf.WriteString("\t\thttp.NotFound(w, r)\n")
+	buf.WriteString("\t\treturn nil, false\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+	buf.WriteString("\t\treturn nil, false\n")
+	buf.WriteString("\t}\n")
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("\tif %s.%s != %s {\n", singular, ToFieldName(fk.Name), pathArg(fk.Name)))
+		buf.WriteString("\t\thttp.NotFound(w, r)\n")
+		buf.WriteString("\t\treturn nil, false\n")
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\treturn %s, true\n", singular))
+	buf.WriteString("}\n\n")
+
+	return buf.String()
+}
+
+// findParent returns the findParent method of the controller of a nested
+// resource, which reads the id of the parent from the request path. With a
+// parent repository, the parent must exist and, when its own parent is in
+// the path too, belong to it.
+func (g *ExampleGenerator) findParent(resource *expr.ResourceExpr, parentRepo string) string {
+	var buf bytes.Buffer
+
+	parent := resource.Parent
+	fk := resource.ForeignKey()
+	parentID := pathArg(fk.Name)
+	record := ToSingular(parent.Name)
+	types := NewTypesGenerator(g.app)
+	idType := types.goType(fk.Type)
+	zero := "0"
+	if idType == "string" {
+		zero = `""`
+	}
+
+	buf.WriteString(fmt.Sprintf("// findParent returns the id of the %s of the request path. It writes a\n", record))
+	buf.WriteString(fmt.Sprintf("// 404 or 500 response and returns false if there is no such %s.\n", record))
+	buf.WriteString(fmt.Sprintf("func (c *%s) findParent(w http.ResponseWriter, r *http.Request) (%s, bool) {\n",
+		controllerType(resource), idType))
+	buf.WriteString(fmt.Sprintf("\t%s, err := interfaces.%sParentID(r)\n", parentID, resourceTitle(resource)))
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\thttp.NotFound(w, r)\n")
+	buf.WriteString(fmt.Sprintf("\t\treturn %s, false\n", zero))
+	buf.WriteString("\t}\n")
+	if parentRepo != "" {
+		// Shallow parents are not nested in the path
+		outer := parent.ForeignKey()
+		if parent.Shallow {
+			outer = nil
+		}
+		found := "_, err ="
+		if outer != nil {
+			found = record + ", err :="
+		}
+		buf.WriteString("\n")
+		buf.WriteString(fmt.Sprintf("\t%s c.%s.Get(r.Context(), %s)\n", found, parentRepo, parentID))
+		buf.WriteString("\tif errors.Is(err, runtime.ErrNotFound) {\n")
+		buf.WriteString("\t\thttp.NotFound(w, r)\n")
+		buf.WriteString(fmt.Sprintf("\t\treturn %s, false\n", zero))
+		buf.WriteString("\t}\n")
+		buf.WriteString("\tif err != nil {\n")
+		buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+		buf.WriteString(fmt.Sprintf("\t\treturn %s, false\n", zero))
+		buf.WriteString("\t}\n")
+		if outer != nil {
+			// The parent must belong to its own parent in the path
+			outerID := pathArg(outer.Name)
+			buf.WriteString(fmt.Sprintf("\t%s, err := interfaces.%s%sID(r)\n",
+				outerID, resourceTitle(resource), toTitle(ToSingular(parent.Parent.Name))))
+			buf.WriteString(fmt.Sprintf("\tif err != nil || %s.%s != %s {\n", record, ToFieldName(outer.Name), outerID))
+			buf.WriteString("\t\thttp.NotFound(w, r)\n")
+			buf.WriteString(fmt.Sprintf("\t\treturn %s, false\n", zero))
+			buf.WriteString("\t}\n")
+		}
+	}
+	buf.WriteString(fmt.Sprintf("\treturn %s, true\n", parentID))
+	buf.WriteString("}\n\n")
+
+	return buf.String()
+}
+
+// bindForm returns the code that binds form with its method, Bind or Patch,
+// and validates it, re-rendering view with the validation errors if that
+// fails, or answering JSON requests with a 422 problem. data holds the other
+// view data.
+func (g *ExampleGenerator) bindForm(resource *expr.ResourceExpr, method, view, data string) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("\terr := form.%s(r)\n", method))
+	buf.WriteString("\tif err == nil {\n")
+	buf.WriteString("\t\terr = form.Validate()\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tvar errs runtime.ValidationErrors\n")
+	buf.WriteString("\tif errors.As(err, &errs) {\n")
+	if resource.HasFormat(expr.FormatJSON) {
+		buf.WriteString("\t\tif runtime.WantsJSON(r) {\n")
+		buf.WriteString("\t\t\truntime.WriteProblem(w, runtime.ValidationProblem(errs))\n")
+		buf.WriteString("\t\t\treturn\n")
+		buf.WriteString("\t\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\t\tc.Render(w, r, \"%s/%s\", map[string]interface{}{\n", resource.QualifiedName(), view))
+	buf.WriteString(strings.ReplaceAll(data, "\t\t\"", "\t\t\t\""))
+	buf.WriteString("\t\t\t\"Form\": form,\n")
+	buf.WriteString("\t\t\t\"Errors\": errs,\n")
+	buf.WriteString("\t\t})\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n")
+	buf.WriteString("\t\treturn\n")
+	buf.WriteString("\t}\n\n")
+
+	return buf.String()
+}
+
+// customActions returns the example methods of the custom actions of
+// resource. Member actions of model controllers load their record with
+// find. Forms are bound before anything else: failures are flashed and
+// redirect back, as these actions have no form view of their own.
+func (g *ExampleGenerator) customActions(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	singular := toSingular(resource.Name)
+	singularTitle := ToTitle(singular)
+	withModel := resource.Model != nil && !resource.Singular
+
+	for _, action := range resource.CustomActions {
+		config := resource.ActionConfigs[action]
+		buf.WriteString(fmt.Sprintf("\n// %s handles %s %s\n", ToCamelCase(action), config.Method, config.Path()))
+		buf.WriteString(fmt.Sprintf("func (c *%s) %s(w http.ResponseWriter, r *http.Request) {\n", controllerType(resource), ToCamelCase(action)))
+
+		// The record of member actions, and where to go when done
+		record, back := "", pathExpr(resource, "")
+		if config.Member {
+			switch {
+			case withModel:
+				pk := resource.Model.PrimaryKey()
+				record = singular
+				back = memberPathExpr(resource, "/") + "+" + formatID(pk.Type, singular+"."+ToFieldName(pk.Name))
+				buf.WriteString(fmt.Sprintf("\t%s, ok := c.find(w, r)\n\tif !ok {\n\t\treturn\n\t}\n\n", singular))
+			case resource.Singular:
+				back = pathExpr(resource, "")
+			default:
+				back = memberPathExpr(resource, "/") + "+id"
+				buf.WriteString("\tid := r.PathValue(\"id\")\n\n")
+			}
+		}
+
+		if form := findForm(g.app, resource, config.FormName); form != nil {
+			buf.WriteString(fmt.Sprintf("\tform := types.New%s()\n", form.Name))
+			buf.WriteString("\terr := form.Bind(r)\n")
+			buf.WriteString("\tif err == nil {\n")
+			buf.WriteString("\t\terr = form.Validate()\n")
+			buf.WriteString("\t}\n")
+			buf.WriteString("\tvar errs runtime.ValidationErrors\n")
+			buf.WriteString("\tif errors.As(err, &errs) {\n")
+			if resource.HasFormat(expr.FormatJSON) {
+				buf.WriteString("\t\tif runtime.WantsJSON(r) {\n")
+				buf.WriteString("\t\t\truntime.WriteProblem(w, runtime.ValidationProblem(errs))\n")
+				buf.WriteString("\t\t\treturn\n")
+				buf.WriteString("\t\t}\n")
+			}
+			buf.WriteString("\t\tc.Flash(w, r, \"error\", errs.Error())\n")
+			buf.WriteString(fmt.Sprintf("\t\tc.Redirect(w, r, %s)\n", back))
+			buf.WriteString("\t\treturn\n")
+			buf.WriteString("\t}\n")
+			buf.WriteString("\tif err != nil {\n")
+			buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n")
+			buf.WriteString("\t\treturn\n")
+			buf.WriteString("\t}\n\n")
+		}
+
+		params, hasParams := actionParamsName(resource, action)
+		if hasParams {
+			buf.WriteString(fmt.Sprintf("\tparams := &types.%s{}\n", params))
+			buf.WriteString("\tif err := params.Bind(r); err != nil {\n")
+			buf.WriteString("\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n")
+			buf.WriteString("\t\treturn\n")
+			buf.WriteString("\t}\n\n")
+		}
+
+		target := resource.Name
+		if config.Member {
+			target = "the " + singular
+		}
+		if config.Method != "GET" {
+			buf.WriteString(fmt.Sprintf("\t// TODO: %s %s\n\n", fieldLabel(action), target))
+			if withModel && resource.HasFormat(expr.FormatJSON) {
+				buf.WriteString("\tif runtime.WantsJSON(r) {\n")
+				if record != "" {
+					buf.WriteString(fmt.Sprintf("\t\truntime.WriteJSON(w, http.StatusOK, %s)\n", record))
+				} else {
+					buf.WriteString("\t\tw.WriteHeader(http.StatusNoContent)\n")
+				}
+				buf.WriteString("\t\treturn\n")
+				buf.WriteString("\t}\n")
+			}
+			buf.WriteString(fmt.Sprintf("\tc.Flash(w, r, \"success\", \"%s succeeded!\")\n", fieldLabel(action)))
+			buf.WriteString(fmt.Sprintf("\tc.Redirect(w, r, %s)\n", back))
+			buf.WriteString("}\n")
+			continue
+		}
+
+		data := fmt.Sprintf("\t\t\"Title\": \"%s\",\n", customActionTitle(config))
+		payload := fmt.Sprintf("map[string]interface{}{%q: []interface{}{}}", resource.Name)
+		switch {
+		case withModel && config.Member:
+			buf.WriteString(fmt.Sprintf("\t// TODO: %s %s\n\n", fieldLabel(action), target))
+			data += fmt.Sprintf("\t\t\"%s\": %s,\n", singularTitle, singular)
+			data += viewPathData(resource, collectionPathExpr(resource, record), "\t\t")
+			payload = singular
+		case config.Member:
+			buf.WriteString(fmt.Sprintf("\t// TODO: Fetch %s from database\n", singular))
+			id := "id"
+			if resource.Singular {
+				id = "1"
+			}
+			buf.WriteString(fmt.Sprintf("\t%s := map[string]interface{}{\n\t\t\"ID\": %s,\n\t\t\"Name\": \"Sample %s\",\n\t}\n\n", singular, id, singularTitle))
+			data += fmt.Sprintf("\t\t\"%s\": %s,\n", singularTitle, singular)
+			data += viewPathData(resource, collectionPathExpr(resource, ""), "\t\t")
+			payload = singular
+		default:
+			buf.WriteString(fmt.Sprintf("\t// TODO: %s %s\n\n", fieldLabel(action), target))
+			data += viewPathData(resource, pathExpr(resource, ""), "\t\t")
+		}
+		if hasParams {
+			data += "\t\t\"Params\": params,\n"
+		}
+		render, payload := respond(resource, payload)
+		buf.WriteString(fmt.Sprintf("\tc.%s(w, r, \"%s/%s\", map[string]interface{}{\n%s\t}%s)\n", render, resource.QualifiedName(), action, data, payload))
+		buf.WriteString("}\n")
+	}
+
+	return buf.String()
+}
+
+// respond returns the BaseController method answering a read action of
+// resource and the arguments following its view data: Respond with payload,
+// what API clients get instead of the view data, for JSON resources, and
+// Render with none for the others.
+func respond(resource *expr.ResourceExpr, payload string) (method, args string) {
+	if resource.HasFormat(expr.FormatJSON) {
+		return "Respond", ", " + payload
+	}
+	return "Render", ""
+}
+
+// customActionImports returns whether the custom actions of resource bind
+// forms, which need the errors and runtime packages, and whether they use
+// the types package for forms or params.
+func (g *ExampleGenerator) customActionImports(resource *expr.ResourceExpr) (forms, types bool) {
+	for _, action := range resource.CustomActions {
+		if findForm(g.app, resource, resource.ActionConfigs[action].FormName) != nil {
+			forms = true
+		}
+		if _, ok := actionParamsName(resource, action); ok {
+			types = true
+		}
+	}
+	return forms, forms || types
+}
+
+// formatID returns the expression that formats an ID for use in a URL path.
+func formatID(dataType expr.DataType, value string) string {
+	switch dataType {
+	case expr.String:
+		return fmt.Sprintf("url.PathEscape(%s)", value)
+	case expr.Int:
+		return fmt.Sprintf("strconv.Itoa(%s)", value)
+	case expr.Int64:
+		return fmt.Sprintf("strconv.FormatInt(%s, 10)", value)
+	default:
+		return fmt.Sprintf("strconv.FormatInt(int64(%s), 10)", value)
+	}
+}
+
 // generatePagesController generates an example pages controller.
 func (g *ExampleGenerator) generatePagesController() error {
 	filename := filepath.Join(g.OutputDir, "app/controllers/pages.go")
@@ -423,6 +1167,11 @@ func (c *pagesController) %s(w http.ResponseWriter, r *http.Request) {
 
 // generateViews generates all view templates.
 func (g *ExampleGenerator) generateViews() error {
+	// Generate the package embedding the views
+	if err := g.generateViewsEmbed(); err != nil {
+		return err
+	}
+
 	// Generate layout
 	if err := g.generateLayout(); err != nil {
 		return err
@@ -443,21 +1192,65 @@ func (g *ExampleGenerator) generateViews() error {
 	return nil
 }
 
-// generateLayout generates the main layout template.
-func (g *ExampleGenerator) generateLayout() error {
-	filename := filepath.Join(g.OutputDir, "app/views/layouts/application.html")
+// generateViewsEmbed generates the package embedding app/views, loaded by
+// the base controller outside development.
+func (g *ExampleGenerator) generateViewsEmbed() error {
+	filename := filepath.Join(g.OutputDir, "app/views/views.go")
 	if fileExists(filename) {
 		fmt.Printf("  Skipping %s (already exists)\n", filename)
 		return nil
 	}
 
-	viewGen := NewViewsGenerator(g.app)
-	content, _ := viewGen.GenerateLayout()
+	// all: keeps the partials, whose names start with an underscore
+	content := `// Package views embeds the templates of the application.
+package views
+
+import "embed"
+
+// FS holds the templates, compiled into the binary.
+//
+//go:embed all:layouts all:shared ` + strings.Join(g.viewDirs(), " ") + `
+var FS embed.FS
+`
 
 	fmt.Printf("  Creating %s\n", filename)
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
+// viewDirs returns the embed patterns of the view directories of the
+// resources, skipping those without views as embed rejects empty ones.
+func (g *ExampleGenerator) viewDirs() []string {
+	viewGen := NewViewsGenerator(g.app)
+	var dirs []string
+	for _, resource := range g.app.Resources {
+		dir := "all:" + resource.QualifiedName()
+		if views, err := viewGen.GenerateResourceViews(resource); err == nil && len(views) > 0 && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// generateLayout generates a template for each layout of the app.
+func (g *ExampleGenerator) generateLayout() error {
+	viewGen := NewViewsGenerator(g.app)
+	content, _ := viewGen.GenerateLayout()
+
+	for _, name := range viewGen.LayoutNames() {
+		filename := filepath.Join(g.OutputDir, "app/views/layouts", name+".html")
+		if fileExists(filename) {
+			fmt.Printf("  Skipping %s (already exists)\n", filename)
+			continue
+		}
+
+		fmt.Printf("  Creating %s\n", filename)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // generateSharedViews generates shared view partials.
 func (g *ExampleGenerator) generateSharedViews() error {
 	viewGen := NewViewsGenerator(g.app)
@@ -482,6 +1275,16 @@ func (g *ExampleGenerator) generateSharedViews() error {
 		}
 	}
 
+	// Generate the page of requests rejected by the CSRF middleware
+	filename = filepath.Join(g.OutputDir, "app/views/shared/forbidden.html")
+	if !fileExists(filename) {
+		content := viewGen.GenerateForbidden()
+		fmt.Printf("  Creating %s\n", filename)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -494,18 +1297,12 @@ func (g *ExampleGenerator) generateResourceViews(resource *expr.ResourceExpr) er
 	}
 
 	for name, content := range views {
-		filename := filepath.Join(g.OutputDir, "app/views", resource.Name, name)
+		filename := filepath.Join(g.OutputDir, "app/views", resource.QualifiedName(), name)
 		if fileExists(filename) {
 			fmt.Printf("  Skipping %s (already exists)\n", filename)
 			continue
 		}
 
-		// Update template definition
-		content = strings.Replace(content,
-			`{{define "content"}}`,
-			fmt.Sprintf(`{{define "%s/%s"}}`, resource.Name, strings.TrimSuffix(name, ".html")),
-			1)
-
 		fmt.Printf("  Creating %s\n", filename)
 		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 			return err
@@ -515,12 +1312,88 @@ func (g *ExampleGenerator) generateResourceViews(resource *expr.ResourceExpr) er
 	return nil
 }
 
+// controllerType returns the name of the example controller type of
+// resource, such as "postsController" or "postCommentsController".
+func controllerType(resource *expr.ResourceExpr) string {
+	if resource.Parent == nil {
+		return resource.Name + "Controller"
+	}
+	title := resourceTitle(resource)
+	return strings.ToLower(title[:1]) + title[1:] + "Controller"
+}
+
+// pathExpr returns the Go expression of the base path of the collection
+// routes of resource followed by suffix. Nested resources read the ids of
+// their ancestors from the request r.
+func pathExpr(resource *expr.ResourceExpr, suffix string) string {
+	if resource.Parent == nil {
+		return strconv.Quote("/" + resource.Name + suffix)
+	}
+	params := resource.ParentParams()
+	args := make([]string, len(params))
+	for i, param := range params {
+		args[i] = fmt.Sprintf("r.PathValue(%q)", param)
+	}
+	return pathHelper(resource, args, suffix)
+}
+
+// memberPathExpr returns the Go expression of the base path of the member
+// routes of resource followed by suffix. Those of shallow resources are not
+// nested.
+func memberPathExpr(resource *expr.ResourceExpr, suffix string) string {
+	if resource.Shallow {
+		return strconv.Quote(resource.MemberPath() + suffix)
+	}
+	return pathExpr(resource, suffix)
+}
+
+// collectionPathExpr returns the Go expression of the base path of the
+// collection routes of resource in its member actions. The member routes of
+// shallow resources lack the id of the parent, which is read from the
+// foreign key of record. Without one the parent is unknown, so they use the
+// root path instead.
+func collectionPathExpr(resource *expr.ResourceExpr, record string) string {
+	if !resource.Shallow || resource.Parent.Singular {
+		return pathExpr(resource, "")
+	}
+	if fk := resource.ForeignKey(); fk != nil && record != "" {
+		value := record + "." + ToFieldName(fk.Name)
+		if fk.Type.Kind() != expr.StringKind {
+			value = formatID(fk.Type, value)
+		}
+		return pathHelper(resource, []string{value}, "")
+	}
+	return `"/"`
+}
+
+// pathHelper returns the call of the generated Path helper of a nested
+// resource with args, followed by suffix.
+func pathHelper(resource *expr.ResourceExpr, args []string, suffix string) string {
+	path := fmt.Sprintf("interfaces.%sPath(%s)", resourceTitle(resource), strings.Join(args, ", "))
+	if suffix != "" {
+		path += "+" + strconv.Quote(suffix)
+	}
+	return path
+}
+
+// viewPathData returns the view data entry passing path, the base path of
+// the collection routes, to the views of a nested resource, indented by
+// indent. Top-level resources do not need it as their views know their
+// paths.
+func viewPathData(resource *expr.ResourceExpr, path, indent string) string {
+	if resource.Parent == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s\"Path\": %s,\n", indent, path)
+}
+
 // toSingular converts a plural resource name to singular.
 func toSingular(plural string) string {
 	if strings.HasSuffix(plural, "ies") {
 		return plural[:len(plural)-3] + "y"
 	}
-	if strings.HasSuffix(plural, "es") {
+	if strings.HasSuffix(plural, "ses") || strings.HasSuffix(plural, "xes") || strings.HasSuffix(plural, "zes") ||
+		strings.HasSuffix(plural, "ches") || strings.HasSuffix(plural, "shes") {
 		return plural[:len(plural)-2]
 	}
 	if strings.HasSuffix(plural, "s") {
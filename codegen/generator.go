@@ -163,9 +163,11 @@ func (g *Generator) generateViews() error {
 		return err
 	}
 
-	layoutFile := filepath.Join(g.outputPath, g.app.Name, "views", "layouts", "application.html")
-	if err := os.WriteFile(layoutFile, []byte(layoutCode), 0644); err != nil {
-		return err
+	for _, name := range gen.LayoutNames() {
+		layoutFile := filepath.Join(g.outputPath, g.app.Name, "views", "layouts", name+".html")
+		if err := os.WriteFile(layoutFile, []byte(layoutCode), 0644); err != nil {
+			return err
+		}
 	}
 
 	// Generate shared partials
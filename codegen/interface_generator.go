@@ -2,11 +2,15 @@ package codegen
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
 )
 
 // InterfaceGenerator generates only interfaces and contracts.
@@ -40,6 +44,11 @@ func (g *InterfaceGenerator) SetCommand(command string) {
 
 // Generate generates all interfaces and contracts.
 func (g *InterfaceGenerator) Generate() error {
+	// Reject invalid designs before writing any file
+	if err := Validate(g.app); err != nil {
+		return err
+	}
+
 	// Create output directories
 	dirs := []string{
 		filepath.Join(g.outDir, "interfaces"),
@@ -68,6 +77,11 @@ func (g *InterfaceGenerator) Generate() error {
 		return fmt.Errorf("failed to generate router: %w", err)
 	}
 
+	// Generate SQL repositories and migrations
+	if err := g.generateSQLStore(); err != nil {
+		return fmt.Errorf("failed to generate SQL store: %w", err)
+	}
+
 	return nil
 }
 
@@ -77,7 +91,7 @@ func (g *InterfaceGenerator) generateControllerInterfaces() error {
 	for _, resource := range g.app.Resources {
 		content := g.generateResourceInterface(resource)
 
-		filename := filepath.Join(g.outDir, "interfaces", resource.Name+"_controller.go")
+		filename := filepath.Join(g.outDir, "interfaces", resource.QualifiedName()+"_controller.go")
 		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 			return err
 		}
@@ -99,33 +113,174 @@ func (g *InterfaceGenerator) generateControllerInterfaces() error {
 // generateResourceInterface generates a controller interface for a resource.
 func (g *InterfaceGenerator) generateResourceInterface(resource *expr.ResourceExpr) string {
 	// Header MUST come first, before package declaration
-	description := fmt.Sprintf("%s controller interface", resource.Name)
+	description := fmt.Sprintf("%s controller interface", resource.QualifiedName())
 	code := GenerateHeader(description, g.version, g.command)
 
 	code += "package interfaces\n\n"
-	code += "import \"net/http\"\n\n"
+	code += routeImports(g.app, resource)
 
-	controllerName := toTitle(resource.Name) + "Controller"
+	controllerName := resourceTitle(resource) + "Controller"
 
-	code += fmt.Sprintf("// %s handles requests for %s resources.\n", controllerName, resource.Name)
+	code += fmt.Sprintf("// %s handles requests for %s resources%s.\n", controllerName, resource.Name, nestedIn(resource))
 	code += fmt.Sprintf("type %s interface {\n", controllerName)
 
 	// Generate method signatures for each action
-	for _, action := range resource.Actions {
+	actions := resource.ActionNames()
+	for i, action := range actions {
 		comment := getActionComment(action, resource.Name)
+		if config := resource.CustomAction(action); config != nil {
+			comment = fmt.Sprintf("%s handles %s %s", ToCamelCase(action), config.Method, config.Path())
+		}
 		code += fmt.Sprintf("\t// %s\n", comment)
-		code += fmt.Sprintf("\t%s(w http.ResponseWriter, r *http.Request)\n", toTitle(action))
+		code += fmt.Sprintf("\t%s(w http.ResponseWriter, r *http.Request)\n", ToCamelCase(action))
 
-		if action != resource.Actions[len(resource.Actions)-1] {
+		if i < len(actions)-1 {
 			code += "\n"
 		}
 	}
 
+	code += "}\n"
+	code += g.routeAccessors(resource)
+
+	return code
+}
+
+// routeImports returns the imports of the controller interface of resource:
+// net/http, and for nested resources those of its route accessors.
+func routeImports(app *expr.AppExpr, resource *expr.ResourceExpr) string {
+	if resource.Parent == nil {
+		return "import \"net/http\"\n\n"
+	}
+	imports := []string{"net/http"}
+	if len(resource.ParentParams()) > 0 {
+		imports = append(imports, "net/url")
+	}
+	types := NewTypesGenerator(app)
+	for _, ancestor := range resource.Ancestors() {
+		if ancestor.Singular || ancestor.Model == nil {
+			continue
+		}
+		if types.goType(ancestor.Model.PrimaryKey().Type) != "string" {
+			imports = append(imports, "strconv")
+			break
+		}
+	}
+	code := "import (\n"
+	for _, path := range imports {
+		code += fmt.Sprintf("\t%q\n", path)
+	}
+	return code + ")\n\n"
+}
+
+// routeAccessors returns the accessors of the path parameters of a nested
+// resource: ParentID for the id of its parent, one ID accessor per other
+// ancestor, and the Path helper building the base path of its collection
+// routes from their ids. IDs are typed after the primary key of the
+// ancestor model, strings without a model.
+func (g *InterfaceGenerator) routeAccessors(resource *expr.ResourceExpr) string {
+	if resource.Parent == nil {
+		return ""
+	}
+	title := resourceTitle(resource)
+	types := NewTypesGenerator(g.app)
+	code := ""
+
+	for _, ancestor := range resource.Ancestors() {
+		if ancestor.Singular {
+			continue
+		}
+		name := title + toTitle(ToSingular(ancestor.Name)) + "ID"
+		if ancestor == resource.Parent {
+			name = title + "ParentID"
+		}
+		idType := "string"
+		if ancestor.Model != nil {
+			idType = types.goType(ancestor.Model.PrimaryKey().Type)
+		}
+		param := ancestor.IDParam()
+
+		code += fmt.Sprintf("\n// %s returns the id of the %s of a request, read\n", name, ToSingular(ancestor.Name))
+		code += fmt.Sprintf("// from the %s path parameter.\n", param)
+		if resource.Shallow {
+			code += "// Only collection routes have it: member routes are not nested.\n"
+		}
+		code += fmt.Sprintf("func %s(r *http.Request) (%s, error) {\n", name, idType)
+		switch idType {
+		case "string":
+			code += fmt.Sprintf("\treturn r.PathValue(%q), nil\n", param)
+		case "int":
+			code += fmt.Sprintf("\treturn strconv.Atoi(r.PathValue(%q))\n", param)
+		case "int32":
+			code += fmt.Sprintf("\tid, err := strconv.ParseInt(r.PathValue(%q), 10, 32)\n", param)
+			code += "\treturn int32(id), err\n"
+		default:
+			code += fmt.Sprintf("\treturn strconv.ParseInt(r.PathValue(%q), 10, 64)\n", param)
+		}
+		code += "}\n"
+	}
+
+	// Path builds the base path from the ids of the ancestors
+	path := strconv.Quote(resource.Path())
+	params := resource.ParentParams()
+	args := make([]string, len(params))
+	for i, param := range params {
+		args[i] = pathArg(param)
+		path = strings.Replace(path, "{"+param+"}", `" + url.PathEscape(`+args[i]+`) + "`, 1)
+	}
+	signature := ""
+	if len(args) > 0 {
+		signature = strings.Join(args, ", ") + " string"
+	}
+	code += fmt.Sprintf("\n// %sPath returns the base path of the collection routes, such as\n", title)
+	code += fmt.Sprintf("// %s.\n", examplePath(resource))
+	code += fmt.Sprintf("func %sPath(%s) string {\n", title, signature)
+	code += fmt.Sprintf("\treturn %s\n", path)
 	code += "}\n"
 
 	return code
 }
 
+// pathArg returns the name of the Path helper argument of the path
+// parameter param, such as postID for post_id.
+func pathArg(param string) string {
+	name := ToCamelCase(strings.TrimSuffix(param, "_id")) + "ID"
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// examplePath returns the base path of resource with sample ids.
+func examplePath(resource *expr.ResourceExpr) string {
+	path := resource.Path()
+	for i, param := range resource.ParentParams() {
+		path = strings.Replace(path, "{"+param+"}", strconv.Itoa(i+1), 1)
+	}
+	return path
+}
+
+// nestedIn returns the " nested in" clause describing the ancestors of a
+// nested resource, or an empty string for top-level resources.
+func nestedIn(resource *expr.ResourceExpr) string {
+	ancestors := resource.Ancestors()
+	if len(ancestors) == 0 {
+		return ""
+	}
+	names := make([]string, len(ancestors))
+	for i, ancestor := range ancestors {
+		names[len(ancestors)-1-i] = ancestor.Name
+	}
+	return " nested in " + strings.Join(names, " in ")
+}
+
+// resourceTitle returns the Go name of resource, prefixed with the singular
+// names of its ancestors for nested resources, such as "PostComments". It
+// names the controller and its field in Controllers. Shallow resources are
+// declared at the top level and keep their name.
+func resourceTitle(resource *expr.ResourceExpr) string {
+	if resource.Parent == nil || resource.Shallow {
+		return toTitle(resource.Name)
+	}
+	return ToSingular(resourceTitle(resource.Parent)) + toTitle(resource.Name)
+}
+
 // generatePagesInterface generates the pages controller interface.
 func (g *InterfaceGenerator) generatePagesInterface() string {
 	// Header MUST come first, before package declaration
@@ -141,7 +296,7 @@ func (g *InterfaceGenerator) generatePagesInterface() string {
 		for j, route := range page.Routes {
 			methodName := toTitle(page.Name)
 			if route.Method != "GET" {
-				methodName += toTitle(route.Method)
+				methodName += toTitle(strings.ToLower(route.Method))
 			}
 
 			comment := fmt.Sprintf("%s handles %s %s", methodName, route.Method, route.Path)
@@ -171,27 +326,95 @@ func (g *InterfaceGenerator) generateTypes() error {
 
 	// TypesGenerator already generates with "package types", so use content as-is
 	filename := filepath.Join(g.outDir, "types", "forms.go")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	if !gen.HasModels() {
+		return nil
+	}
+	content, err = gen.GenerateModels()
+	if err != nil {
+		return err
+	}
+	filename = filepath.Join(g.outDir, "types", "models.go")
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
+// generateSQLStore generates the sqlstore package with the database/sql
+// repositories and migrations of the models, if there are any.
+func (g *InterfaceGenerator) generateSQLStore() error {
+	if !NewTypesGenerator(g.app).HasModels() {
+		return nil
+	}
+
+	dir := filepath.Join(g.outDir, "sqlstore")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	gen := NewSQLGenerator(g.app)
+	gen.SetVersion(g.version)
+	gen.SetCommand(g.command)
+
+	content, err := gen.GenerateMigrations()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "migrations.go"), []byte(content), 0644); err != nil {
+		return err
+	}
+
+	for _, resource := range g.app.Resources {
+		if resource.Model == nil {
+			continue
+		}
+		content, err := gen.GenerateRepository(resource)
+		if err != nil {
+			return err
+		}
+		filename := filepath.Join(dir, resource.QualifiedName()+".go")
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // generateRouter generates the HTTP router.
 func (g *InterfaceGenerator) generateRouter() error {
-	content := g.generateRouterContent()
+	content, err := g.generateRouterContent()
+	if err != nil {
+		return err
+	}
 
 	filename := filepath.Join(g.outDir, "http", "router.go")
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
 // generateRouterContent generates router content.
-func (g *InterfaceGenerator) generateRouterContent() string {
+func (g *InterfaceGenerator) generateRouterContent() (string, error) {
+	if err := checkMiddleware(g.app); err != nil {
+		return "", err
+	}
+	routes, err := NewRouteTable(g.app)
+	if err != nil {
+		return "", err
+	}
+
 	// Header MUST come first, before package declaration
 	description := "HTTP router setup"
 	code := GenerateHeader(description, g.version, g.command)
 
+	hasAuth := hasAuthRequirements(g.app)
+	hasMiddleware := len(g.app.Middleware) > 0 || hasRouteMiddleware(g.app)
+
 	code += "package http\n\n"
 	code += "import (\n"
 	code += "\t\"net/http\"\n"
 	code += fmt.Sprintf("\t\"%s/gen/interfaces\"\n", g.app.Name)
+	code += routerImports(g.app)
 	code += ")\n\n"
 
 	// Generate Controllers struct
@@ -199,138 +422,435 @@ func (g *InterfaceGenerator) generateRouterContent() string {
 	code += "type Controllers struct {\n"
 
 	for _, resource := range g.app.Resources {
-		controllerName := toTitle(resource.Name) + "Controller"
-		code += fmt.Sprintf("\t%s interfaces.%s\n", toTitle(resource.Name), controllerName)
+		controllerName := resourceTitle(resource) + "Controller"
+		code += fmt.Sprintf("\t%s interfaces.%s\n", resourceTitle(resource), controllerName)
 	}
 
 	if len(g.app.Pages) > 0 {
 		code += "\tPages interfaces.PagesController\n"
 	}
 
+	if hasAuth {
+		code += "\n"
+		code += "\t// Authorizer checks the Auth requirements of routes. It is required.\n"
+		code += "\tAuthorizer runtime.Authorizer\n"
+		code += "\t// AuthPolicy decides how failed checks are answered.\n"
+		code += "\tAuthPolicy runtime.AuthPolicy\n"
+	}
+
+	if g.app.SessionStore != "" {
+		code += sessionsField(g.app)
+	}
+
+	code += csrfFailureField(g.app)
+
+	if hasMiddleware {
+		code += "\n"
+		code += "\t// Middleware supplies the custom middleware named in Use, and may\n"
+		code += "\t// replace built-in ones.\n"
+		code += "\tMiddleware map[string]runtime.Middleware\n"
+	}
+
 	code += "}\n\n"
 
 	// Generate MountRoutes function
-	code += "// MountRoutes mounts all routes on the given mux.\n"
-	code += "func MountRoutes(mux *http.ServeMux, c Controllers) {\n"
-
-	// Register routes in proper order to avoid conflicts
-	// More specific routes first
-	for _, resource := range g.app.Resources {
-		g.addResourceRoutes(&code, resource)
+	code += "// MountRoutes mounts all routes on the given mux and returns the handler\n"
+	code += "// to serve, which wraps the mux with the middleware stack.\n"
+	if hasAuth {
+		code += "// It panics if c.Authorizer is nil, as some routes require authorization.\n"
+	}
+	if g.app.SessionStore == "cookie" {
+		code += "// It panics if c.Sessions is nil, as the cookie store needs keys.\n"
+	}
+	code += "func MountRoutes(mux *http.ServeMux, c Controllers) http.Handler {\n"
+
+	if hasAuth {
+		code += "\tif c.Authorizer == nil {\n"
+		code += "\t\tpanic(\"Controllers.Authorizer is required by routes with Auth requirements\")\n"
+		code += "\t}\n"
+		code += "\t// auth wraps a handler with the Auth requirements of its route\n"
+		code += "\tauth := func(resource, action string, next http.HandlerFunc, requirements ...string) http.HandlerFunc {\n"
+		code += "\t\treturn runtime.RequireAuth(c.Authorizer, c.AuthPolicy, resource, action, requirements, next)\n"
+		code += "\t}\n\n"
 	}
 
-	// Page routes (non-root first)
-	for _, page := range g.app.Pages {
-		hasRootRoute := false
-		for _, route := range page.Routes {
-			if route.Path == "/" {
-				hasRootRoute = true
-				break
-			}
-		}
-		if !hasRootRoute {
-			g.addPageRoute(&code, page)
-		}
+	if g.app.SessionStore != "" {
+		code += sessionsSetup(g.app) + "\n"
 	}
 
-	// Static files
-	code += "\t// Static files\n"
-	code += "\tmux.Handle(\"GET /static/\", http.StripPrefix(\"/static/\", http.FileServer(http.Dir(\"public\"))))\n"
-	code += "\n"
+	if hasRouteMiddleware(g.app) {
+		code += useHelper
+	}
 
-	// Root route last
-	for _, page := range g.app.Pages {
-		for _, route := range page.Routes {
-			if route.Path == "/" {
-				g.addPageRoute(&code, page)
-				break
-			}
+	// Routes are sorted by path, more specific paths first, in groups
+	// sharing their first segment
+	var group string
+	for _, route := range routes.Routes {
+		first, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		if route != routes.Routes[0] && first != group {
+			code += "\n"
 		}
+		group = first
+		code += fmt.Sprintf("\tmux.HandleFunc(%q, %s)\n", route.Pattern(), route.Handler)
 	}
+	code += "\n"
 
+	if !strings.HasSuffix(code, "\n\n") {
+		code += "\n"
+	}
+	code += middlewareReturn(g.app)
 	code += "}\n"
 
-	return code
+	return code, nil
+}
+
+// resourceHandler wraps the handler expression of a resource action with
+// its route middleware, then with the format negotiation of the resource,
+// so that denied requests are already answered in the right format.
+func resourceHandler(app *expr.AppExpr, resource *expr.ResourceExpr, action, handler string) string {
+	handler = routeHandler(resource.QualifiedName(), action, handler, routeLayout(app, resource.Layout), actionMiddleware(resource, action), resource.AuthRequirements[action])
+	if negotiatesFormat(resource) {
+		handler = fmt.Sprintf("runtime.UseFormats([]string{%s}, %s)", quoteAll(resource.Formats), handler)
+	}
+	return handler
+}
+
+// negotiatesFormat returns true if the resource responds in another format
+// than HTML.
+func negotiatesFormat(resource *expr.ResourceExpr) bool {
+	return len(resource.Formats) > 0 && !slices.Equal(resource.Formats, []string{expr.FormatHTML})
 }
 
-// addResourceRoutes adds resource routes to the router code.
-func (g *InterfaceGenerator) addResourceRoutes(code *string, resource *expr.ResourceExpr) {
-	controllerVar := "c." + toTitle(resource.Name)
+// jsonRoute returns true if the route of a resource action at path has a
+// .json twin: JSON resources answer in JSON at every path but those of
+// the HTML forms, and paths ending with the id take the suffix in the id.
+func jsonRoute(resource *expr.ResourceExpr, action, path string) bool {
+	return resource.HasFormat(expr.FormatJSON) && action != "new" && action != "edit" && !strings.HasSuffix(path, "}")
+}
+
+// hasFormats returns true if any resource of the app negotiates its format.
+func hasFormats(app *expr.AppExpr) bool {
+	return slices.ContainsFunc(app.Resources, negotiatesFormat)
+}
+
+// useHelper declares the use helper of MountRoutes.
+const useHelper = "\t// use wraps a handler with the middleware of its route, outermost first\n" +
+	"\tuse := func(next http.HandlerFunc, middleware ...string) http.HandlerFunc {\n" +
+	"\t\treturn runtime.Chain(next, runtime.ResolveMiddleware(c.Middleware, middleware...)...).ServeHTTP\n" +
+	"\t}\n\n"
+
+// routeHandler wraps a handler expression with the route middleware, then
+// the Auth requirements, so that authorization runs first, and then the
+// route layout, so that pages rendered by either use it.
+func routeHandler(resource, action, handler, layout string, middleware, requirements []string) string {
+	if len(middleware) > 0 {
+		handler = fmt.Sprintf("use(%s, %s)", handler, quoteAll(middleware))
+	}
+	if len(requirements) > 0 {
+		handler = authHandler(resource, action, handler, requirements)
+	}
+	if layout != "" {
+		handler = fmt.Sprintf("runtime.UseLayout(%q, %s)", layout, handler)
+	}
+	return handler
+}
+
+// routeLayout returns the layout of a resource or page declaring layout,
+// or an empty string if its views use the runtime default.
+func routeLayout(app *expr.AppExpr, layout string) string {
+	if layout == "" {
+		layout = app.DefaultLayout
+	}
+	if layout == runtime.DefaultLayout {
+		return ""
+	}
+	return layout
+}
 
-	// Use singular path for singular resources
-	basePath := "/" + resource.Name
-	if resource.Singular {
-		// Remove trailing 's' if present for singular resources
-		// This is a simple heuristic - could be improved
-		if strings.HasSuffix(resource.Name, "s") {
-			basePath = "/" + resource.Name[:len(resource.Name)-1]
+// hasRouteLayouts returns true if any resource or page of the app renders
+// inside another layout than the runtime default.
+func hasRouteLayouts(app *expr.AppExpr) bool {
+	if routeLayout(app, "") != "" {
+		return len(app.Resources) > 0 || len(app.Pages) > 0
+	}
+	for _, resource := range app.Resources {
+		if routeLayout(app, resource.Layout) != "" {
+			return true
+		}
+	}
+	for _, page := range app.Pages {
+		if routeLayout(app, page.Layout) != "" {
+			return true
 		}
 	}
+	return false
+}
 
-	*code += fmt.Sprintf("\t// %s routes\n", toTitle(resource.Name))
+// actionMiddleware returns the middleware of a resource action: those of
+// the resource followed by those of the action.
+func actionMiddleware(resource *expr.ResourceExpr, action string) []string {
+	middleware := slices.Clone(resource.Middleware)
+	if config := resource.ActionConfigs[action]; config != nil {
+		middleware = append(middleware, config.Middleware...)
+	}
+	return middleware
+}
 
-	// For singular resources, routes are different
-	if resource.Singular {
-		// Singular resources don't have index or {id} in paths
-		if resource.HasAction("new") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s/new\", %s.New)\n", basePath, controllerVar)
+// hasRouteMiddleware returns true if any resource, action or page of the
+// app uses middleware.
+func hasRouteMiddleware(app *expr.AppExpr) bool {
+	for _, resource := range app.Resources {
+		for _, action := range resource.ActionNames() {
+			if len(actionMiddleware(resource, action)) > 0 {
+				return true
+			}
 		}
-		if resource.HasAction("edit") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s/edit\", %s.Edit)\n", basePath, controllerVar)
+	}
+	for _, page := range app.Pages {
+		if len(page.Middleware) > 0 {
+			return true
 		}
-		if resource.HasAction("show") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s\", %s.Show)\n", basePath, controllerVar)
+	}
+	return false
+}
+
+// authHandler wraps a handler expression with the auth helper declared by
+// MountRoutes.
+func authHandler(resource, action, handler string, requirements []string) string {
+	return fmt.Sprintf("auth(%q, %q, %s, %s)", resource, action, handler, quoteAll(requirements))
+}
+
+// Validate returns the errors of app that the DSL cannot detect on its
+// own: unknown middleware names and conflicting routes. Generators run it
+// before writing any file.
+func Validate(app *expr.AppExpr) error {
+	if err := checkMiddleware(app); err != nil {
+		return err
+	}
+	_, err := NewRouteTable(app)
+	return err
+}
+
+// checkMiddleware returns an error if Use names middleware that is neither
+// built in nor declared with CustomMiddleware, in the app or in any of its
+// resources, actions and pages.
+func checkMiddleware(app *expr.AppExpr) error {
+	if err := checkMiddlewareNames(app, "", app.Middleware); err != nil {
+		return err
+	}
+	for _, resource := range app.Resources {
+		scope := fmt.Sprintf(" of resource %q", resource.Name)
+		if err := checkMiddlewareNames(app, scope, resource.Middleware); err != nil {
+			return err
 		}
-		if resource.HasAction("destroy") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"DELETE %s\", %s.Destroy)\n", basePath, controllerVar)
+		for _, action := range slices.Sorted(maps.Keys(resource.ActionConfigs)) {
+			scope := fmt.Sprintf(" of action %s#%s", resource.Name, action)
+			if err := checkMiddlewareNames(app, scope, resource.ActionConfigs[action].Middleware); err != nil {
+				return err
+			}
 		}
-		if resource.HasAction("update") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"PATCH %s\", %s.Update)\n", basePath, controllerVar)
+	}
+	for _, page := range app.Pages {
+		scope := fmt.Sprintf(" of page %q", page.Name)
+		if err := checkMiddlewareNames(app, scope, page.Middleware); err != nil {
+			return err
 		}
-		if resource.HasAction("create") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"POST %s\", %s.Create)\n", basePath, controllerVar)
+	}
+	return nil
+}
+
+// checkMiddlewareNames checks the names used in one scope of the app.
+func checkMiddlewareNames(app *expr.AppExpr, scope string, names []string) error {
+	for _, name := range names {
+		if _, ok := runtime.LookupMiddleware(name); ok {
+			continue
 		}
-	} else {
-		// Regular plural resources
-		// Order matters for Go 1.22+ routing - more specific paths first
-		if resource.HasAction("new") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s/new\", %s.New)\n", basePath, controllerVar)
+		if slices.Contains(app.CustomMiddleware, name) {
+			continue
 		}
-		if resource.HasAction("edit") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s/{id}/edit\", %s.Edit)\n", basePath, controllerVar)
+		return fmt.Errorf("unknown middleware %q in Use%s: built-in middleware are %s, declare application middleware with CustomMiddleware(%q)",
+			name, scope, strings.Join(runtime.MiddlewareNames(), ", "), name)
+	}
+	return nil
+}
+
+// middlewareReturn returns the statement ending MountRoutes, which wraps
+// the mux with the method override, the session manager and the middleware
+// stack in declaration order. Apps using MethodOverride get it from the
+// stack only.
+func middlewareReturn(app *expr.AppExpr) string {
+	handler := "mux"
+	if needsMethodOverride(app) && builtinMiddlewareName(app, "methodoverride") == "" {
+		handler = fmt.Sprintf("runtime.MethodOverride(%s)", handler)
+	}
+	if app.SessionStore != "" {
+		handler = fmt.Sprintf("c.Sessions.Handler(%s)", handler)
+	}
+	if len(app.Middleware) == 0 {
+		return fmt.Sprintf("\treturn %s\n", handler)
+	}
+
+	code := ""
+	middleware := "c.Middleware"
+	if name := csrfMiddlewareName(app); name != "" {
+		code += "\t// The CSRF middleware answers rejected requests with c.CSRFFailure,\n"
+		code += "\t// unless c.Middleware replaces it\n"
+		code += fmt.Sprintf("\tmiddleware := map[string]runtime.Middleware{%q: runtime.CSRFProtection{OnFailure: c.CSRFFailure}.Handler}\n", name)
+		code += "\tfor name, m := range c.Middleware {\n"
+		code += "\t\tmiddleware[name] = m\n"
+		code += "\t}\n"
+		middleware = "middleware"
+	}
+	code += "\t// Middleware in declaration order, outermost first\n"
+	chain := fmt.Sprintf("runtime.Chain(%s, runtime.ResolveMiddleware(%s, %s)...)", handler, middleware, quoteAll(app.Middleware))
+	skipped := csrfSkippedRoutes(app)
+	if len(skipped) == 0 {
+		return code + fmt.Sprintf("\treturn %s\n", chain)
+	}
+	code += fmt.Sprintf("\thandler := %s\n", chain)
+	code += "\t// Routes of SkipCSRF pages are exempt from CSRF checks\n"
+	code += fmt.Sprintf("\treturn runtime.SkipCSRF(mux, handler, %s)\n", quoteAll(skipped))
+	return code
+}
+
+// csrfMiddlewareName returns the name the app uses the CSRF middleware
+// with, or an empty string if it doesn't.
+func csrfMiddlewareName(app *expr.AppExpr) string {
+	return builtinMiddlewareName(app, "csrf")
+}
+
+// builtinMiddlewareName returns the name the app uses the built-in
+// middleware with the normalized name builtin with, or an empty string if
+// it doesn't.
+func builtinMiddlewareName(app *expr.AppExpr, builtin string) string {
+	for _, name := range app.Middleware {
+		if runtime.NormalizeMiddlewareName(name) == builtin {
+			return name
 		}
-		if resource.HasAction("show") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s/{id}\", %s.Show)\n", basePath, controllerVar)
+	}
+	return ""
+}
+
+// csrfFailureField returns the Controllers field answering requests
+// rejected by the CSRF middleware, if the app uses it.
+func csrfFailureField(app *expr.AppExpr) string {
+	if csrfMiddlewareName(app) == "" {
+		return ""
+	}
+	return "\n\t// CSRFFailure, if set, answers requests rejected by the CSRF middleware,\n" +
+		"\t// such as with the application's 403 page. By default API clients get\n" +
+		"\t// a problem document and browsers a plain 403.\n" +
+		"\tCSRFFailure func(w http.ResponseWriter, r *http.Request, err error)\n"
+}
+
+// csrfSkippedRoutes returns the patterns of the routes of SkipCSRF pages if
+// the app uses the CSRF middleware.
+func csrfSkippedRoutes(app *expr.AppExpr) []string {
+	if csrfMiddlewareName(app) == "" {
+		return nil
+	}
+	var patterns []string
+	for _, page := range app.Pages {
+		if !page.SkipCSRF {
+			continue
 		}
-		if resource.HasAction("destroy") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"DELETE %s/{id}\", %s.Destroy)\n", basePath, controllerVar)
+		for _, route := range page.Routes {
+			patterns = append(patterns, route.Method+" "+route.Path)
 		}
-		if resource.HasAction("update") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"PATCH %s/{id}\", %s.Update)\n", basePath, controllerVar)
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"PUT %s/{id}\", %s.Update)\n", basePath, controllerVar)
+	}
+	return patterns
+}
+
+// needsMethodOverride returns true if the app has PUT, PATCH or DELETE
+// routes, which browser forms reach through the _method field.
+func needsMethodOverride(app *expr.AppExpr) bool {
+	for _, resource := range app.Resources {
+		if resource.HasAction("update") || resource.HasAction("destroy") {
+			return true
 		}
-		if resource.HasAction("index") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"GET %s\", %s.Index)\n", basePath, controllerVar)
+		for _, action := range resource.CustomActions {
+			switch resource.ActionConfigs[action].Method {
+			case "PUT", "PATCH", "DELETE":
+				return true
+			}
 		}
-		if resource.HasAction("create") {
-			*code += fmt.Sprintf("\tmux.HandleFunc(\"POST %s\", %s.Create)\n", basePath, controllerVar)
+	}
+	for _, page := range app.Pages {
+		for _, route := range page.Routes {
+			switch route.Method {
+			case "PUT", "PATCH", "DELETE":
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	*code += "\n"
+// sessionsField returns the Controllers field holding the session manager
+// of the store chosen with Sessions.
+func sessionsField(app *expr.AppExpr) string {
+	code := "\n\t// Sessions loads and saves the session of each request. "
+	switch app.SessionStore {
+	case "cookie":
+		code += "It is required\n\t// and must use a session.CookieStore.\n"
+	case "filesystem":
+		code += "It defaults to a\n\t// manager keeping sessions in tmp/sessions.\n"
+	default:
+		code += "It defaults to a\n\t// manager keeping sessions in memory.\n"
+	}
+	return code + "\tSessions *session.Manager\n"
 }
 
-// addPageRoute adds a page route to the router code.
-func (g *InterfaceGenerator) addPageRoute(code *string, page *expr.PageExpr) {
-	for _, route := range page.Routes {
-		methodName := toTitle(page.Name)
-		if route.Method != "GET" {
-			methodName += toTitle(route.Method)
-		}
+// sessionsSetup returns the statements of MountRoutes defaulting or
+// requiring c.Sessions.
+func sessionsSetup(app *expr.AppExpr) string {
+	code := "\tif c.Sessions == nil {\n"
+	switch app.SessionStore {
+	case "cookie":
+		code += "\t\tpanic(\"Controllers.Sessions is required by the cookie session store\")\n"
+	case "filesystem":
+		code += "\t\tstore, err := session.NewFilesystemStore(\"tmp/sessions\")\n"
+		code += "\t\tif err != nil {\n"
+		code += "\t\t\tpanic(err)\n"
+		code += "\t\t}\n"
+		code += "\t\tc.Sessions = session.NewManager(store)\n"
+	default:
+		code += "\t\tc.Sessions = session.NewManager(session.NewMemoryStore())\n"
+	}
+	return code + "\t}\n"
+}
+
+// routerImports returns the gluey imports of the generated router.
+func routerImports(app *expr.AppExpr) string {
+	var imports []string
+	if hasAuthRequirements(app) || len(app.Middleware) > 0 || hasRouteMiddleware(app) || needsMethodOverride(app) || hasRouteLayouts(app) || hasFormats(app) {
+		imports = append(imports, "\t\"github.com/gobijan/gluey/runtime\"\n")
+	}
+	if app.SessionStore != "" {
+		imports = append(imports, "\t\"github.com/gobijan/gluey/runtime/session\"\n")
+	}
+	if len(imports) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(imports, "")
+}
 
-		*code += fmt.Sprintf("\tmux.HandleFunc(\"%s %s\", c.Pages.%s)\n",
-			route.Method, route.Path, methodName)
+// hasAuthRequirements returns true if any route of the app has Auth
+// requirements.
+func hasAuthRequirements(app *expr.AppExpr) bool {
+	for _, resource := range app.Resources {
+		for _, action := range resource.ActionNames() {
+			if len(resource.AuthRequirements[action]) > 0 {
+				return true
+			}
+		}
+	}
+	for _, page := range app.Pages {
+		if len(page.AuthRequirements) > 0 {
+			return true
+		}
 	}
+	return false
 }
 
 // getActionComment returns a descriptive comment for an action.
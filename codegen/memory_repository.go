@@ -0,0 +1,261 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gobijan/gluey/expr"
+)
+
+// generateMemoryRepository generates the in-memory repository for a
+// resource's model if it doesn't exist.
+func (g *ExampleGenerator) generateMemoryRepository(resource *expr.ResourceExpr) error {
+	filename := filepath.Join(g.OutputDir, fmt.Sprintf("app/repositories/%s.go", resource.QualifiedName()))
+	if fileExists(filename) {
+		fmt.Printf("  Skipping %s (already exists)\n", filename)
+		return nil
+	}
+
+	fmt.Printf("  Creating %s\n", filename)
+	return os.WriteFile(filename, []byte(g.memoryRepository(resource)), 0644)
+}
+
+// memoryRepository returns the source of a thread-safe in-memory
+// implementation of the repository interface of a resource's model. Missing
+// IDs are allocated on Create, in sequence for integers and at random for
+// strings. List applies the search, filter and pagination index params.
+func (g *ExampleGenerator) memoryRepository(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	pk := model.PrimaryKey()
+	types := NewTypesGenerator(g.app)
+	repoType := model.Name + "MemoryRepository"
+	idType := types.goType(pk.Type)
+	idField := ToFieldName(pk.Name)
+	singular := ToSingular(resource.Name)
+	params, hasParams := indexParamsName(resource)
+	isString := pk.Type.Kind() == expr.StringKind
+
+	search := resource.SearchableFields["index"]
+	filters := resource.FilterableFields["index"]
+	perPage := resource.Pagination["index"]
+
+	buf.WriteString("package repositories\n\n")
+	buf.WriteString("import (\n")
+	buf.WriteString("\t\"context\"\n")
+	buf.WriteString("\t\"fmt\"\n")
+	buf.WriteString("\t\"sort\"\n")
+	if len(search) > 0 {
+		buf.WriteString("\t\"strings\"\n")
+	}
+	buf.WriteString("\t\"sync\"\n\n")
+	buf.WriteString("\t\"github.com/gobijan/gluey/runtime\"\n\n")
+	buf.WriteString(fmt.Sprintf("\t\"%s/gen/types\"\n", g.app.Name))
+	buf.WriteString(")\n\n")
+
+	buf.WriteString(fmt.Sprintf("// %s is a thread-safe, in-memory types.%sRepository.\n", repoType, model.Name))
+	buf.WriteString("// Records are lost when the process exits, which makes it suitable for\n")
+	buf.WriteString("// demos and handler tests.\n")
+	buf.WriteString(fmt.Sprintf("type %s struct {\n", repoType))
+	buf.WriteString("\tmu      sync.RWMutex\n")
+	buf.WriteString(fmt.Sprintf("\trecords map[%s]*types.%s\n", idType, model.Name))
+	if !isString {
+		buf.WriteString(fmt.Sprintf("\tlastID  %s\n", idType))
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("var _ types.%sRepository = (*%s)(nil)\n\n", model.Name, repoType))
+
+	buf.WriteString(fmt.Sprintf("// New%s creates an empty in-memory %s repository.\n", repoType, singular))
+	buf.WriteString(fmt.Sprintf("func New%s() *%s {\n", repoType, repoType))
+	buf.WriteString(fmt.Sprintf("\treturn &%s{records: make(map[%s]*types.%s)}\n", repoType, idType, model.Name))
+	buf.WriteString("}\n\n")
+
+	if hasScopedChildren(g.app, resource) {
+		buf.WriteString(fmt.Sprintf("// Shared%s is the repository of New%s, shared with the controllers\n", repoType, resourceTitle(resource)))
+		buf.WriteString(fmt.Sprintf("// of the resources nested in %s, which look up their %s in it.\n", resource.Name, singular))
+		buf.WriteString(fmt.Sprintf("var Shared%s = New%s()\n\n", repoType, repoType))
+	}
+
+	// List
+	of, scope, fk := resource.Name, "", resource.ForeignKey()
+	if fk != nil {
+		of = fmt.Sprintf("%s with the given %s", resource.Name, fk.Name)
+		scope = fmt.Sprintf(", %s %s", pathArg(fk.Name), types.goType(fk.Type))
+	}
+	if hasParams {
+		buf.WriteString(fmt.Sprintf("// List returns the %s matching params ordered by %s, and the\n", of, pk.Name))
+		buf.WriteString("// total number of matches before pagination.\n")
+		buf.WriteString(fmt.Sprintf("func (r *%s) List(ctx context.Context%s, params *types.%s) ([]*types.%s, int, error) {\n",
+			repoType, scope, params, model.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("// List returns all %s ordered by %s, and their total number.\n", of, pk.Name))
+		buf.WriteString(fmt.Sprintf("func (r *%s) List(ctx context.Context%s) ([]*types.%s, int, error) {\n",
+			repoType, scope, model.Name))
+	}
+	buf.WriteString("\tr.mu.RLock()\n")
+	buf.WriteString("\tdefer r.mu.RUnlock()\n\n")
+	buf.WriteString(fmt.Sprintf("\tlist := make([]*types.%s, 0, len(r.records))\n", model.Name))
+	buf.WriteString("\tfor _, m := range r.records {\n")
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("\t\tif m.%s != %s {\n", ToFieldName(fk.Name), pathArg(fk.Name)))
+		buf.WriteString("\t\t\tcontinue\n")
+		buf.WriteString("\t\t}\n")
+	}
+	if len(search) > 0 || len(filters) > 0 {
+		buf.WriteString(fmt.Sprintf("\t\tif params != nil && !match%s(m, params) {\n", model.Name))
+		buf.WriteString("\t\t\tcontinue\n")
+		buf.WriteString("\t\t}\n")
+	}
+	buf.WriteString("\t\tc := *m\n")
+	buf.WriteString("\t\tlist = append(list, &c)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tsort.Slice(list, func(i, j int) bool {\n")
+	buf.WriteString(fmt.Sprintf("\t\treturn list[i].%s < list[j].%s\n", idField, idField))
+	buf.WriteString("\t})\n\n")
+	buf.WriteString("\ttotal := len(list)\n")
+	if perPage > 0 {
+		buf.WriteString("\tif params != nil {\n")
+		buf.WriteString(fmt.Sprintf("\t\tpage := runtime.NewPage(params.%s, params.%s, %d)\n",
+			ToCamelCase(expr.ParamPage), ToCamelCase(expr.ParamPerPage), perPage))
+		buf.WriteString("\t\tstart, end := page.Bounds(total)\n")
+		buf.WriteString("\t\tlist = list[start:end]\n")
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\treturn list, total, nil\n")
+	buf.WriteString("}\n\n")
+
+	// Get
+	buf.WriteString(fmt.Sprintf("// Get returns the %s with the given %s.\n", singular, pk.Name))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Get(ctx context.Context, id %s) (*types.%s, error) {\n", repoType, idType, model.Name))
+	buf.WriteString("\tr.mu.RLock()\n")
+	buf.WriteString("\tdefer r.mu.RUnlock()\n\n")
+	buf.WriteString("\tm, ok := r.records[id]\n")
+	buf.WriteString("\tif !ok {\n")
+	buf.WriteString("\t\treturn nil, runtime.ErrNotFound\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tc := *m\n")
+	buf.WriteString("\treturn &c, nil\n")
+	buf.WriteString("}\n\n")
+
+	// Create
+	if isString {
+		buf.WriteString(fmt.Sprintf("// Create stores a new %s, generating its %s if it is empty.\n", singular, pk.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("// Create stores a new %s, allocating its %s if it is zero.\n", singular, pk.Name))
+	}
+	buf.WriteString(fmt.Sprintf("func (r *%s) Create(ctx context.Context, m *types.%s) error {\n", repoType, model.Name))
+	buf.WriteString("\tr.mu.Lock()\n")
+	buf.WriteString("\tdefer r.mu.Unlock()\n\n")
+	if isString {
+		buf.WriteString(fmt.Sprintf("\tif m.%s == \"\" {\n", idField))
+		buf.WriteString(fmt.Sprintf("\t\tm.%s = runtime.NewID()\n", idField))
+		buf.WriteString("\t}\n")
+	} else {
+		buf.WriteString(fmt.Sprintf("\tif m.%s == 0 {\n", idField))
+		buf.WriteString(fmt.Sprintf("\t\tm.%s = r.lastID + 1\n", idField))
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\tif _, ok := r.records[m.%s]; ok {\n", idField))
+	buf.WriteString(fmt.Sprintf("\t\treturn fmt.Errorf(\"%s %%v: %%w\", m.%s, runtime.ErrExists)\n", singular, idField))
+	buf.WriteString("\t}\n")
+	if !isString {
+		buf.WriteString(fmt.Sprintf("\tr.lastID = max(r.lastID, m.%s)\n", idField))
+	}
+	buf.WriteString("\tc := *m\n")
+	buf.WriteString(fmt.Sprintf("\tr.records[m.%s] = &c\n", idField))
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n\n")
+
+	// Update
+	buf.WriteString(fmt.Sprintf("// Update replaces the stored %s with m.\n", singular))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Update(ctx context.Context, m *types.%s) error {\n", repoType, model.Name))
+	buf.WriteString("\tr.mu.Lock()\n")
+	buf.WriteString("\tdefer r.mu.Unlock()\n\n")
+	buf.WriteString(fmt.Sprintf("\tif _, ok := r.records[m.%s]; !ok {\n", idField))
+	buf.WriteString("\t\treturn runtime.ErrNotFound\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tc := *m\n")
+	buf.WriteString(fmt.Sprintf("\tr.records[m.%s] = &c\n", idField))
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n\n")
+
+	// Delete
+	buf.WriteString(fmt.Sprintf("// Delete removes the %s with the given %s.\n", singular, pk.Name))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Delete(ctx context.Context, id %s) error {\n", repoType, idType))
+	buf.WriteString("\tr.mu.Lock()\n")
+	buf.WriteString("\tdefer r.mu.Unlock()\n\n")
+	buf.WriteString("\tif _, ok := r.records[id]; !ok {\n")
+	buf.WriteString("\t\treturn runtime.ErrNotFound\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tdelete(r.records, id)\n")
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n")
+
+	if len(search) > 0 || len(filters) > 0 {
+		buf.WriteString("\n")
+		buf.WriteString(g.generateMatch(resource, params))
+	}
+
+	return buf.String()
+}
+
+// hasScopedChildren returns true if resources nested in resource look up
+// their parent in its repository.
+func hasScopedChildren(app *expr.AppExpr, resource *expr.ResourceExpr) bool {
+	for _, r := range app.Resources {
+		if r.Parent == resource && r.ForeignKey() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateMatch generates the function that applies the search and filter
+// index params to a single record.
+func (g *ExampleGenerator) generateMatch(resource *expr.ResourceExpr, params string) string {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	search := resource.SearchableFields["index"]
+	filters := resource.FilterableFields["index"]
+
+	buf.WriteString(fmt.Sprintf("// match%s reports whether m matches the search and filter params.\n", model.Name))
+	buf.WriteString(fmt.Sprintf("func match%s(m *types.%s, params *types.%s) bool {\n", model.Name, model.Name, params))
+
+	if len(search) > 0 {
+		q := "params." + ToCamelCase(expr.ParamSearch)
+		buf.WriteString(fmt.Sprintf("\tif %s != \"\" {\n", q))
+		buf.WriteString(fmt.Sprintf("\t\tq := strings.ToLower(%s)\n", q))
+		buf.WriteString("\t\tif ")
+		for i, name := range search {
+			if i > 0 {
+				buf.WriteString(" &&\n\t\t\t")
+			}
+			buf.WriteString(fmt.Sprintf("!strings.Contains(strings.ToLower(m.%s), q)", ToFieldName(name)))
+		}
+		buf.WriteString(" {\n")
+		buf.WriteString("\t\t\treturn false\n")
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+	}
+
+	for _, name := range filters {
+		param := "params." + ToCamelCase(name)
+		value := "m." + ToFieldName(name)
+		if field := model.Field(name); field == nil || field.Type.Kind() != expr.StringKind {
+			value = fmt.Sprintf("fmt.Sprint(%s)", value)
+		}
+		buf.WriteString(fmt.Sprintf("\tif %s != \"\" && %s != %s {\n", param, value, param))
+		buf.WriteString("\t\treturn false\n")
+		buf.WriteString("\t}\n")
+	}
+
+	buf.WriteString("\treturn true\n")
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
@@ -0,0 +1,210 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/gobijan/gluey/expr"
+)
+
+// HasModels returns true if any resource defines a model.
+func (g *TypesGenerator) HasModels() bool {
+	for _, resource := range g.app.Resources {
+		if resource.Model != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateModels generates the entity structs, repository interfaces and
+// form mapping helpers for the models of the application. The code belongs
+// to the same types package as the forms.
+func (g *TypesGenerator) GenerateModels() (string, error) {
+	var buf bytes.Buffer
+
+	for _, resource := range g.app.Resources {
+		if resource.Model == nil {
+			continue
+		}
+		buf.WriteString(g.generateEntity(resource.Model))
+		buf.WriteString("\n")
+		buf.WriteString(g.generateRepository(resource))
+		buf.WriteString("\n")
+
+		for _, form := range resourceForms(g.app, resource) {
+			if code := g.generateModelMapping(form, resource.Model); code != "" {
+				buf.WriteString(code)
+				buf.WriteString("\n")
+			}
+		}
+	}
+
+	var out bytes.Buffer
+
+	// Header MUST come first, before package declaration
+	description := "models and repository interfaces"
+	out.WriteString(GenerateHeader(description, g.version, g.command))
+
+	out.WriteString("package types\n\n")
+	out.WriteString("import \"context\"\n\n")
+	out.Write(buf.Bytes())
+
+	return out.String(), nil
+}
+
+// generateEntity generates the entity struct for a model.
+func (g *TypesGenerator) generateEntity(model *expr.ModelExpr) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("// %s is the record behind the %s resource.\n", model.Name, model.Resource.Name))
+	buf.WriteString(fmt.Sprintf("type %s struct {\n", model.Name))
+
+	for _, field := range model.Fields {
+		if field.Description != "" {
+			buf.WriteString(fmt.Sprintf("\t// %s\n", field.Description))
+		}
+		buf.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\" db:\"%s\"`\n",
+			ToFieldName(field.Name), g.goType(field.Type), field.Name, field.Name))
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// generateRepository generates the persistence-agnostic repository
+// interface for a resource's model.
+func (g *TypesGenerator) generateRepository(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	name := model.Name + "Repository"
+	idType := g.goType(model.PrimaryKey().Type)
+	plural := resource.Name
+	singular := ToSingular(resource.Name)
+
+	buf.WriteString(fmt.Sprintf("// %s persists %s records.\n", name, model.Name))
+	buf.WriteString("// Get, Update and Delete return runtime.ErrNotFound for unknown IDs.\n")
+	buf.WriteString(fmt.Sprintf("type %s interface {\n", name))
+
+	// Nested resources are listed per parent
+	of, scope := plural, ""
+	if fk := resource.ForeignKey(); fk != nil {
+		of = fmt.Sprintf("%s with the given %s", plural, fk.Name)
+		scope = fmt.Sprintf(", %s %s", pathArg(fk.Name), g.goType(fk.Type))
+	}
+	if params, ok := indexParamsName(resource); ok {
+		buf.WriteString(fmt.Sprintf("\t// List returns the %s matching params and the total number of\n", of))
+		buf.WriteString("\t// matches before pagination.\n")
+		buf.WriteString(fmt.Sprintf("\tList(ctx context.Context%s, params *%s) ([]*%s, int, error)\n", scope, params, model.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("\t// List returns all %s and their total number.\n", of))
+		buf.WriteString(fmt.Sprintf("\tList(ctx context.Context%s) ([]*%s, int, error)\n", scope, model.Name))
+	}
+
+	buf.WriteString(fmt.Sprintf("\t// Get returns the %s with the given ID.\n", singular))
+	buf.WriteString(fmt.Sprintf("\tGet(ctx context.Context, id %s) (*%s, error)\n", idType, model.Name))
+	buf.WriteString(fmt.Sprintf("\t// Create stores a new %s, assigning its ID if unset.\n", singular))
+	buf.WriteString(fmt.Sprintf("\tCreate(ctx context.Context, m *%s) error\n", model.Name))
+	buf.WriteString(fmt.Sprintf("\t// Update stores the changes to an existing %s.\n", singular))
+	buf.WriteString(fmt.Sprintf("\tUpdate(ctx context.Context, m *%s) error\n", model.Name))
+	buf.WriteString(fmt.Sprintf("\t// Delete removes the %s with the given ID.\n", singular))
+	buf.WriteString(fmt.Sprintf("\tDelete(ctx context.Context, id %s) error\n", idType))
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// generateModelMapping generates the helpers that copy fields between a
+// form and a model. Fields are matched by name and type; the primary key is
+// never copied from a form. It returns an empty string if the form shares
+// no fields with the model.
+func (g *TypesGenerator) generateModelMapping(form *expr.FormExpr, model *expr.ModelExpr) string {
+	fields := g.mappedFields(form, model)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("// To%s returns a new %s with the fields of %s.\n", model.Name, model.Name, form.Name))
+	buf.WriteString(fmt.Sprintf("func (f *%s) To%s() *%s {\n", form.Name, model.Name, model.Name))
+	buf.WriteString(fmt.Sprintf("\tm := &%s{}\n", model.Name))
+	buf.WriteString(fmt.Sprintf("\tf.ApplyTo%s(m)\n", model.Name))
+	buf.WriteString("\treturn m\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("// ApplyTo%s copies the fields of %s to m, leaving its ID unchanged.\n", model.Name, form.Name))
+	buf.WriteString(fmt.Sprintf("func (f *%s) ApplyTo%s(m *%s) {\n", form.Name, model.Name, model.Name))
+	for _, field := range fields {
+		buf.WriteString(fmt.Sprintf("\tm.%s = f.%s\n", field.model, field.form))
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("// From%s fills %s from m, e.g. to pre-fill an edit form.\n", model.Name, form.Name))
+	buf.WriteString(fmt.Sprintf("func (f *%s) From%s(m *%s) {\n", form.Name, model.Name, model.Name))
+	for _, field := range fields {
+		buf.WriteString(fmt.Sprintf("\tf.%s = m.%s\n", field.form, field.model))
+	}
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// mappedField is a field copied between a form and a model, by its Go
+// names in both.
+type mappedField struct {
+	form  string
+	model string
+}
+
+// mappedFields returns the fields copied between a form and a model.
+func (g *TypesGenerator) mappedFields(form *expr.FormExpr, model *expr.ModelExpr) []mappedField {
+	var fields []mappedField
+	for _, attr := range form.Attributes {
+		field := model.Field(attr.Name)
+		if field == nil || field.IsPrimaryKey() || g.goType(field.Type) != g.goType(attr.Type) {
+			continue
+		}
+		fields = append(fields, mappedField{form: g.toGoName(attr.Name), model: ToFieldName(field.Name)})
+	}
+	return fields
+}
+
+// resourceForms returns the forms used by a resource: those defined within
+// it, sorted by name, followed by app-level forms used by its actions.
+func resourceForms(app *expr.AppExpr, resource *expr.ResourceExpr) []*expr.FormExpr {
+	names := make([]string, 0, len(resource.Forms))
+	for name := range resource.Forms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	forms := make([]*expr.FormExpr, 0, len(names)+2)
+	for _, name := range names {
+		forms = append(forms, resource.Forms[name])
+	}
+
+	for _, name := range []string{resource.NewFormName(), resource.EditFormName()} {
+		if _, ok := resource.Forms[name]; ok {
+			continue
+		}
+		form := app.Form(name)
+		if form == nil {
+			continue
+		}
+		duplicate := false
+		for _, f := range forms {
+			if f == form {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			forms = append(forms, form)
+		}
+	}
+
+	return forms
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
 )
 
 // RouterGenerator generates the router setup.
@@ -36,16 +37,24 @@ func (g *RouterGenerator) SetCommand(command string) {
 
 // Generate generates the router setup code.
 func (g *RouterGenerator) Generate() (string, error) {
+	if err := Validate(g.app); err != nil {
+		return "", err
+	}
+
 	var buf bytes.Buffer
 
 	// Header MUST come first, before package declaration
 	description := "HTTP router setup"
 	buf.WriteString(GenerateHeader(description, g.version, g.command))
 
+	hasAuth := hasAuthRequirements(g.app)
+	hasMiddleware := len(g.app.Middleware) > 0 || hasRouteMiddleware(g.app)
+
 	buf.WriteString(fmt.Sprintf("package %s\n\n", g.app.Name))
 	buf.WriteString("import (\n")
 	buf.WriteString("\t\"net/http\"\n")
 	buf.WriteString(fmt.Sprintf("\t\"%s/app/controllers\"\n", g.app.Name))
+	buf.WriteString(routerImports(g.app))
 	buf.WriteString(")\n\n")
 
 	// Generate Controllers interface
@@ -62,17 +71,56 @@ func (g *RouterGenerator) Generate() (string, error) {
 		buf.WriteString("\tPages controllers.PagesController\n")
 	}
 
+	if hasAuth {
+		buf.WriteString("\n")
+		buf.WriteString("\t// Authorizer checks the Auth requirements of routes. It is required.\n")
+		buf.WriteString("\tAuthorizer runtime.Authorizer\n")
+		buf.WriteString("\t// AuthPolicy decides how failed checks are answered.\n")
+		buf.WriteString("\tAuthPolicy runtime.AuthPolicy\n")
+	}
+
+	if g.app.SessionStore != "" {
+		buf.WriteString(sessionsField(g.app))
+	}
+
+	buf.WriteString(csrfFailureField(g.app))
+
+	if hasMiddleware {
+		buf.WriteString("\n")
+		buf.WriteString("\t// Middleware supplies the custom middleware named in Use, and may\n")
+		buf.WriteString("\t// replace built-in ones.\n")
+		buf.WriteString("\tMiddleware map[string]runtime.Middleware\n")
+	}
+
 	buf.WriteString("}\n\n")
 
 	// Generate MountRoutes function
-	buf.WriteString("// MountRoutes mounts all routes on the given mux.\n")
-	buf.WriteString("func MountRoutes(mux *http.ServeMux, c Controllers) {\n")
+	buf.WriteString("// MountRoutes mounts all routes on the given mux and returns the handler\n")
+	buf.WriteString("// to serve, which wraps the mux with the middleware stack.\n")
+	if hasAuth {
+		buf.WriteString("// It panics if c.Authorizer is nil, as some routes require authorization.\n")
+	}
+	if g.app.SessionStore == "cookie" {
+		buf.WriteString("// It panics if c.Sessions is nil, as the cookie store needs keys.\n")
+	}
+	buf.WriteString("func MountRoutes(mux *http.ServeMux, c Controllers) http.Handler {\n")
+
+	if hasAuth {
+		buf.WriteString("\tif c.Authorizer == nil {\n")
+		buf.WriteString("\t\tpanic(\"Controllers.Authorizer is required by routes with Auth requirements\")\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\t// auth wraps a handler with the Auth requirements of its route\n")
+		buf.WriteString("\tauth := func(resource, action string, next http.HandlerFunc, requirements ...string) http.HandlerFunc {\n")
+		buf.WriteString("\t\treturn runtime.RequireAuth(c.Authorizer, c.AuthPolicy, resource, action, requirements, next)\n")
+		buf.WriteString("\t}\n\n")
+	}
+
+	if g.app.SessionStore != "" {
+		buf.WriteString(sessionsSetup(g.app) + "\n")
+	}
 
-	// Add middleware comment
-	if len(g.app.Middleware) > 0 {
-		buf.WriteString("\t// TODO: Apply middleware stack: ")
-		buf.WriteString(strings.Join(g.app.Middleware, ", "))
-		buf.WriteString("\n\n")
+	if hasRouteMiddleware(g.app) {
+		buf.WriteString(useHelper)
 	}
 
 	// Mount resource routes
@@ -93,6 +141,10 @@ func (g *RouterGenerator) Generate() (string, error) {
 			g.app.AssetsPath, g.app.AssetsPath))
 	}
 
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n\n")) {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(middlewareReturn(g.app))
 	buf.WriteString("}\n")
 
 	return buf.String(), nil
@@ -101,25 +153,27 @@ func (g *RouterGenerator) Generate() (string, error) {
 // generateResourceRoutes generates routes for a resource.
 func (g *RouterGenerator) generateResourceRoutes(buf *bytes.Buffer, resource *expr.ResourceExpr) {
 	controllerVar := "c." + ToTitle(resource.Name)
-	basePath := "/" + resource.Name
+	basePath, memberPath := "/"+resource.Name, "/"+resource.Name
 
-	// Handle nested resources
+	// Nested resources are mounted under the id params of their ancestors,
+	// except for the member routes of shallow ones
 	if resource.Parent != nil {
-		basePath = "/" + resource.Parent.Name + "/{" + g.toSingular(resource.Parent.Name) + "_id}/" + resource.Name
+		basePath, memberPath = resource.Path(), resource.MemberPath()
 	}
 
 	fmt.Fprintf(buf, "\t// %s routes\n", ToTitle(resource.Name))
 
-	for _, action := range resource.Actions {
-		method, path := g.getRouteForAction(action, basePath, resource.Name)
-		handler := fmt.Sprintf("%s.%s", controllerVar, ToTitle(action))
+	for _, action := range resource.ActionNames() {
+		methods, path := g.getRouteForAction(resource, action, basePath, memberPath)
+		handler := fmt.Sprintf("%s.%s", controllerVar, ToCamelCase(action))
+		handler = resourceHandler(g.app, resource, action, handler)
 
-		// Add auth comment if required
-		if auths, ok := resource.AuthRequirements[action]; ok && len(auths) > 0 {
-			fmt.Fprintf(buf, "\t// Requires: %s\n", strings.Join(auths, ", "))
+		for _, method := range methods {
+			fmt.Fprintf(buf, "\tmux.HandleFunc(\"%s %s\", %s)\n", method, path, handler)
+			if jsonRoute(resource, action, path) {
+				fmt.Fprintf(buf, "\tmux.HandleFunc(\"%s %s\", %s)\n", method, path+runtime.JSONSuffix, handler)
+			}
 		}
-
-		fmt.Fprintf(buf, "\tmux.HandleFunc(\"%s %s\", %s)\n", method, path, handler)
 	}
 }
 
@@ -127,41 +181,40 @@ func (g *RouterGenerator) generateResourceRoutes(buf *bytes.Buffer, resource *ex
 func (g *RouterGenerator) generatePageRoutes(buf *bytes.Buffer, page *expr.PageExpr) {
 	for _, route := range page.Routes {
 		methodName := g.toPageMethodName(page.Name, route.Method)
-		handler := fmt.Sprintf("c.Pages.%s", methodName)
-
-		// Add auth comment if required
-		if len(page.AuthRequirements) > 0 {
-			fmt.Fprintf(buf, "\t// Requires: %s\n", strings.Join(page.AuthRequirements, ", "))
-		}
+		handler := routeHandler("pages", page.Name, fmt.Sprintf("c.Pages.%s", methodName), routeLayout(g.app, page.Layout), page.Middleware, page.AuthRequirements)
 
 		fmt.Fprintf(buf, "\tmux.HandleFunc(\"%s %s\", %s)\n", route.Method, route.Path, handler)
 	}
 }
 
-// getRouteForAction returns the HTTP method and path for a RESTful action.
-func (g *RouterGenerator) getRouteForAction(action, basePath, resourceName string) (string, string) {
+// getRouteForAction returns the HTTP methods and path for an action of
+// resource, collection actions under basePath and member actions under
+// memberPath.
+// Browser forms reach PATCH, PUT and DELETE routes through the _method field
+// handled by runtime.MethodOverride.
+func (g *RouterGenerator) getRouteForAction(resource *expr.ResourceExpr, action, basePath, memberPath string) ([]string, string) {
 	switch action {
 	case "index":
-		return "GET", basePath
+		return []string{"GET"}, basePath
 	case "show":
-		return "GET", basePath + "/{id}"
+		return []string{"GET"}, memberPath + "/{id}"
 	case "new":
-		return "GET", basePath + "/new"
+		return []string{"GET"}, basePath + "/new"
 	case "create":
-		return "POST", basePath
+		return []string{"POST"}, basePath
 	case "edit":
-		return "GET", basePath + "/{id}/edit"
+		return []string{"GET"}, memberPath + "/{id}/edit"
 	case "update":
-		// Using POST with _method override for browser compatibility
-		// Real apps would handle both PUT and PATCH
-		return "POST", basePath + "/{id}"
+		return []string{"PATCH", "PUT"}, memberPath + "/{id}"
 	case "destroy":
-		// Using POST with _method override for browser compatibility
-		// Real apps would handle DELETE
-		return "POST", basePath + "/{id}/delete"
+		return []string{"DELETE"}, memberPath + "/{id}"
 	default:
-		// Custom action
-		return "POST", basePath + "/{id}/" + action
+		// Custom action declared with Member or Collection
+		config := resource.ActionConfigs[action]
+		if !config.Member {
+			return []string{config.Method}, basePath + "/" + action
+		}
+		return []string{config.Method}, memberPath + "/{id}/" + action
 	}
 }
 
@@ -174,11 +227,6 @@ func (g *RouterGenerator) toControllerName(resourceName string) string {
 	return name + "Controller"
 }
 
-// toSingular converts a plural resource name to singular.
-func (g *RouterGenerator) toSingular(plural string) string {
-	return ToSingular(plural)
-}
-
 // toPageMethodName converts a page name and method to a method name.
 func (g *RouterGenerator) toPageMethodName(pageName, method string) string {
 	name := ToTitle(pageName)
@@ -0,0 +1,535 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
+)
+
+// Route is a route of the generated router.
+type Route struct {
+	// Method is the HTTP method of the route.
+	Method string `json:"method"`
+	// Path is the ServeMux path pattern, such as "/posts/{id}".
+	Path string `json:"path"`
+	// Handler is the Go expression of the handler mounted by MountRoutes.
+	Handler string `json:"-"`
+	// Controller is the controller method serving the route, such as
+	// "Posts.Show". It is empty for static files.
+	Controller string `json:"controller,omitempty"`
+	// Auth lists the Auth requirements of the route.
+	Auth []string `json:"auth,omitempty"`
+	// Middleware lists the middleware of the route, outermost first.
+	Middleware []string `json:"middleware,omitempty"`
+	// Form is the form bound by the action of the route, if any.
+	Form string `json:"form,omitempty"`
+	// Source describes the declaration of the route, such as
+	// `resource "posts" action "show"`.
+	Source string `json:"source"`
+}
+
+// Pattern returns the ServeMux pattern of the route, such as
+// "GET /posts/{id}".
+func (r *Route) Pattern() string {
+	return r.Method + " " + r.Path
+}
+
+// RouteTable holds the routes of the generated router of an app.
+type RouteTable struct {
+	// Routes are sorted by path, more specific paths first, then by
+	// method.
+	Routes []*Route
+}
+
+// NewRouteTable collects the routes of the resources, pages and static
+// files of app. ServeMux panics when two patterns match the same requests
+// and neither is more specific, so these are returned as validation errors
+// naming the declarations of both routes.
+func NewRouteTable(app *expr.AppExpr) (*RouteTable, error) {
+	t := &RouteTable{}
+	for _, resource := range app.Resources {
+		t.addResource(app, resource)
+	}
+	for _, page := range app.Pages {
+		t.addPage(app, page)
+	}
+
+	// Static files
+	assets := app.AssetsPath
+	if assets == "" {
+		assets = "/static"
+	}
+	t.Routes = append(t.Routes, &Route{
+		Method:  "GET",
+		Path:    assets + "/",
+		Handler: fmt.Sprintf("http.StripPrefix(%q, http.FileServer(http.Dir(\"public\"))).ServeHTTP", assets+"/"),
+		Source:  "static files",
+	})
+
+	slices.SortStableFunc(t.Routes, compareRoutes)
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Filter returns the routes of the table matching pattern, a case
+// insensitive regular expression, in their method, path, controller, auth
+// requirements, middleware or form.
+func (t *RouteTable) Filter(pattern string) (*RouteTable, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+	}
+	filtered := &RouteTable{}
+	for _, route := range t.Routes {
+		if slices.ContainsFunc(route.columns(), re.MatchString) {
+			filtered.Routes = append(filtered.Routes, route)
+		}
+	}
+	return filtered, nil
+}
+
+// WriteText writes the routes of the table to w as aligned columns.
+func (t *RouteTable) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tCONTROLLER\tAUTH\tMIDDLEWARE\tFORM")
+	for _, route := range t.Routes {
+		columns := route.columns()
+		for i, column := range columns {
+			if column == "" {
+				columns[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the routes of the table to w as a JSON array.
+func (t *RouteTable) WriteJSON(w io.Writer) error {
+	routes := t.Routes
+	if routes == nil {
+		routes = []*Route{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(routes)
+}
+
+// columns returns the columns of the route printed by WriteText.
+func (r *Route) columns() []string {
+	return []string{
+		r.Method,
+		r.Path,
+		r.Controller,
+		strings.Join(r.Auth, ","),
+		strings.Join(r.Middleware, ","),
+		r.Form,
+	}
+}
+
+// addResource adds the routes of the actions of resource.
+func (t *RouteTable) addResource(app *expr.AppExpr, resource *expr.ResourceExpr) {
+	// Nested resources are mounted under the id params of their ancestors,
+	// except for the member routes of shallow ones
+	basePath, memberPath := resource.Path(), resource.MemberPath()
+
+	// add adds the route of action, and for JSON resources the .json twin
+	// of paths not ending with the id, which UseFormats strips
+	add := func(method, path, action string) {
+		controller := resourceTitle(resource) + "." + ToCamelCase(action)
+		route := &Route{
+			Method:     method,
+			Path:       path,
+			Handler:    resourceHandler(app, resource, action, "c."+controller),
+			Controller: controller,
+			Auth:       resource.AuthRequirements[action],
+			Middleware: actionMiddleware(resource, action),
+			Form:       actionForm(app, resource, action),
+			Source:     fmt.Sprintf("resource %q action %q", resource.QualifiedName(), action),
+		}
+		t.Routes = append(t.Routes, route)
+		if jsonRoute(resource, action, path) {
+			twin := *route
+			twin.Path += runtime.JSONSuffix
+			t.Routes = append(t.Routes, &twin)
+		}
+	}
+
+	// Singular resources don't have index or {id} in paths
+	member := memberPath + "/{id}"
+	if resource.Singular {
+		member = basePath
+	}
+	for _, action := range resource.ActionNames() {
+		switch action {
+		case "index":
+			if !resource.Singular {
+				add("GET", basePath, action)
+			}
+		case "new":
+			add("GET", basePath+"/new", action)
+		case "create":
+			add("POST", basePath, action)
+		case "show":
+			add("GET", member, action)
+		case "edit":
+			add("GET", member+"/edit", action)
+		case "update":
+			add("PATCH", member, action)
+			if !resource.Singular {
+				add("PUT", member, action)
+			}
+		case "destroy":
+			add("DELETE", member, action)
+		default:
+			config := resource.ActionConfigs[action]
+			add(config.Method, config.Path(), action)
+		}
+	}
+}
+
+// addPage adds the routes of page.
+func (t *RouteTable) addPage(app *expr.AppExpr, page *expr.PageExpr) {
+	for _, route := range page.Routes {
+		controller := "Pages." + toTitle(page.Name)
+		if route.Method != "GET" {
+			controller += toTitle(strings.ToLower(route.Method))
+		}
+		t.Routes = append(t.Routes, &Route{
+			Method:     route.Method,
+			Path:       route.Path,
+			Handler:    routeHandler("pages", page.Name, "c."+controller, routeLayout(app, page.Layout), page.Middleware, page.AuthRequirements),
+			Controller: controller,
+			Auth:       page.AuthRequirements,
+			Middleware: page.Middleware,
+			Form:       page.FormName,
+			Source:     fmt.Sprintf("page %q", page.Name),
+		})
+	}
+}
+
+// actionForm returns the form bound by an action of resource, if any. Like
+// the generators, the RESTful actions bind the form named by UseForm or by
+// convention only if it is declared.
+func actionForm(app *expr.AppExpr, resource *expr.ResourceExpr, action string) string {
+	var name string
+	switch action {
+	case "new", "create":
+		name = resource.NewFormName()
+	case "edit", "update":
+		name = resource.EditFormName()
+	}
+	if name != "" {
+		if findForm(app, resource, name) == nil {
+			return ""
+		}
+		return name
+	}
+	if config := resource.CustomAction(action); config != nil {
+		return config.FormName
+	}
+	return ""
+}
+
+// check returns the conflicts between the routes of the table.
+func (t *RouteTable) check() error {
+	var errs []error
+	for i, r1 := range t.Routes {
+		p1, err := parseRoute(r1)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, r2 := range t.Routes[i+1:] {
+			p2, err := parseRoute(r2)
+			if err != nil {
+				continue
+			}
+			switch p1.compare(p2) {
+			case equivalent:
+				errs = append(errs, &expr.ValidationError{
+					Message: fmt.Sprintf("duplicate route %q of %s and %q of %s", r1.Pattern(), r1.Source, r2.Pattern(), r2.Source),
+				})
+			case overlaps:
+				errs = append(errs, &expr.ValidationError{
+					Message: fmt.Sprintf("ambiguous routes %q of %s and %q of %s: both match %s %s and neither is more specific",
+						r1.Pattern(), r1.Source, r2.Pattern(), r2.Source, p1.commonMethod(p2), p1.commonPath(p2)),
+				})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// relationship is the relationship between the sets of requests matched
+// by two patterns, as defined by ServeMux.
+type relationship int
+
+const (
+	equivalent relationship = iota
+	moreGeneral
+	moreSpecific
+	disjoint
+	overlaps
+)
+
+// inverse returns the relationship seen from the other pattern.
+func (r relationship) inverse() relationship {
+	switch r {
+	case moreGeneral:
+		return moreSpecific
+	case moreSpecific:
+		return moreGeneral
+	}
+	return r
+}
+
+// combine returns the relationship of two patterns from that of two of
+// their parts.
+func (r relationship) combine(other relationship) relationship {
+	switch r {
+	case equivalent:
+		return other
+	case disjoint:
+		return disjoint
+	case overlaps:
+		if other == disjoint {
+			return disjoint
+		}
+		return overlaps
+	}
+	switch other {
+	case equivalent:
+		return r
+	case r.inverse():
+		return overlaps
+	}
+	return other
+}
+
+// segment is a segment of a path pattern: a literal, a {name} wildcard,
+// or a trailing {name...} or "/" matching the rest of the path. The {$}
+// ending is the literal "/".
+type segment struct {
+	literal string
+	wild    bool
+	multi   bool
+}
+
+// routePattern is a parsed route.
+type routePattern struct {
+	method   string
+	segments []segment
+}
+
+// parseRoute parses the pattern of a route like ServeMux does.
+func parseRoute(route *Route) (*routePattern, error) {
+	invalid := func(reason string) error {
+		return &expr.ValidationError{
+			Message: fmt.Sprintf("invalid route %q of %s: %s", route.Pattern(), route.Source, reason),
+		}
+	}
+	if !strings.HasPrefix(route.Path, "/") {
+		return nil, invalid("paths must start with /")
+	}
+
+	p := &routePattern{method: route.Method}
+	rest := route.Path[1:]
+	for rest != "" {
+		var seg string
+		seg, rest, _ = strings.Cut(rest, "/")
+		last := rest == "" && !strings.HasSuffix(route.Path, "/")
+		switch {
+		case !strings.HasPrefix(seg, "{"):
+			if strings.ContainsAny(seg, "{}") {
+				return nil, invalid("wildcards must be whole segments")
+			}
+			p.segments = append(p.segments, segment{literal: seg})
+		case !strings.HasSuffix(seg, "}"):
+			return nil, invalid("wildcards must be whole segments")
+		case seg == "{$}":
+			if !last {
+				return nil, invalid("{$} must end the path")
+			}
+			p.segments = append(p.segments, segment{literal: "/"})
+		case strings.HasSuffix(seg, "...}"):
+			if !last {
+				return nil, invalid("{name...} must end the path")
+			}
+			p.segments = append(p.segments, segment{multi: true})
+		default:
+			p.segments = append(p.segments, segment{wild: true})
+		}
+	}
+	// A trailing slash matches the rest of the path
+	if strings.HasSuffix(route.Path, "/") {
+		p.segments = append(p.segments, segment{multi: true})
+	}
+	return p, nil
+}
+
+// compare returns the relationship between the requests matched by p1 and
+// p2, following net/http: methods first, GET also matching HEAD, then
+// paths segment by segment.
+func (p1 *routePattern) compare(p2 *routePattern) relationship {
+	var methods relationship
+	switch {
+	case p1.method == p2.method:
+		methods = equivalent
+	case p1.method == "GET" && p2.method == "HEAD":
+		methods = moreGeneral
+	case p1.method == "HEAD" && p2.method == "GET":
+		methods = moreSpecific
+	default:
+		return disjoint
+	}
+	return methods.combine(p1.comparePaths(p2))
+}
+
+// comparePaths returns the relationship between the paths matched by p1
+// and p2.
+func (p1 *routePattern) comparePaths(p2 *routePattern) relationship {
+	s1, s2 := p1.segments, p2.segments
+	if len(s1) != len(s2) && !p1.multi() && !p2.multi() {
+		return disjoint
+	}
+	rel := equivalent
+	for ; len(s1) > 0 && len(s2) > 0; s1, s2 = s1[1:], s2[1:] {
+		rel = rel.combine(compareSegments(s1[0], s2[0]))
+		if rel == disjoint {
+			return rel
+		}
+	}
+	switch {
+	case len(s1) == 0 && len(s2) == 0:
+		return rel
+	case len(s1) < len(s2) && p1.multi():
+		return rel.combine(moreGeneral)
+	case len(s2) < len(s1) && p2.multi():
+		return rel.combine(moreSpecific)
+	}
+	return disjoint
+}
+
+// compareSegments returns the relationship between the path segments
+// matched by s1 and s2.
+func compareSegments(s1, s2 segment) relationship {
+	switch {
+	case s1.multi && s2.multi:
+		return equivalent
+	case s1.multi:
+		return moreGeneral
+	case s2.multi:
+		return moreSpecific
+	case s1.wild && s2.wild:
+		return equivalent
+	case s1.wild:
+		if s2.literal == "/" {
+			return disjoint
+		}
+		return moreGeneral
+	case s2.wild:
+		if s1.literal == "/" {
+			return disjoint
+		}
+		return moreSpecific
+	case s1.literal == s2.literal:
+		return equivalent
+	}
+	return disjoint
+}
+
+// multi returns true if the path of p ends with a segment matching the
+// rest of the path.
+func (p *routePattern) multi() bool {
+	return len(p.segments) > 0 && p.segments[len(p.segments)-1].multi
+}
+
+// commonMethod returns a method matched by both p1 and p2.
+func (p1 *routePattern) commonMethod(p2 *routePattern) string {
+	if p1.method == "HEAD" || p2.method == "HEAD" {
+		return "HEAD"
+	}
+	return p1.method
+}
+
+// commonPath returns a path matched by both p1 and p2, which match the
+// same requests or overlap: the literals of either, with "x" for
+// segments both match with wildcards.
+func (p1 *routePattern) commonPath(p2 *routePattern) string {
+	var parts []string
+	for i := 0; ; i++ {
+		var s1, s2 *segment
+		if i < len(p1.segments) {
+			s1 = &p1.segments[i]
+		}
+		if i < len(p2.segments) {
+			s2 = &p2.segments[i]
+		}
+		// Segments matching the rest of the path match what the other
+		// pattern has left
+		if s1 == nil || s1.multi {
+			s1 = s2
+		}
+		if s2 == nil || s2.multi {
+			s2 = s1
+		}
+		switch {
+		case s1 == nil || s1.multi:
+			return "/" + strings.Join(parts, "/")
+		case !s1.wild:
+			parts = append(parts, strings.TrimSuffix(s1.literal, "/"))
+		case !s2.wild:
+			parts = append(parts, strings.TrimSuffix(s2.literal, "/"))
+		default:
+			parts = append(parts, "x")
+		}
+	}
+}
+
+// compareRoutes orders routes by path, segment by segment with literals
+// before wildcards, so that more specific paths come first, then by
+// method.
+func compareRoutes(r1, r2 *Route) int {
+	s1, s2 := strings.Split(strings.TrimPrefix(r1.Path, "/"), "/"), strings.Split(strings.TrimPrefix(r2.Path, "/"), "/")
+	for i := 0; i < len(s1) && i < len(s2); i++ {
+		if c := compareSegmentNames(s1[i], s2[i]); c != 0 {
+			return c
+		}
+	}
+	if len(s1) != len(s2) {
+		return len(s1) - len(s2)
+	}
+	return slices.Index(routeMethods, r1.Method) - slices.Index(routeMethods, r2.Method)
+}
+
+// routeMethods lists the methods in the order of their routes.
+var routeMethods = []string{"GET", "HEAD", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"}
+
+// compareSegmentNames orders path segments: literals alphabetically, then
+// wildcards, then empty segments matching the rest of the path.
+func compareSegmentNames(a, b string) int {
+	rank := func(s string) int {
+		switch {
+		case s == "" || strings.HasSuffix(s, "...}"):
+			return 2
+		case strings.HasPrefix(s, "{") && s != "{$}":
+			return 1
+		}
+		return 0
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,508 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
+)
+
+// SQLGenerator generates database/sql repositories and the CREATE TABLE
+// migrations of the models of an application.
+type SQLGenerator struct {
+	app     *expr.AppExpr
+	types   *TypesGenerator
+	version string
+	command string
+}
+
+// NewSQLGenerator creates a new SQL generator.
+func NewSQLGenerator(app *expr.AppExpr) *SQLGenerator {
+	return &SQLGenerator{
+		app:     app,
+		types:   NewTypesGenerator(app),
+		version: "0.1.0",
+		command: "gluey gen design",
+	}
+}
+
+// SetVersion sets the version for generated headers.
+func (g *SQLGenerator) SetVersion(version string) {
+	g.version = version
+}
+
+// SetCommand sets the command for generated headers.
+func (g *SQLGenerator) SetCommand(command string) {
+	g.command = command
+}
+
+// sqlDialects lists the dialects migrations are generated for.
+var sqlDialects = []struct {
+	name  string
+	value runtime.Dialect
+}{
+	{"Postgres", runtime.Postgres},
+	{"SQLite", runtime.SQLite},
+}
+
+// GenerateMigrations generates the CREATE TABLE statements of every model
+// for each dialect, and the Migrate function that runs them.
+func (g *SQLGenerator) GenerateMigrations() (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(GenerateHeader("SQL migrations", g.version, g.command))
+	buf.WriteString("package sqlstore\n\n")
+	buf.WriteString("import (\n")
+	buf.WriteString("\t\"context\"\n")
+	buf.WriteString("\t\"fmt\"\n\n")
+	buf.WriteString("\t\"github.com/gobijan/gluey/runtime\"\n")
+	buf.WriteString(")\n\n")
+
+	buf.WriteString("// migrations holds the CREATE TABLE statements of each dialect.\n")
+	buf.WriteString("var migrations = map[runtime.Dialect][]string{\n")
+	for _, dialect := range sqlDialects {
+		buf.WriteString(fmt.Sprintf("\truntime.%s: {\n", dialect.name))
+		for _, resource := range g.app.Resources {
+			if resource.Model == nil {
+				continue
+			}
+			buf.WriteString(fmt.Sprintf("\t\t%s,\n", goStringLiteral(g.CreateTable(resource, dialect.value))))
+		}
+		buf.WriteString("\t},\n")
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// Schema returns the CREATE TABLE statements for dialect, e.g. to seed a\n")
+	buf.WriteString("// migration tool.\n")
+	buf.WriteString("func Schema(dialect runtime.Dialect) []string {\n")
+	buf.WriteString("\treturn append([]string(nil), migrations[dialect]...)\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// Migrate creates the tables that don't exist yet. Existing tables are\n")
+	buf.WriteString("// never altered.\n")
+	buf.WriteString("func Migrate(ctx context.Context, db runtime.DBTX, dialect runtime.Dialect) error {\n")
+	buf.WriteString("\tstatements, ok := migrations[dialect]\n")
+	buf.WriteString("\tif !ok {\n")
+	buf.WriteString("\t\treturn fmt.Errorf(\"unsupported SQL dialect %q\", dialect)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tfor _, stmt := range statements {\n")
+	buf.WriteString("\t\tif _, err := db.ExecContext(ctx, stmt); err != nil {\n")
+	buf.WriteString("\t\t\treturn err\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn nil\n")
+	buf.WriteString("}\n")
+
+	return buf.String(), nil
+}
+
+// CreateTable returns the CREATE TABLE statement of a resource's model. The
+// table is named after the qualified name of the resource, such as
+// post_comments for comments nested in posts.
+// MaxLength maps to VARCHAR(n), Required to NOT NULL and Enum to a CHECK
+// constraint. Integer primary keys are generated by the database.
+func (g *SQLGenerator) CreateTable(resource *expr.ResourceExpr, dialect runtime.Dialect) string {
+	model := resource.Model
+
+	lines := make([]string, 0, len(model.Fields))
+	for _, field := range model.Fields {
+		lines = append(lines, "  "+g.columnDefinition(field, dialect))
+	}
+	for _, field := range model.Fields {
+		if check := g.enumCheck(field); check != "" {
+			lines = append(lines, "  "+check)
+		}
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", quoteIdent(resource.QualifiedName()), strings.Join(lines, ",\n"))
+}
+
+// columnDefinition returns the definition of a model field's column.
+func (g *SQLGenerator) columnDefinition(field *expr.AttributeExpr, dialect runtime.Dialect) string {
+	column := quoteIdent(field.Name)
+
+	if field.IsPrimaryKey() && field.Type.Kind() == expr.IntKind {
+		if dialect == runtime.SQLite {
+			return column + " INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+		if field.Type == expr.Int32 {
+			return column + " SERIAL PRIMARY KEY"
+		}
+		return column + " BIGSERIAL PRIMARY KEY"
+	}
+
+	def := column + " " + columnType(field, dialect)
+	if field.IsPrimaryKey() {
+		return def + " NOT NULL PRIMARY KEY"
+	}
+	if field.IsRequired() {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// columnType returns the SQL type of a model field.
+func columnType(field *expr.AttributeExpr, dialect runtime.Dialect) string {
+	switch field.Type {
+	case expr.Boolean:
+		return "BOOLEAN"
+	case expr.Int, expr.Int64:
+		return "BIGINT"
+	case expr.Int32:
+		return "INTEGER"
+	case expr.Float32:
+		return "REAL"
+	case expr.Float64:
+		return "DOUBLE PRECISION"
+	case expr.Bytes:
+		if dialect == runtime.Postgres {
+			return "BYTEA"
+		}
+		return "BLOB"
+	case expr.String:
+		if n, ok := field.MaxLength(); ok {
+			return fmt.Sprintf("VARCHAR(%d)", n)
+		}
+		return "TEXT"
+	}
+
+	// Arrays and maps are stored as JSON
+	if dialect == runtime.Postgres {
+		return "JSONB"
+	}
+	return "TEXT"
+}
+
+// enumCheck returns the CHECK constraint of a field with an Enum
+// validation, or an empty string. Optional fields also accept their zero
+// value, as the Enum validation skips empty values.
+func (g *SQLGenerator) enumCheck(field *expr.AttributeExpr) string {
+	values, ok := field.Enum()
+	if !ok || len(values) == 0 {
+		return ""
+	}
+
+	numeric := field.Type.Kind() == expr.IntKind || field.Type.Kind() == expr.FloatKind
+	literal := func(v string) string {
+		if _, err := strconv.ParseFloat(v, 64); numeric && err == nil {
+			return v
+		}
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+
+	literals := make([]string, 0, len(values)+1)
+	for _, v := range values {
+		literals = append(literals, literal(v))
+	}
+	if !field.IsRequired() && !field.IsPrimaryKey() {
+		zero := ""
+		if numeric {
+			zero = "0"
+		}
+		if !slices.Contains(values, zero) {
+			literals = append(literals, literal(zero))
+		}
+	}
+
+	return fmt.Sprintf("CHECK (%s IN (%s))", quoteIdent(field.Name), strings.Join(literals, ", "))
+}
+
+// GenerateRepository generates the database/sql implementation of the
+// repository interface of a resource's model.
+func (g *SQLGenerator) GenerateRepository(resource *expr.ResourceExpr) (string, error) {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	pk := model.PrimaryKey()
+	repoType := model.Name + "Repository"
+	idType := g.types.goType(pk.Type)
+	idField := ToFieldName(pk.Name)
+	singular := ToSingular(resource.Name)
+	table := quoteIdent(resource.QualifiedName())
+	columnsConst := lowerFirst(model.Name) + "Columns"
+	scanFunc := "scan" + model.Name
+	params, hasParams := indexParamsName(resource)
+	autoID := pk.Type.Kind() == expr.IntKind
+
+	search := resource.SearchableFields["index"]
+	filters := resource.FilterableFields["index"]
+	perPage := resource.Pagination["index"]
+	if !hasParams {
+		search, filters, perPage = nil, nil, 0
+	}
+
+	columns := make([]string, len(model.Fields))
+	fields := make([]string, 0, len(model.Fields))
+	var values []string
+	var scans []string
+	for i, field := range model.Fields {
+		columns[i] = quoteIdent(field.Name)
+		name := "m." + ToFieldName(field.Name)
+		if kind := field.Type.Kind(); kind == expr.ArrayKind || kind == expr.MapKind {
+			scans = append(scans, fmt.Sprintf("runtime.JSON(&%s)", name))
+			name = fmt.Sprintf("runtime.JSON(%s)", name)
+		} else {
+			scans = append(scans, "&"+name)
+		}
+		if field != pk {
+			fields = append(fields, field.Name)
+			values = append(values, name)
+		}
+	}
+
+	needsStrconv := perPage > 0
+	for _, name := range filters {
+		if field := model.Field(name); field != nil && field.Type.Kind() != expr.StringKind {
+			needsStrconv = true
+		}
+	}
+
+	buf.WriteString(GenerateHeader(fmt.Sprintf("%s SQL repository", singular), g.version, g.command))
+	buf.WriteString("package sqlstore\n\n")
+	buf.WriteString("import (\n")
+	buf.WriteString("\t\"context\"\n")
+	buf.WriteString("\t\"database/sql\"\n")
+	buf.WriteString("\t\"errors\"\n")
+	if needsStrconv {
+		buf.WriteString("\t\"strconv\"\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString("\t\"github.com/gobijan/gluey/runtime\"\n\n")
+	buf.WriteString(fmt.Sprintf("\t\"%s/gen/types\"\n", g.app.Name))
+	buf.WriteString(")\n\n")
+
+	buf.WriteString(fmt.Sprintf("// %s lists the columns of the %s table in scan order.\n", columnsConst, resource.QualifiedName()))
+	buf.WriteString(fmt.Sprintf("const %s = %s\n\n", columnsConst, goStringLiteral(strings.Join(columns, ", "))))
+
+	buf.WriteString(fmt.Sprintf("// %s is a database/sql types.%sRepository.\n", repoType, model.Name))
+	buf.WriteString(fmt.Sprintf("type %s struct {\n", repoType))
+	buf.WriteString("\tdb      runtime.DBTX\n")
+	buf.WriteString("\tdialect runtime.Dialect\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("var _ types.%sRepository = (*%s)(nil)\n\n", model.Name, repoType))
+
+	buf.WriteString(fmt.Sprintf("// New%s returns a %s repository using db, which may be a\n", repoType, singular))
+	buf.WriteString("// *sql.DB or a *sql.Tx.\n")
+	buf.WriteString(fmt.Sprintf("func New%s(db runtime.DBTX, dialect runtime.Dialect) *%s {\n", repoType, repoType))
+	buf.WriteString(fmt.Sprintf("\treturn &%s{db: db, dialect: dialect}\n", repoType))
+	buf.WriteString("}\n\n")
+
+	// List
+	of, scope, fk := resource.Name, "", resource.ForeignKey()
+	if fk != nil {
+		of = fmt.Sprintf("%s with the given %s", resource.Name, fk.Name)
+		scope = fmt.Sprintf(", %s %s", pathArg(fk.Name), g.types.goType(fk.Type))
+	}
+	if hasParams {
+		buf.WriteString(fmt.Sprintf("// List returns the %s matching params ordered by %s, and the\n", of, pk.Name))
+		buf.WriteString("// total number of matches before pagination.\n")
+		buf.WriteString(fmt.Sprintf("func (r *%s) List(ctx context.Context%s, params *types.%s) ([]*types.%s, int, error) {\n",
+			repoType, scope, params, model.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("// List returns all %s ordered by %s, and their total number.\n", of, pk.Name))
+		buf.WriteString(fmt.Sprintf("func (r *%s) List(ctx context.Context%s) ([]*types.%s, int, error) {\n",
+			repoType, scope, model.Name))
+	}
+	buf.WriteString("\twhere := runtime.NewWhere(r.dialect)\n")
+	if fk != nil {
+		buf.WriteString(fmt.Sprintf("\twhere.Equal(%s, %s)\n", goStringLiteral(quoteIdent(fk.Name)), pathArg(fk.Name)))
+	}
+	if len(search) > 0 || len(filters) > 0 {
+		buf.WriteString("\tif params != nil {\n")
+		buf.WriteString(g.generateWhere(resource))
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString("\tvar total int\n")
+	buf.WriteString(fmt.Sprintf("\tcount := r.dialect.Rebind(%s + where.String())\n", goStringLiteral("SELECT COUNT(*) FROM "+table)))
+	buf.WriteString("\tif err := r.db.QueryRowContext(ctx, count, where.Args()...).Scan(&total); err != nil {\n")
+	buf.WriteString("\t\treturn nil, 0, err\n")
+	buf.WriteString("\t}\n\n")
+	buf.WriteString(fmt.Sprintf("\tquery := \"SELECT \" + %s + %s + where.String() + %s\n",
+		columnsConst, goStringLiteral(" FROM "+table), goStringLiteral(" ORDER BY "+quoteIdent(pk.Name))))
+	if perPage > 0 {
+		buf.WriteString("\tif params != nil {\n")
+		buf.WriteString(fmt.Sprintf("\t\tpage := runtime.NewPage(params.%s, params.%s, %d)\n",
+			ToCamelCase(expr.ParamPage), ToCamelCase(expr.ParamPerPage), perPage))
+		buf.WriteString("\t\tquery += \" LIMIT \" + strconv.Itoa(page.PerPage) + \" OFFSET \" + strconv.Itoa(page.Offset())\n")
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\trows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query), where.Args()...)\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\treturn nil, 0, err\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tdefer rows.Close()\n\n")
+	buf.WriteString(fmt.Sprintf("\tlist := []*types.%s{}\n", model.Name))
+	buf.WriteString("\tfor rows.Next() {\n")
+	buf.WriteString(fmt.Sprintf("\t\tm, err := %s(rows)\n", scanFunc))
+	buf.WriteString("\t\tif err != nil {\n")
+	buf.WriteString("\t\t\treturn nil, 0, err\n")
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t\tlist = append(list, m)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn list, total, rows.Err()\n")
+	buf.WriteString("}\n\n")
+
+	// Get
+	buf.WriteString(fmt.Sprintf("// Get returns the %s with the given %s.\n", singular, pk.Name))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Get(ctx context.Context, id %s) (*types.%s, error) {\n", repoType, idType, model.Name))
+	buf.WriteString(fmt.Sprintf("\tquery := \"SELECT \" + %s + %s\n",
+		columnsConst, goStringLiteral(fmt.Sprintf(" FROM %s WHERE %s = ?", table, quoteIdent(pk.Name)))))
+	buf.WriteString(fmt.Sprintf("\tm, err := %s(r.db.QueryRowContext(ctx, r.dialect.Rebind(query), id))\n", scanFunc))
+	buf.WriteString("\tif errors.Is(err, sql.ErrNoRows) {\n")
+	buf.WriteString("\t\treturn nil, runtime.ErrNotFound\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn m, err\n")
+	buf.WriteString("}\n\n")
+
+	// Create
+	insert := func(names []string) string {
+		if len(names) == 0 {
+			return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table)
+		}
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quoteIdent(name)
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table,
+			strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+	}
+	allFields := append([]string{pk.Name}, fields...)
+	allValues := append([]string{"m." + idField}, values...)
+	if autoID {
+		buf.WriteString(fmt.Sprintf("// Create inserts a new %s. A zero %s is assigned by the database.\n", singular, pk.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("// Create inserts a new %s, generating its %s if it is empty.\n", singular, pk.Name))
+	}
+	buf.WriteString(fmt.Sprintf("func (r *%s) Create(ctx context.Context, m *types.%s) error {\n", repoType, model.Name))
+	if autoID {
+		buf.WriteString(fmt.Sprintf("\tif m.%s == 0 {\n", idField))
+		buf.WriteString(fmt.Sprintf("\t\tquery := %s\n", goStringLiteral(insert(fields)+" RETURNING "+quoteIdent(pk.Name))))
+		buf.WriteString(fmt.Sprintf("\t\treturn r.db.QueryRowContext(ctx, r.dialect.Rebind(query)%s).Scan(&m.%s)\n",
+			argList(values), idField))
+		buf.WriteString("\t}\n")
+	} else {
+		buf.WriteString(fmt.Sprintf("\tif m.%s == \"\" {\n", idField))
+		buf.WriteString(fmt.Sprintf("\t\tm.%s = runtime.NewID()\n", idField))
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString(fmt.Sprintf("\tquery := %s\n", goStringLiteral(insert(allFields))))
+	buf.WriteString(fmt.Sprintf("\t_, err := r.db.ExecContext(ctx, r.dialect.Rebind(query)%s)\n", argList(allValues)))
+	buf.WriteString("\treturn err\n")
+	buf.WriteString("}\n\n")
+
+	// Update
+	sets := make([]string, len(fields))
+	for i, name := range fields {
+		sets[i] = quoteIdent(name) + " = ?"
+	}
+	updateValues := append(append([]string(nil), values...), "m."+idField)
+	if len(sets) == 0 {
+		// Nothing to change, but still report unknown records
+		sets = []string{quoteIdent(pk.Name) + " = ?"}
+		updateValues = []string{"m." + idField, "m." + idField}
+	}
+	buf.WriteString(fmt.Sprintf("// Update stores the changes to an existing %s.\n", singular))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Update(ctx context.Context, m *types.%s) error {\n", repoType, model.Name))
+	buf.WriteString(fmt.Sprintf("\tquery := %s\n", goStringLiteral(fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?",
+		table, strings.Join(sets, ", "), quoteIdent(pk.Name)))))
+	buf.WriteString(fmt.Sprintf("\treturn runtime.CheckAffected(r.db.ExecContext(ctx, r.dialect.Rebind(query)%s))\n", argList(updateValues)))
+	buf.WriteString("}\n\n")
+
+	// Delete
+	buf.WriteString(fmt.Sprintf("// Delete removes the %s with the given %s.\n", singular, pk.Name))
+	buf.WriteString(fmt.Sprintf("func (r *%s) Delete(ctx context.Context, id %s) error {\n", repoType, idType))
+	buf.WriteString(fmt.Sprintf("\tquery := %s\n", goStringLiteral(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, quoteIdent(pk.Name)))))
+	buf.WriteString("\treturn runtime.CheckAffected(r.db.ExecContext(ctx, r.dialect.Rebind(query), id))\n")
+	buf.WriteString("}\n\n")
+
+	// Scan
+	buf.WriteString(fmt.Sprintf("// %s scans a row of %s.\n", scanFunc, columnsConst))
+	buf.WriteString(fmt.Sprintf("func %s(row interface{ Scan(dest ...any) error }) (*types.%s, error) {\n", scanFunc, model.Name))
+	buf.WriteString(fmt.Sprintf("\tm := &types.%s{}\n", model.Name))
+	buf.WriteString(fmt.Sprintf("\tif err := row.Scan(%s); err != nil {\n", strings.Join(scans, ", ")))
+	buf.WriteString("\t\treturn nil, err\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn m, nil\n")
+	buf.WriteString("}\n")
+
+	return buf.String(), nil
+}
+
+// generateWhere generates the conditions applying the search and filter
+// index params. A filter that cannot be parsed as its field's type matches
+// no record.
+func (g *SQLGenerator) generateWhere(resource *expr.ResourceExpr) string {
+	var buf bytes.Buffer
+
+	model := resource.Model
+	search := resource.SearchableFields["index"]
+	filters := resource.FilterableFields["index"]
+
+	if len(search) > 0 {
+		q := "params." + ToCamelCase(expr.ParamSearch)
+		columns := make([]string, len(search))
+		for i, name := range search {
+			columns[i] = goStringLiteral(quoteIdent(name))
+		}
+		buf.WriteString(fmt.Sprintf("\t\tif %s != \"\" {\n", q))
+		buf.WriteString(fmt.Sprintf("\t\t\twhere.Search([]string{%s}, %s)\n", strings.Join(columns, ", "), q))
+		buf.WriteString("\t\t}\n")
+	}
+
+	for _, name := range filters {
+		param := "params." + ToCamelCase(name)
+		column := goStringLiteral(quoteIdent(name))
+
+		var parse string
+		field := model.Field(name)
+		if field != nil {
+			switch field.Type {
+			case expr.Int:
+				parse = fmt.Sprintf("strconv.Atoi(%s)", param)
+			case expr.Int32:
+				parse = fmt.Sprintf("strconv.ParseInt(%s, 10, 32)", param)
+			case expr.Int64:
+				parse = fmt.Sprintf("strconv.ParseInt(%s, 10, 64)", param)
+			case expr.Float32:
+				parse = fmt.Sprintf("strconv.ParseFloat(%s, 32)", param)
+			case expr.Float64:
+				parse = fmt.Sprintf("strconv.ParseFloat(%s, 64)", param)
+			case expr.Boolean:
+				parse = fmt.Sprintf("strconv.ParseBool(%s)", param)
+			}
+		}
+
+		buf.WriteString(fmt.Sprintf("\t\tif %s != \"\" {\n", param))
+		if parse == "" {
+			buf.WriteString(fmt.Sprintf("\t\t\twhere.Equal(%s, %s)\n", column, param))
+		} else {
+			buf.WriteString(fmt.Sprintf("\t\t\tv, err := %s\n", parse))
+			buf.WriteString("\t\t\tif err != nil {\n")
+			buf.WriteString(fmt.Sprintf("\t\t\t\treturn []*types.%s{}, 0, nil\n", model.Name))
+			buf.WriteString("\t\t\t}\n")
+			buf.WriteString(fmt.Sprintf("\t\t\twhere.Equal(%s, v)\n", column))
+		}
+		buf.WriteString("\t\t}\n")
+	}
+
+	return buf.String()
+}
+
+// quoteIdent quotes an SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// argList formats values as trailing call arguments.
+func argList(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(values, ", ")
+}
@@ -0,0 +1,6 @@
+// Package sqlitetest runs the SQL store generated by package codegen
+// against an in-memory SQLite database.
+//
+// It is a module of its own so that the SQLite driver stays out of the
+// dependencies of gluey. Run its tests from this directory with go test.
+package sqlitetest
@@ -0,0 +1,335 @@
+package sqlitetest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gobijan/gluey/codegen"
+	"github.com/gobijan/gluey/expr"
+	"github.com/gobijan/gluey/runtime"
+	"golang.org/x/mod/modfile"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteProgram exercises the generated SQL store of TestSQLStoreSQLite
+// and prints what it read back as JSON.
+const sqliteProgram = `package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/gobijan/gluey/runtime"
+	_ "modernc.org/sqlite"
+
+	"testapp/gen/sqlstore"
+	"testapp/gen/types"
+)
+
+func main() {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Every connection would open a database of its own
+	db.SetMaxOpenConns(1)
+
+	if err := sqlstore.Migrate(ctx, db, runtime.SQLite); err != nil {
+		log.Fatal(err)
+	}
+	repo := sqlstore.NewPostRepository(db, runtime.SQLite)
+	for _, post := range []*types.Post{
+		{Title: "Hello SQLite", Status: "published", Views: 3, Tags: []string{"go", "sql"}},
+		{Title: "Draft", Body: "About SQLite", Status: "draft"},
+		{Title: "100% done", Body: "Nothing to see", Status: "published", Views: 3},
+	} {
+		if err := repo.Create(ctx, post); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	post, err := repo.Get(ctx, 1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = repo.Get(ctx, 42)
+	notFound := errors.Is(err, runtime.ErrNotFound)
+
+	list := func(params *types.PostsIndexParams) map[string]any {
+		posts, total, err := repo.List(ctx, params)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ids := []int64{}
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return map[string]any{"ids": ids, "total": total}
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]any{
+		"get":       post,
+		"not_found": notFound,
+		"all":       list(&types.PostsIndexParams{}),
+		"search":    list(&types.PostsIndexParams{Q: "sqlite"}),
+		"filter":    list(&types.PostsIndexParams{Q: "sqlite", Status: "published"}),
+		"views":     list(&types.PostsIndexParams{Views: "3", PerPage: 1, Page: 2}),
+		"like":      list(&types.PostsIndexParams{Q: "100%"}),
+	})
+}
+`
+
+// TestSQLStoreSQLite runs the generated migrations and repository against
+// an in-memory SQLite database, building them as a module of their own.
+func TestSQLStoreSQLite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the build of the generated SQL store in short mode")
+	}
+
+	posts := &expr.ResourceExpr{
+		Name:             "posts",
+		Actions:          []string{"index", "show"},
+		ActionConfigs:    map[string]*expr.ActionConfig{},
+		Pagination:       map[string]int{"index": 10},
+		SearchableFields: map[string][]string{"index": {"title", "body"}},
+		FilterableFields: map[string][]string{"index": {"status", "views"}},
+	}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{
+		{Name: "title", Type: expr.String, Validations: []expr.Validation{&expr.RequiredValidation{}}},
+		{Name: "body", Type: expr.String},
+		{Name: "status", Type: expr.String, Validations: []expr.Validation{
+			&expr.EnumValidation{Values: []string{"draft", "published"}},
+		}},
+		{Name: "views", Type: expr.Int},
+		{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}},
+	}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts}}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	// The migration enforces the field validations
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() failed: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(codegen.NewSQLGenerator(app).CreateTable(posts, runtime.SQLite)); err != nil {
+		t.Fatalf("CREATE TABLE failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO "posts" ("title", "status") VALUES ('Hello', 'archived')`); err == nil {
+		t.Error("the posts table should reject statuses outside the enum")
+	}
+	if _, err := db.Exec(`INSERT INTO "posts" ("body") VALUES ('No title')`); err == nil {
+		t.Error("the posts table should require a title")
+	}
+
+	out := runStore(t, app, sqliteProgram)
+
+	type listResult struct {
+		IDs   []int64 `json:"ids"`
+		Total int     `json:"total"`
+	}
+	var got struct {
+		Get struct {
+			ID     int64    `json:"id"`
+			Title  string   `json:"title"`
+			Status string   `json:"status"`
+			Views  int      `json:"views"`
+			Tags   []string `json:"tags"`
+		} `json:"get"`
+		NotFound bool       `json:"not_found"`
+		All      listResult `json:"all"`
+		Search   listResult `json:"search"`
+		Filter   listResult `json:"filter"`
+		Views    listResult `json:"views"`
+		Like     listResult `json:"like"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+
+	if post := got.Get; post.ID != 1 || post.Title != "Hello SQLite" || post.Status != "published" || post.Views != 3 || !slices.Equal(post.Tags, []string{"go", "sql"}) {
+		t.Errorf("Get(1) = %+v", post)
+	}
+	if !got.NotFound {
+		t.Error("Get() should return runtime.ErrNotFound for missing posts")
+	}
+	for _, tt := range []struct {
+		name string
+		got  listResult
+		ids  []int64
+		want int
+	}{
+		{"all", got.All, []int64{1, 2, 3}, 3},
+		{"search", got.Search, []int64{1, 2}, 2},
+		{"filter", got.Filter, []int64{1}, 1},
+		{"views", got.Views, []int64{3}, 2},
+		{"like", got.Like, []int64{3}, 1},
+	} {
+		if !slices.Equal(tt.got.IDs, tt.ids) || tt.got.Total != tt.want {
+			t.Errorf("List() %s = %v of %d, want %v of %d", tt.name, tt.got.IDs, tt.got.Total, tt.ids, tt.want)
+		}
+	}
+}
+
+// nestedProgram stores comments of a post and top-level comments with the
+// generated SQL store of TestSQLStoreNestedTables.
+const nestedProgram = `package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/gobijan/gluey/runtime"
+	_ "modernc.org/sqlite"
+
+	"testapp/gen/sqlstore"
+	"testapp/gen/types"
+)
+
+func main() {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := sqlstore.Migrate(ctx, db, runtime.SQLite); err != nil {
+		log.Fatal(err)
+	}
+	if err := sqlstore.NewPostRepository(db, runtime.SQLite).Create(ctx, &types.Post{Title: "Hello"}); err != nil {
+		log.Fatal(err)
+	}
+	replies := sqlstore.NewPostCommentRepository(db, runtime.SQLite)
+	if err := replies.Create(ctx, &types.PostComment{PostID: 1, Text: "Nested"}); err != nil {
+		log.Fatal(err)
+	}
+	comments := sqlstore.NewCommentRepository(db, runtime.SQLite)
+	if err := comments.Create(ctx, &types.Comment{Body: "Top-level"}); err != nil {
+		log.Fatal(err)
+	}
+
+	nested, _, err := replies.List(ctx, 1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	top, _, err := comments.List(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]any{"nested": nested, "top": top})
+}
+`
+
+// TestSQLStoreNestedTables checks that a nested resource and a top-level
+// resource of the same name are stored in tables of their own.
+func TestSQLStoreNestedTables(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the build of the generated SQL store in short mode")
+	}
+
+	posts := &expr.ResourceExpr{Name: "posts"}
+	posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts, Fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+	replies := &expr.ResourceExpr{Name: "comments", Parent: posts}
+	replies.Model = &expr.ModelExpr{Name: "PostComment", Resource: replies, Fields: []*expr.AttributeExpr{{Name: "text", Type: expr.String}}}
+	comments := &expr.ResourceExpr{Name: "comments"}
+	comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "body", Type: expr.String}}}
+	app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts, replies, comments}}
+	for _, r := range app.Resources {
+		r.Prepare()
+	}
+
+	schema := codegen.NewSQLGenerator(app)
+	if table := schema.CreateTable(replies, runtime.SQLite); !strings.Contains(table, `CREATE TABLE IF NOT EXISTS "post_comments"`) {
+		t.Errorf("nested comments should have a table of their own, got:\n%s", table)
+	}
+
+	var got struct {
+		Nested []struct {
+			PostID int64  `json:"post_id"`
+			Text   string `json:"text"`
+		} `json:"nested"`
+		Top []struct {
+			Body string `json:"body"`
+		} `json:"top"`
+	}
+	out := runStore(t, app, nestedProgram)
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+	if len(got.Nested) != 1 || got.Nested[0].PostID != 1 || got.Nested[0].Text != "Nested" {
+		t.Errorf("nested comments = %+v, want the comment of post 1", got.Nested)
+	}
+	if len(got.Top) != 1 || got.Top[0].Body != "Top-level" {
+		t.Errorf("top-level comments = %+v, want the top-level comment", got.Top)
+	}
+}
+
+// runStore generates the interfaces of app, builds program with them and
+// the dependencies of this module, and returns its output.
+func runStore(t *testing.T, app *expr.AppExpr, program string) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	if err := codegen.NewInterfaceGenerator(app, filepath.Join(dir, "gen")).Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod := "module testapp\n\ngo " + mod.Go.Version + "\n\nreplace github.com/gobijan/gluey => " + root + "\n"
+	for _, req := range mod.Require {
+		goMod += "\nrequire " + req.Mod.Path + " " + req.Mod.Version + "\n"
+	}
+	goSum, err := os.ReadFile("go.sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{
+		"go.mod":  []byte(goMod),
+		"go.sum":  goSum,
+		"main.go": []byte(program),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off", "GOPROXY=off")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			t.Fatalf("running the generated store failed: %v\n%s", err, exitErr.Stderr)
+		}
+		t.Fatalf("running the generated store failed: %v", err)
+	}
+	return out
+}
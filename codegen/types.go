@@ -3,7 +3,12 @@ package codegen
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/gobijan/gluey/expr"
 )
@@ -13,6 +18,18 @@ type TypesGenerator struct {
 	app     *expr.AppExpr
 	version string
 	command string
+	// patterns collects the package-level regexps needed by the
+	// generated Validate methods.
+	patterns []patternVar
+	// formatsNumbers records whether a Validate method formats a number
+	// with fmt, to check it against an enum.
+	formatsNumbers bool
+}
+
+// patternVar is a precompiled regexp declared once per generated package.
+type patternVar struct {
+	name    string
+	pattern string
 }
 
 // NewTypesGenerator creates a new types generator.
@@ -37,33 +54,8 @@ func (g *TypesGenerator) SetCommand(command string) {
 // Generate generates all form types.
 func (g *TypesGenerator) Generate() (string, error) {
 	var buf bytes.Buffer
-
-	// Header MUST come first, before package declaration
-	description := "form types and validation"
-	buf.WriteString(GenerateHeader(description, g.version, g.command))
-
-	// Write package header
-	buf.WriteString("package types\n\n")
-
-	// Check if we need imports
-	needsImports := len(g.app.Forms) > 0
-
-	// Also check resource forms
-	if !needsImports {
-		for _, resource := range g.app.Resources {
-			if len(resource.Forms) > 0 {
-				needsImports = true
-				break
-			}
-		}
-	}
-
-	// Add imports if needed
-	if needsImports {
-		buf.WriteString("import (\n")
-		buf.WriteString("\t\"github.com/gobijan/gluey/runtime\"\n")
-		buf.WriteString(")\n\n")
-	}
+	g.patterns = nil
+	g.formatsNumbers = false
 
 	// Generate app-level form types (legacy support)
 	for _, form := range g.app.Forms {
@@ -88,11 +80,12 @@ func (g *TypesGenerator) Generate() (string, error) {
 		}
 
 		// Generate query parameter types for actions with params
-		if config, ok := resource.ActionConfigs["index"]; ok && len(config.Params) > 0 {
-			typeName := ToCamelCase(resource.Name) + "IndexParams"
-			code := g.generateParamsType(typeName, config.Params)
-			buf.WriteString(code)
-			buf.WriteString("\n")
+		for _, action := range append([]string{"index"}, resource.CustomActions...) {
+			if typeName, ok := actionParamsName(resource, action); ok {
+				code := g.generateParamsType(typeName, resource.ActionConfigs[action].Params)
+				buf.WriteString(code)
+				buf.WriteString("\n")
+			}
 		}
 
 		// Check if we need to generate default forms
@@ -133,7 +126,43 @@ func (g *TypesGenerator) Generate() (string, error) {
 		}
 	}
 
-	return buf.String(), nil
+	var out bytes.Buffer
+
+	// Header MUST come first, before package declaration
+	description := "form types and validation"
+	out.WriteString(GenerateHeader(description, g.version, g.command))
+
+	// Write package header
+	out.WriteString("package types\n\n")
+
+	// Add imports if needed
+	if g.needsImports() {
+		out.WriteString("import (\n")
+		if g.formatsNumbers {
+			out.WriteString("\t\"fmt\"\n")
+		}
+		out.WriteString("\t\"net/http\"\n")
+		out.WriteString("\t\"net/url\"\n")
+		if len(g.patterns) > 0 {
+			out.WriteString("\t\"regexp\"\n")
+		}
+		out.WriteString("\n\t\"github.com/gobijan/gluey/runtime\"\n")
+		out.WriteString(")\n\n")
+	}
+
+	// Declare precompiled patterns once for the package
+	if len(g.patterns) > 0 {
+		out.WriteString("// Patterns used by Validate methods, compiled once.\n")
+		out.WriteString("var (\n")
+		for _, p := range g.patterns {
+			out.WriteString(fmt.Sprintf("\t%s = regexp.MustCompile(%s)\n", p.name, goStringLiteral(p.pattern)))
+		}
+		out.WriteString(")\n\n")
+	}
+
+	out.Write(buf.Bytes())
+
+	return out.String(), nil
 }
 
 // generateForm generates a single form type.
@@ -151,13 +180,29 @@ func (g *TypesGenerator) generateForm(form *expr.FormExpr) (string, error) {
 
 	buf.WriteString("}\n\n")
 
+	// Generate constructor with default values
+	buf.WriteString(g.generateConstructor(form))
+
+	// Generate the hook interface for custom validations
+	hooks := g.generateValidatorHooks(form)
+	buf.WriteString(hooks)
+
 	// Generate Validate method
 	buf.WriteString(fmt.Sprintf("// Validate validates %s.\n", form.Name))
 	buf.WriteString(fmt.Sprintf("func (f *%s) Validate() error {\n", form.Name))
+	if hooks != "" {
+		iface := form.Name + "Validator"
+		buf.WriteString(fmt.Sprintf("\tif %s == nil {\n", lowerFirst(iface)))
+		buf.WriteString(fmt.Sprintf("\t\treturn &runtime.MissingValidatorError{Name: %q}\n", iface))
+		buf.WriteString("\t}\n\n")
+	}
 	buf.WriteString("\tv := runtime.NewValidator()\n\n")
 
 	for _, attr := range form.Attributes {
-		validationCode := g.generateValidation(attr)
+		validationCode, err := g.generateValidation(form.Name, attr)
+		if err != nil {
+			return "", err
+		}
 		if validationCode != "" {
 			buf.WriteString(validationCode)
 		}
@@ -167,11 +212,290 @@ func (g *TypesGenerator) generateForm(form *expr.FormExpr) (string, error) {
 	buf.WriteString("\t\treturn v.Errors()\n")
 	buf.WriteString("\t}\n")
 	buf.WriteString("\treturn nil\n")
-	buf.WriteString("}\n")
+	buf.WriteString("}\n\n")
+
+	// Generate typed decoding methods
+	fields := make([]decodeField, 0, len(form.Attributes))
+	for _, attr := range form.Attributes {
+		fields = append(fields, decodeField{
+			name:     attr.Name,
+			dataType: attr.Type,
+			def:      attr.DefaultValue,
+		})
+	}
+	buf.WriteString(g.generateDecoder(form.Name, fields))
+	buf.WriteString(g.generatePatch(form.Name, fields))
 
 	return buf.String(), nil
 }
 
+// generateConstructor generates a New{Form} function that returns the form
+// pre-filled with its attribute defaults.
+func (g *TypesGenerator) generateConstructor(form *expr.FormExpr) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("// New%s returns a %s with its default values.\n", form.Name, form.Name))
+	buf.WriteString(fmt.Sprintf("func New%s() *%s {\n", form.Name, form.Name))
+
+	var defaults []string
+	for _, attr := range form.Attributes {
+		if attr.DefaultValue == nil {
+			continue
+		}
+		defaults = append(defaults, fmt.Sprintf("\t\t%s: %s,\n",
+			g.toGoName(attr.Name), g.goLiteral(attr.Type, attr.DefaultValue)))
+	}
+
+	if len(defaults) == 0 {
+		buf.WriteString(fmt.Sprintf("\treturn &%s{}\n", form.Name))
+	} else {
+		buf.WriteString(fmt.Sprintf("\treturn &%s{\n", form.Name))
+		for _, d := range defaults {
+			buf.WriteString(d)
+		}
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("}\n\n")
+
+	return buf.String()
+}
+
+// indexParamsName returns the name of the generated index params type of a
+// resource, if it declares index params.
+func indexParamsName(resource *expr.ResourceExpr) (string, bool) {
+	return actionParamsName(resource, "index")
+}
+
+// actionParamsName returns the name of the generated params type of an
+// action, such as "PostsSearchParams", if it declares params.
+func actionParamsName(resource *expr.ResourceExpr, action string) (string, bool) {
+	if config, ok := resource.ActionConfigs[action]; ok && len(config.Params) > 0 {
+		return ToCamelCase(resource.QualifiedName()) + ToCamelCase(action) + "Params", true
+	}
+	return "", false
+}
+
+// needsImports returns true if any generated type needs the runtime imports.
+func (g *TypesGenerator) needsImports() bool {
+	if len(g.app.Forms) > 0 {
+		return true
+	}
+	for _, resource := range g.app.Resources {
+		if len(resource.Forms) > 0 {
+			return true
+		}
+		for _, action := range append([]string{"index"}, resource.CustomActions...) {
+			if _, ok := actionParamsName(resource, action); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeField describes a field decoded by a generated FromValues method.
+type decodeField struct {
+	name     string
+	dataType expr.DataType
+	def      interface{}
+}
+
+// generateDecoder generates the FromValues and Bind methods for a type.
+func (g *TypesGenerator) generateDecoder(typeName string, fields []decodeField) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("// FromValues decodes %s from form values.\n", typeName))
+	buf.WriteString("// Missing and empty fields take their default value and conversion\n")
+	buf.WriteString("// failures are returned as runtime.ValidationErrors.\n")
+	buf.WriteString(fmt.Sprintf("func (f *%s) FromValues(values url.Values) error {\n", typeName))
+	buf.WriteString("\td := runtime.NewFormDecoder(values)\n\n")
+
+	for _, field := range fields {
+		fieldName := g.toGoName(field.name)
+		decode, ok := g.decodeExpr(field.name, field.dataType, field.def)
+		if !ok {
+			buf.WriteString(fmt.Sprintf("\t// %s: type %s cannot be decoded from form values\n",
+				fieldName, g.goType(field.dataType)))
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("\tf.%s = %s\n", fieldName, decode))
+	}
+
+	buf.WriteString("\n\treturn d.Err()\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("// Bind decodes %s from the request's form and query values, or\n", typeName))
+	buf.WriteString("// its JSON body, see runtime.RequestValues.\n")
+	buf.WriteString(fmt.Sprintf("func (f *%s) Bind(r *http.Request) error {\n", typeName))
+	buf.WriteString("\tvalues, err := runtime.RequestValues(r)\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\treturn err\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn f.FromValues(values)\n")
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// generatePatch generates the Patch method of a form, which binds the
+// submitted fields only, for partial updates.
+func (g *TypesGenerator) generatePatch(typeName string, fields []decodeField) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("\n// Patch decodes the fields of %s submitted in the request, see Bind,\n", typeName))
+	buf.WriteString("// and leaves the others unchanged.\n")
+	buf.WriteString(fmt.Sprintf("func (f *%s) Patch(r *http.Request) error {\n", typeName))
+	buf.WriteString("\tvalues, err := runtime.RequestValues(r)\n")
+	buf.WriteString("\tif err != nil {\n")
+	buf.WriteString("\t\treturn err\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\tsubmitted := *f\n")
+	buf.WriteString("\terr = submitted.FromValues(values)\n\n")
+	buf.WriteString("\td := runtime.NewFormDecoder(values)\n")
+	for _, field := range fields {
+		if _, ok := g.decodeExpr(field.name, field.dataType, field.def); !ok {
+			continue
+		}
+		fieldName := g.toGoName(field.name)
+		buf.WriteString(fmt.Sprintf("\tif d.Has(%q) {\n", field.name))
+		buf.WriteString(fmt.Sprintf("\t\tf.%s = submitted.%s\n", fieldName, fieldName))
+		buf.WriteString("\t}\n")
+	}
+	buf.WriteString("\treturn err\n")
+	buf.WriteString("}\n")
+
+	return buf.String()
+}
+
+// decodeExpr returns the expression that decodes a field with a FormDecoder.
+func (g *TypesGenerator) decodeExpr(name string, dataType expr.DataType, def interface{}) (string, bool) {
+	if dataType == nil {
+		dataType = expr.String
+	}
+	key := fmt.Sprintf("%q", name)
+	defLit := g.goLiteral(dataType, def)
+
+	switch dataType {
+	case expr.Boolean:
+		return fmt.Sprintf("d.Bool(%s, %s)", key, defLit), true
+	case expr.Int:
+		return fmt.Sprintf("d.Int(%s, %s)", key, defLit), true
+	case expr.Int32:
+		return fmt.Sprintf("d.Int32(%s, %s)", key, defLit), true
+	case expr.Int64:
+		return fmt.Sprintf("d.Int64(%s, %s)", key, defLit), true
+	case expr.Float32:
+		return fmt.Sprintf("d.Float32(%s, %s)", key, defLit), true
+	case expr.Float64:
+		return fmt.Sprintf("d.Float64(%s, %s)", key, defLit), true
+	case expr.String:
+		return fmt.Sprintf("d.String(%s, %s)", key, defLit), true
+	case expr.Bytes:
+		return fmt.Sprintf("d.Bytes(%s, %s)", key, defLit), true
+	}
+
+	if arrayType, ok := dataType.(*expr.ArrayType); ok {
+		parse, ok := parseFunc(arrayType.ElemType)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("runtime.DecodeSlice(d, %s, %s, %s)", key, parse, defLit), true
+	}
+
+	if mapType, ok := dataType.(*expr.MapType); ok {
+		parseKey, ok := parseFunc(mapType.KeyType)
+		if !ok {
+			return "", false
+		}
+		parseValue, ok := parseFunc(mapType.ElemType)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("runtime.DecodeMap(d, %s, %s, %s, %s)", key, parseKey, parseValue, defLit), true
+	}
+
+	return "", false
+}
+
+// parseFunc returns the runtime parse function for a primitive type.
+func parseFunc(dataType expr.DataType) (string, bool) {
+	switch dataType {
+	case expr.Boolean:
+		return "runtime.ParseBool", true
+	case expr.Int:
+		return "runtime.ParseInt", true
+	case expr.Int32:
+		return "runtime.ParseInt32", true
+	case expr.Int64:
+		return "runtime.ParseInt64", true
+	case expr.Float32:
+		return "runtime.ParseFloat32", true
+	case expr.Float64:
+		return "runtime.ParseFloat64", true
+	case expr.String, nil:
+		return "runtime.ParseString", true
+	case expr.Bytes:
+		return "runtime.ParseBytes", true
+	}
+	return "", false
+}
+
+// goLiteral renders a default value as a Go literal of the given type.
+// A nil value renders as the type's zero value.
+func (g *TypesGenerator) goLiteral(dataType expr.DataType, value interface{}) string {
+	if dataType == nil {
+		dataType = expr.String
+	}
+
+	if value == nil {
+		switch dataType.Kind() {
+		case expr.BooleanKind:
+			return "false"
+		case expr.IntKind, expr.FloatKind:
+			return "0"
+		case expr.StringKind:
+			return `""`
+		default:
+			return "nil"
+		}
+	}
+
+	switch dataType.Kind() {
+	case expr.StringKind:
+		return fmt.Sprintf("%q", fmt.Sprint(value))
+	case expr.BytesKind:
+		return fmt.Sprintf("[]byte(%q)", fmt.Sprint(value))
+	case expr.BooleanKind, expr.IntKind, expr.FloatKind:
+		return fmt.Sprint(value)
+	}
+
+	rv := reflect.ValueOf(value)
+
+	if arrayType, ok := dataType.(*expr.ArrayType); ok && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) {
+		elems := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elems = append(elems, g.goLiteral(arrayType.ElemType, rv.Index(i).Interface()))
+		}
+		return fmt.Sprintf("%s{%s}", g.goType(dataType), strings.Join(elems, ", "))
+	}
+
+	if mapType, ok := dataType.(*expr.MapType); ok && rv.Kind() == reflect.Map {
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		entries := make([]string, 0, len(keys))
+		for _, k := range keys {
+			entries = append(entries, fmt.Sprintf("%s: %s",
+				g.goLiteral(mapType.KeyType, k.Interface()),
+				g.goLiteral(mapType.ElemType, rv.MapIndex(k).Interface())))
+		}
+		return fmt.Sprintf("%s{%s}", g.goType(dataType), strings.Join(entries, ", "))
+	}
+
+	return "nil"
+}
+
 // generateField generates a struct field.
 func (g *TypesGenerator) generateField(attr *expr.AttributeExpr) string {
 	fieldName := g.toGoName(attr.Name)
@@ -209,51 +533,282 @@ func (g *TypesGenerator) generateTags(attr *expr.AttributeExpr) string {
 		validations = append(validations, fmt.Sprintf("min=%d", min))
 	}
 
+	if isNumeric(attr.Type) {
+		if min, ok := attr.Min(); ok {
+			validations = append(validations, fmt.Sprintf("min=%d", min))
+		}
+		if max, ok := attr.Max(); ok {
+			validations = append(validations, fmt.Sprintf("max=%d", max))
+		}
+	}
+
 	if format, ok := attr.Format(); ok {
 		switch format {
 		case expr.FormatEmail:
 			validations = append(validations, "email")
 		case expr.FormatURL:
 			validations = append(validations, "url")
+		case expr.FormatUUID:
+			validations = append(validations, "uuid")
+		case expr.FormatDate:
+			validations = append(validations, "date")
+		case expr.FormatDateTime:
+			validations = append(validations, "datetime")
 		}
 	}
 
+	if values, ok := attr.Enum(); ok && (isString(attr.Type) || isNumeric(attr.Type)) && !slices.ContainsFunc(values, hasBacktick) {
+		validations = append(validations, "oneof="+oneOfOptions(values))
+	}
+
+	// Patterns may contain commas, so they must be the last rule
+	if pattern, ok := attr.Pattern(); ok && isString(attr.Type) && !strings.Contains(pattern, "`") {
+		validations = append(validations, "pattern="+pattern)
+	}
+
 	if len(validations) > 0 {
-		tags = append(tags, fmt.Sprintf(`validate:"%s"`, strings.Join(validations, ",")))
+		tags = append(tags, fmt.Sprintf(`validate:%q`, strings.Join(validations, ",")))
 	}
 
 	return fmt.Sprintf("`%s`", strings.Join(tags, " "))
 }
 
+// oneOfOptions returns the options of a oneof tag rule, single-quoting
+// values that contain spaces, commas or quotes, or are empty.
+func oneOfOptions(values []string) string {
+	options := make([]string, len(values))
+	for i, value := range values {
+		options[i] = value
+		if value == "" || strings.ContainsFunc(value, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == '\'' }) {
+			options[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+	}
+	return strings.Join(options, " ")
+}
+
+// hasBacktick returns true if s cannot be written in a raw struct tag.
+func hasBacktick(s string) bool {
+	return strings.Contains(s, "`")
+}
+
 // generateValidation generates validation code for an attribute.
-func (g *TypesGenerator) generateValidation(attr *expr.AttributeExpr) string {
+func (g *TypesGenerator) generateValidation(formName string, attr *expr.AttributeExpr) (string, error) {
 	var buf bytes.Buffer
 	fieldName := g.toGoName(attr.Name)
+	field := "f." + fieldName
 
-	if attr.IsRequired() {
-		buf.WriteString(fmt.Sprintf("\tv.Required(\"%s\", f.%s)\n", attr.Name, fieldName))
-	}
+	switch {
+	case isString(attr.Type):
+		if attr.IsRequired() {
+			buf.WriteString(fmt.Sprintf("\tv.Required(%q, %s)\n", attr.Name, field))
+		}
 
-	if format, ok := attr.Format(); ok {
-		switch format {
-		case expr.FormatEmail:
-			buf.WriteString(fmt.Sprintf("\tv.Email(\"%s\", f.%s)\n", attr.Name, fieldName))
-		case expr.FormatURL:
-			buf.WriteString(fmt.Sprintf("\tv.URL(\"%s\", f.%s)\n", attr.Name, fieldName))
+		if format, ok := attr.Format(); ok {
+			switch format {
+			case expr.FormatEmail:
+				buf.WriteString(fmt.Sprintf("\tv.Email(%q, %s)\n", attr.Name, field))
+			case expr.FormatURL:
+				buf.WriteString(fmt.Sprintf("\tv.URL(%q, %s)\n", attr.Name, field))
+			case expr.FormatDate:
+				buf.WriteString(fmt.Sprintf("\tv.Date(%q, %s)\n", attr.Name, field))
+			case expr.FormatDateTime:
+				buf.WriteString(fmt.Sprintf("\tv.DateTime(%q, %s)\n", attr.Name, field))
+			case expr.FormatUUID:
+				buf.WriteString(fmt.Sprintf("\tv.UUID(%q, %s)\n", attr.Name, field))
+			}
+		}
+
+		if min, ok := attr.MinLength(); ok {
+			buf.WriteString(fmt.Sprintf("\tv.MinLength(%q, %s, %d)\n", attr.Name, field, min))
+		}
+
+		if max, ok := attr.MaxLength(); ok {
+			buf.WriteString(fmt.Sprintf("\tv.MaxLength(%q, %s, %d)\n", attr.Name, field, max))
+		}
+
+		if pattern, ok := attr.Pattern(); ok {
+			varName, err := g.addPattern(formName, attr.Name, pattern)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(fmt.Sprintf("\tv.Matches(%q, %s, %s)\n", attr.Name, field, varName))
+		}
+
+		if values, ok := attr.Enum(); ok {
+			buf.WriteString(fmt.Sprintf("\tv.OneOf(%q, %s, %s)\n", attr.Name, field, quoteAll(values)))
+		}
+
+	case isNumeric(attr.Type):
+		if attr.IsRequired() {
+			buf.WriteString(fmt.Sprintf("\tv.Check(%q, %s != 0, \"is required\")\n", attr.Name, field))
+		}
+
+		value := "float64(" + field + ")"
+		if attr.Type == expr.Float64 {
+			value = field
+		}
+
+		min, hasMin := attr.Min()
+		max, hasMax := attr.Max()
+		switch {
+		case hasMin && hasMax:
+			buf.WriteString(fmt.Sprintf("\tv.Range(%q, %s, %d, %d)\n", attr.Name, value, min, max))
+		case hasMin:
+			buf.WriteString(fmt.Sprintf("\tv.Min(%q, %s, %d)\n", attr.Name, value, min))
+		case hasMax:
+			buf.WriteString(fmt.Sprintf("\tv.Max(%q, %s, %d)\n", attr.Name, value, max))
+		}
+
+		// Numeric enums compare the formatted value, zero meaning unset
+		if values, ok := attr.Enum(); ok {
+			g.formatsNumbers = true
+			buf.WriteString(fmt.Sprintf("\tif %s != 0 {\n", field))
+			buf.WriteString(fmt.Sprintf("\t\tv.OneOf(%q, fmt.Sprint(%s), %s)\n", attr.Name, field, quoteAll(values)))
+			buf.WriteString("\t}\n")
+		}
+
+	case attr.Type.Kind() == expr.BooleanKind:
+		if attr.IsRequired() {
+			buf.WriteString(fmt.Sprintf("\tv.Check(%q, %s, \"is required\")\n", attr.Name, field))
+		}
+
+	default:
+		// Bytes, arrays and maps are validated by length
+		if attr.IsRequired() {
+			buf.WriteString(fmt.Sprintf("\tv.Check(%q, len(%s) > 0, \"is required\")\n", attr.Name, field))
+		}
+
+		if min, ok := attr.MinLength(); ok {
+			buf.WriteString(fmt.Sprintf("\tv.MinItems(%q, len(%s), %d)\n", attr.Name, field, min))
+		}
+
+		if max, ok := attr.MaxLength(); ok {
+			buf.WriteString(fmt.Sprintf("\tv.MaxItems(%q, len(%s), %d)\n", attr.Name, field, max))
+		}
+
+		// Enums on string lists apply to every item
+		if arrayType, ok := attr.Type.(*expr.ArrayType); ok && isString(arrayType.ElemType) {
+			if values, ok := attr.Enum(); ok {
+				buf.WriteString(fmt.Sprintf("\tfor _, item := range %s {\n", field))
+				buf.WriteString(fmt.Sprintf("\t\tv.OneOf(%q, item, %s)\n", attr.Name, quoteAll(values)))
+				buf.WriteString("\t}\n")
+			}
 		}
 	}
 
-	if min, ok := attr.MinLength(); ok {
-		buf.WriteString(fmt.Sprintf("\tv.MinLength(\"%s\", f.%s, %d)\n", attr.Name, fieldName, min))
+	// Custom validations declared with dsl.Validation
+	for _, c := range attr.CustomValidations() {
+		switch c.Rule {
+		case expr.RuleMatches:
+			buf.WriteString(fmt.Sprintf("\tv.Match(%q, f.%s, %q, %s)\n",
+				c.Args[0], g.toGoName(c.Args[0]), attr.Name, field))
+		case expr.RuleAccepted:
+			buf.WriteString(fmt.Sprintf("\tv.Check(%q, %s, \"must be accepted\")\n", attr.Name, field))
+		default:
+			buf.WriteString(fmt.Sprintf("\tv.Custom(%q, %s.%s(f))\n",
+				attr.Name, lowerFirst(formName)+"Validator", g.hookName(attr.Name, c.Rule)))
+		}
 	}
 
-	if max, ok := attr.MaxLength(); ok {
-		buf.WriteString(fmt.Sprintf("\tv.MaxLength(\"%s\", f.%s, %d)\n", attr.Name, fieldName, max))
+	return buf.String(), nil
+}
+
+// hookName returns the validator interface method for a custom rule.
+func (g *TypesGenerator) hookName(attrName, rule string) string {
+	return "Validate" + g.toGoName(attrName) + g.toGoName(rule)
+}
+
+// generateValidatorHooks generates the interface, registration variable and
+// setter for the custom validation hooks of a form. It returns an empty
+// string if the form declares no hooks.
+func (g *TypesGenerator) generateValidatorHooks(form *expr.FormExpr) string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, attr := range form.Attributes {
+		for _, c := range attr.CustomValidations() {
+			name := g.hookName(attr.Name, c.Rule)
+			if !c.IsHook() || seen[name] {
+				continue
+			}
+			seen[name] = true
+			methods = append(methods, fmt.Sprintf("\t// %s implements the %q validation of %s.\n\t%s(f *%s) error\n",
+				name, c.Rule, attr.Name, name, form.Name))
+		}
 	}
+	if len(methods) == 0 {
+		return ""
+	}
+
+	iface := form.Name + "Validator"
+	varName := lowerFirst(iface)
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("// %s implements the custom validations declared for %s.\n", iface, form.Name))
+	buf.WriteString(fmt.Sprintf("// Register an implementation with Set%s.\n", iface))
+	buf.WriteString(fmt.Sprintf("type %s interface {\n", iface))
+	buf.WriteString(strings.Join(methods, ""))
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(fmt.Sprintf("// %s is the registered %s.\n", varName, iface))
+	buf.WriteString(fmt.Sprintf("var %s %s\n\n", varName, iface))
+
+	buf.WriteString(fmt.Sprintf("// Set%s registers the %s used by %s.Validate.\n", iface, iface, form.Name))
+	buf.WriteString("// Call it once during application start-up.\n")
+	buf.WriteString(fmt.Sprintf("func Set%s(v %s) {\n", iface, iface))
+	buf.WriteString(fmt.Sprintf("\t%s = v\n", varName))
+	buf.WriteString("}\n\n")
 
 	return buf.String()
 }
 
+// addPattern registers a package-level regexp for a form field and returns
+// its variable name.
+func (g *TypesGenerator) addPattern(formName, attrName, pattern string) (string, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "", fmt.Errorf("%s.%s: invalid pattern %q: %w", formName, attrName, pattern, err)
+	}
+
+	name := lowerFirst(formName) + ToCamelCase(attrName) + "Pattern"
+	g.patterns = append(g.patterns, patternVar{name: name, pattern: pattern})
+	return name, nil
+}
+
+// isString returns true for string attributes (the default type).
+func isString(dataType expr.DataType) bool {
+	return dataType == nil || dataType.Kind() == expr.StringKind
+}
+
+// isNumeric returns true for integer and floating point attributes.
+func isNumeric(dataType expr.DataType) bool {
+	return dataType != nil && (dataType.Kind() == expr.IntKind || dataType.Kind() == expr.FloatKind)
+}
+
+// quoteAll renders values as a comma-separated list of Go string literals.
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// goStringLiteral renders s as a raw string literal when possible, which
+// keeps regular expressions readable.
+func goStringLiteral(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// lowerFirst lowercases the first letter of a string.
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
 // generateDefaultNewForm generates a default form for creating resources.
 func (g *TypesGenerator) generateDefaultNewForm(resource *expr.ResourceExpr) string {
 	name := resource.NewFormName()
@@ -348,7 +903,18 @@ func (g *TypesGenerator) generateParamsType(name string, params []*expr.ParamExp
 		buf.WriteString(fmt.Sprintf("\t%s %s %s\n", fieldName, fieldType, tags))
 	}
 
-	buf.WriteString("}\n")
+	buf.WriteString("}\n\n")
+
+	// Generate typed decoding methods
+	fields := make([]decodeField, 0, len(params))
+	for _, param := range params {
+		fields = append(fields, decodeField{
+			name:     param.Name,
+			dataType: param.Type,
+			def:      param.Default,
+		})
+	}
+	buf.WriteString(g.generateDecoder(name, fields))
 
 	return buf.String()
 }
@@ -3,6 +3,7 @@ package codegen
 import (
 	"strings"
 
+	"github.com/gobijan/gluey/expr"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -20,7 +21,8 @@ func ToSingular(plural string) string {
 	if strings.HasSuffix(plural, "ies") {
 		return plural[:len(plural)-3] + "y"
 	}
-	if strings.HasSuffix(plural, "es") {
+	if strings.HasSuffix(plural, "ses") || strings.HasSuffix(plural, "xes") || strings.HasSuffix(plural, "zes") ||
+		strings.HasSuffix(plural, "ches") || strings.HasSuffix(plural, "shes") {
 		return plural[:len(plural)-2]
 	}
 	if strings.HasSuffix(plural, "s") {
@@ -51,3 +53,27 @@ func ToCamelCase(snakeCase string) string {
 	}
 	return strings.Join(parts, "")
 }
+
+// ToFieldName converts the snake_case name of a model field to the name of
+// its entity field, spelling id as the ID initialism like Go names do
+// (post_id becomes PostID). Forms and params keep ToCamelCase names.
+func ToFieldName(snakeCase string) string {
+	parts := strings.Split(snakeCase, "_")
+	for i, part := range parts {
+		if part == "id" {
+			parts[i] = "ID"
+		} else if part != "" {
+			parts[i] = ToTitle(part)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// findForm returns the form with the given name, looking first in the
+// resource and then at the app level. It returns nil if none is defined.
+func findForm(app *expr.AppExpr, resource *expr.ResourceExpr, name string) *expr.FormExpr {
+	if form, ok := resource.Forms[name]; ok {
+		return form
+	}
+	return app.Form(name)
+}
@@ -1,7 +1,11 @@
 package codegen
 
 import (
+	"bytes"
 	"fmt"
+	"html"
+	"slices"
+	"strings"
 
 	"github.com/gobijan/gluey/expr"
 )
@@ -16,14 +20,16 @@ func NewViewsGenerator(app *expr.AppExpr) *ViewsGenerator {
 	return &ViewsGenerator{app: app}
 }
 
-// GenerateLayout generates the main layout template.
+// GenerateLayout generates the main layout template. Views fill its
+// "title", "head" and "scripts" blocks by defining templates of the same
+// name.
 func (g *ViewsGenerator) GenerateLayout() (string, error) {
 	return `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>{{.Title}} - ` + g.app.Name + `</title>
+    <title>{{block "title" .}}{{.Title}}{{end}} - ` + g.app.Name + `</title>
     <style>
         * { margin: 0; padding: 0; box-sizing: border-box; }
         body { font-family: system-ui, -apple-system, sans-serif; line-height: 1.6; color: #333; }
@@ -53,6 +59,7 @@ func (g *ViewsGenerator) GenerateLayout() (string, error) {
         .actions { margin-top: 20px; }
         .actions a { margin-right: 10px; }
     </style>
+    {{block "head" .}}{{end}}
 </head>
 <body>
     <header>
@@ -72,11 +79,27 @@ func (g *ViewsGenerator) GenerateLayout() (string, error) {
         
         {{.Content}}
     </div>
+    {{block "scripts" .}}{{end}}
 </body>
 </html>
 `, nil
 }
 
+// LayoutNames returns the layouts of the app: the application layout, the
+// default layout and those declared with Layout.
+func (g *ViewsGenerator) LayoutNames() []string {
+	names := []string{"application"}
+	if g.app.DefaultLayout != "" && g.app.DefaultLayout != "application" {
+		names = append(names, g.app.DefaultLayout)
+	}
+	for _, layout := range g.app.Layouts {
+		if !slices.Contains(names, layout.Name) {
+			names = append(names, layout.Name)
+		}
+	}
+	return names
+}
+
 // GenerateErrors generates the errors partial.
 func (g *ViewsGenerator) GenerateErrors() string {
 	return `{{if .Errors}}
@@ -91,19 +114,25 @@ func (g *ViewsGenerator) GenerateErrors() string {
 {{end}}`
 }
 
-// GenerateFlash generates the flash messages partial.
+// GenerateFlash generates the flash messages partial. Its data is a
+// runtime.Flash, which lists the messages of each level.
 func (g *ViewsGenerator) GenerateFlash() string {
-	return `{{if .success}}
-<div class="flash success">{{.success}}</div>
-{{end}}
-{{if .error}}
-<div class="flash error">{{.error}}</div>
+	return `{{range $level, $messages := .}}
+{{range $messages}}
+<div class="flash {{$level}}">{{.}}</div>
 {{end}}
-{{if .warning}}
-<div class="flash warning">{{.warning}}</div>
-{{end}}
-{{if .info}}
-<div class="flash info">{{.info}}</div>
+{{end}}`
+}
+
+// GenerateForbidden generates the page of requests rejected by the CSRF
+// middleware, rendered by the Forbidden method of the base controller.
+func (g *ViewsGenerator) GenerateForbidden() string {
+	return `{{define "content"}}
+<div class="forbidden">
+    <h1>{{.Title}}</h1>
+    <p>The form has expired or was sent from another site.</p>
+    <p>Reload the page and submit it again.</p>
+</div>
 {{end}}`
 }
 
@@ -127,41 +156,55 @@ func (g *ViewsGenerator) GenerateResourceViews(resource *expr.ResourceExpr) (map
 		views["edit.html"] = g.generateEditView(resource)
 	}
 
+	// Custom GET actions render a view of their own
+	for _, action := range resource.CustomActions {
+		if config := resource.ActionConfigs[action]; config.Method == "GET" {
+			views[action+".html"] = g.generateCustomView(config)
+		}
+	}
+
 	return views, nil
 }
 
 // generateIndexView generates the index view for a resource.
 func (g *ViewsGenerator) generateIndexView(resource *expr.ResourceExpr) string {
 	singular := g.toSingular(resource.Name)
+	base, members := viewPath(resource), memberViewPath(resource)
+	id, headers, cells := "ID", "                <th>ID</th>\n                <th>Name</th>\n", "                <td>{{.Name}}</td>\n"
+
+	if model := resource.Model; model != nil {
+		id = ToFieldName(model.PrimaryKey().Name)
+		headers = fmt.Sprintf("                <th>%s</th>\n", fieldLabel(model.PrimaryKey().Name))
+		cells = ""
+		for _, field := range listFields(model) {
+			headers += fmt.Sprintf("                <th>%s</th>\n", fieldLabel(field.Name))
+			cells += fmt.Sprintf("                <td>{{.%s}}</td>\n", ToFieldName(field.Name))
+		}
+	}
 
 	return fmt.Sprintf(`{{define "content"}}
 <div class="%s-index">
     <h1>%s</h1>
     
     <div class="actions">
-        <a href="/%s/new" class="btn">New %s</a>
-    </div>
+        <a href="%s/new" class="btn">New %s</a>
+%s    </div>
     
     {{if .%s}}
     <table>
         <thead>
             <tr>
-                <th>ID</th>
-                <th>Name</th>
-                <th>Actions</th>
+%s                <th>Actions</th>
             </tr>
         </thead>
         <tbody>
             {{range .%s}}
             <tr>
-                <td>{{.ID}}</td>
-                <td>{{.Name}}</td>
-                <td>
-                    <a href="/%s/{{.ID}}">View</a>
-                    <a href="/%s/{{.ID}}/edit">Edit</a>
-                    <form method="post" action="/%s/{{.ID}}/delete" style="display:inline">
-                        <button type="submit" onclick="return confirm('Are you sure?')" class="btn danger">Delete</button>
-                    </form>
+                <td>{{.%s}}</td>
+%s                <td>
+                    <a href="%s/{{.%s}}">View</a>
+                    <a href="%s/{{.%s}}/edit">Edit</a>
+                    {{button_to "Delete" %s "DELETE" $.CSRFToken "class=\"btn danger\"" "onclick=\"return confirm('Are you sure?')\""}}
                 </td>
             </tr>
             {{end}}
@@ -174,13 +217,16 @@ func (g *ViewsGenerator) generateIndexView(resource *expr.ResourceExpr) string {
 {{end}}`,
 		resource.Name,
 		ToTitle(resource.Name),
-		resource.Name,
+		base,
 		ToTitle(singular),
+		customActionLinks(resource, false, id),
 		ToTitle(resource.Name),
+		headers,
 		ToTitle(resource.Name),
-		resource.Name,
-		resource.Name,
-		resource.Name,
+		id, cells,
+		members, id,
+		members, id,
+		memberPath(resource, id, ""),
 		resource.Name,
 	)
 }
@@ -188,6 +234,8 @@ func (g *ViewsGenerator) generateIndexView(resource *expr.ResourceExpr) string {
 // generateShowView generates the show view for a resource.
 func (g *ViewsGenerator) generateShowView(resource *expr.ResourceExpr) string {
 	singular := g.toSingular(resource.Name)
+	base, members := viewPath(resource), memberViewPath(resource)
+	id, fields := detailFields(resource)
 
 	return fmt.Sprintf(`{{define "content"}}
 <div class="%s-show">
@@ -195,22 +243,13 @@ func (g *ViewsGenerator) generateShowView(resource *expr.ResourceExpr) string {
     
     {{with .%s}}
     <dl>
-        <dt>ID:</dt>
-        <dd>{{.ID}}</dd>
-        
-        <dt>Name:</dt>
-        <dd>{{.Name}}</dd>
-        
-        <!-- Add more fields as needed -->
-    </dl>
+%s    </dl>
     
     <div class="actions">
-        <a href="/%s/{{.ID}}/edit" class="btn">Edit</a>
-        <a href="/%s">Back to List</a>
+        <a href="%s/{{.%s}}/edit" class="btn">Edit</a>
+%s        <a href="%s">Back to List</a>
         
-        <form method="post" action="/%s/{{.ID}}/delete" style="display:inline">
-            <button type="submit" onclick="return confirm('Are you sure?')" class="btn danger">Delete</button>
-        </form>
+        {{button_to "Delete" %s "DELETE" $.CSRFToken "class=\"btn danger\"" "onclick=\"return confirm('Are you sure?')\""}}
     </div>
     {{else}}
     <p>%s not found.</p>
@@ -220,86 +259,417 @@ func (g *ViewsGenerator) generateShowView(resource *expr.ResourceExpr) string {
 		singular,
 		ToTitle(singular),
 		ToTitle(singular),
-		resource.Name,
-		resource.Name,
-		resource.Name,
+		fields,
+		members, id,
+		customActionLinks(resource, true, id),
+		base,
+		memberPath(resource, id, ""),
 		ToTitle(singular),
 	)
 }
 
+// generateCustomView generates the view of a custom GET action. Member
+// actions show the record; actions with params get a form to change them.
+func (g *ViewsGenerator) generateCustomView(config *expr.ActionConfig) string {
+	resource := config.Resource
+	singular := g.toSingular(resource.Name)
+	back := fmt.Sprintf("        <a href=\"%s\">Back to List</a>\n", viewPath(resource))
+	content := fmt.Sprintf("    <!-- TODO: Render the %s action -->\n", config.Action)
+
+	if config.Member {
+		id, fields := detailFields(resource)
+		link := fmt.Sprintf("%s/{{.%s.%s}}", memberViewPath(resource), ToTitle(singular), id)
+		if resource.Singular {
+			link = memberViewPath(resource)
+		}
+		back = fmt.Sprintf("        <a href=\"%s\">Back to %s</a>\n", link, ToTitle(singular))
+		content = fmt.Sprintf("    {{with .%s}}\n    <dl>\n%s    </dl>\n    {{end}}\n    \n", ToTitle(singular), fields) + content
+	}
+
+	if len(config.Params) > 0 {
+		var inputs strings.Builder
+		for _, param := range config.Params {
+			inputs.WriteString(fmt.Sprintf("        <div class=\"form-group\">\n            <label for=\"%s\">%s</label>\n", param.Name, fieldLabel(param.Name)))
+			inputs.WriteString(fmt.Sprintf("            <input type=\"text\" id=\"%s\" name=\"%s\" value=\"{{.Params.%s}}\">\n        </div>\n",
+				param.Name, param.Name, ToCamelCase(param.Name)))
+		}
+		content += fmt.Sprintf("    \n    <form method=\"get\">\n%s        <button type=\"submit\" class=\"btn\">%s</button>\n    </form>\n",
+			inputs.String(), fieldLabel(config.Action))
+	}
+
+	return fmt.Sprintf(`{{define "content"}}
+<div class="%s-%s">
+    <h1>%s</h1>
+    
+%s    
+    <div class="actions">
+%s    </div>
+</div>
+{{end}}`,
+		resource.Name, strings.ReplaceAll(config.Action, "_", "-"),
+		customActionTitle(config),
+		content,
+		back,
+	)
+}
+
+// customActionTitle returns the title of the view of a custom action, such
+// as "Preview Post" or "Search Posts".
+func customActionTitle(config *expr.ActionConfig) string {
+	name := config.Resource.Name
+	if config.Member {
+		name = ToSingular(name)
+	}
+	return fieldLabel(config.Action) + " " + ToTitle(name)
+}
+
+// detailFields returns the id field of the records of resource and the
+// entries of the description list showing them.
+func detailFields(resource *expr.ResourceExpr) (string, string) {
+	model := resource.Model
+	if model == nil {
+		return "ID", `        <dt>ID:</dt>
+        <dd>{{.ID}}</dd>
+        
+        <dt>Name:</dt>
+        <dd>{{.Name}}</dd>
+        
+        <!-- Add more fields as needed -->
+`
+	}
+	entries := make([]string, 0, len(model.Fields))
+	for _, field := range model.Fields {
+		entries = append(entries, fmt.Sprintf("        <dt>%s:</dt>\n        <dd>{{.%s}}</dd>\n",
+			fieldLabel(field.Name), ToFieldName(field.Name)))
+	}
+	return ToFieldName(model.PrimaryKey().Name), strings.Join(entries, "        \n")
+}
+
+// customActionLinks returns the links of the show view to the custom member
+// actions of resource, or of the index view to its collection actions. GET
+// actions are links, the others buttons sending their method. id is the id
+// field of the record shown.
+func customActionLinks(resource *expr.ResourceExpr, member bool, id string) string {
+	var links strings.Builder
+	for _, action := range resource.CustomActions {
+		config := resource.ActionConfigs[action]
+		if config.Member != member {
+			continue
+		}
+		label := fieldLabel(action)
+		var href, path string
+		switch {
+		case !member:
+			href, path = viewPath(resource)+"/"+action, collectionPath(resource, action)
+		case resource.Singular:
+			href = memberViewPath(resource) + "/" + action
+			path = fmt.Sprintf("%q", href)
+		default:
+			href, path = fmt.Sprintf("%s/{{.%s}}/%s", memberViewPath(resource), id, action), memberPath(resource, id, "/"+action)
+		}
+		if config.Method == "GET" {
+			links.WriteString(fmt.Sprintf("        <a href=\"%s\" class=\"btn\">%s</a>\n", href, label))
+		} else {
+			links.WriteString(fmt.Sprintf("        {{button_to %q %s %q $.CSRFToken \"class=\\\"btn\\\"\"}}\n", label, path, config.Method))
+		}
+	}
+	return links.String()
+}
+
 // generateNewView generates the new view for a resource.
 func (g *ViewsGenerator) generateNewView(resource *expr.ResourceExpr) string {
 	singular := g.toSingular(resource.Name)
+	base := viewPath(resource)
 	formName := resource.NewFormName()
 
 	return fmt.Sprintf(`{{define "content"}}
 <div class="%s-new">
     <h1>New %s</h1>
     
-    {{template "shared/_errors.html" .}}
+    {{template "_errors.html" .}}
     
-    <form method="post" action="/%s">
-        <div class="form-group">
-            <label for="name">Name</label>
-            <input type="text" id="name" name="name" value="{{.Form.Name}}" required>
-        </div>
-        
-        <!-- Add more form fields based on your %s struct -->
-        
-        <div class="actions">
+    <form method="post" action="%s">
+        {{csrf_field .CSRFToken}}
+%s        <div class="actions">
             <button type="submit" class="btn">Create %s</button>
-            <a href="/%s">Cancel</a>
+            <a href="%s">Cancel</a>
         </div>
     </form>
 </div>
 {{end}}`,
 		singular,
 		ToTitle(singular),
-		resource.Name,
-		formName,
+		base,
+		g.generateFormFields(findForm(g.app, resource, formName), formName, ".Form.Name"),
 		ToTitle(singular),
-		resource.Name,
+		base,
 	)
 }
 
 // generateEditView generates the edit view for a resource.
 func (g *ViewsGenerator) generateEditView(resource *expr.ResourceExpr) string {
 	singular := g.toSingular(resource.Name)
+	members := memberViewPath(resource)
 	formName := resource.EditFormName()
+	id := "ID"
+	if resource.Model != nil {
+		id = ToFieldName(resource.Model.PrimaryKey().Name)
+	}
 
 	return fmt.Sprintf(`{{define "content"}}
 <div class="%s-edit">
     <h1>Edit %s</h1>
     
-    {{template "shared/_errors.html" .}}
+    {{template "_errors.html" .}}
     
-    <form method="post" action="/%s/{{.%s.ID}}">
-        <div class="form-group">
-            <label for="name">Name</label>
-            <input type="text" id="name" name="name" value="{{.%s.Name}}">
-        </div>
-        
-        <!-- Add more form fields based on your %s struct -->
-        
-        <div class="actions">
+    <form method="post" action="%s/{{.%s.%s}}">
+        <input type="hidden" name="_method" value="PATCH">
+        {{csrf_field .CSRFToken}}
+%s        <div class="actions">
             <button type="submit" class="btn">Update %s</button>
-            <a href="/%s/{{.%s.ID}}">Cancel</a>
+            <a href="%s/{{.%s.%s}}">Cancel</a>
         </div>
     </form>
 </div>
 {{end}}`,
 		singular,
 		ToTitle(singular),
-		resource.Name,
-		ToTitle(singular),
-		ToTitle(singular),
-		formName,
-		ToTitle(singular),
-		resource.Name,
+		members,
+		ToTitle(singular), id,
+		g.generateFormFields(findForm(g.app, resource, formName), formName, "."+ToTitle(singular)+".Name"),
 		ToTitle(singular),
+		members,
+		ToTitle(singular), id,
 	)
 }
 
+// viewPath returns the base path of the links of the views of resource. The
+// paths of nested resources hold the ids of their ancestors, so their views
+// link to the Path passed by the controller.
+func viewPath(resource *expr.ResourceExpr) string {
+	if resource.Parent != nil {
+		return "{{$.Path}}"
+	}
+	return "/" + resource.Name
+}
+
+// memberViewPath returns the base path of the links of the views of
+// resource to its members: viewPath, except for shallow resources whose
+// member routes are not nested.
+func memberViewPath(resource *expr.ResourceExpr) string {
+	if resource.Shallow {
+		return resource.MemberPath()
+	}
+	return viewPath(resource)
+}
+
+// memberPath returns the template expression of the path of the record
+// whose id is field of the dot, followed by suffix.
+func memberPath(resource *expr.ResourceExpr, field, suffix string) string {
+	if resource.Parent != nil && !resource.Shallow {
+		return fmt.Sprintf("(printf \"%%v/%%v%s\" $.Path .%s)", suffix, field)
+	}
+	return fmt.Sprintf("(printf \"%s/%%v%s\" .%s)", memberViewPath(resource), suffix, field)
+}
+
+// collectionPath returns the template expression of the path of the
+// collection action of resource.
+func collectionPath(resource *expr.ResourceExpr, action string) string {
+	if resource.Parent != nil {
+		return fmt.Sprintf("(printf \"%%v/%s\" $.Path)", action)
+	}
+	return fmt.Sprintf("%q", "/"+resource.Name+"/"+action)
+}
+
+// generateFormFields generates the inputs for a form's attributes, bound to
+// .Form. Without a form definition it falls back to a single name input
+// bound to placeholder.
+func (g *ViewsGenerator) generateFormFields(form *expr.FormExpr, formName, placeholder string) string {
+	if form == nil {
+		return fmt.Sprintf(`        <div class="form-group">
+            <label for="name">Name</label>
+            <input type="text" id="name" name="name" value="{{%s}}">
+        </div>
+        
+        <!-- Add more form fields based on your %s struct -->
+        
+`, placeholder, formName)
+	}
+
+	var buf bytes.Buffer
+	for _, attr := range form.Attributes {
+		buf.WriteString(g.generateFormField(attr))
+	}
+	return buf.String()
+}
+
+// generateFormField generates the input for a single form attribute.
+// Enum options are pre-selected from the form value, falling back to the
+// attribute default.
+func (g *ViewsGenerator) generateFormField(attr *expr.AttributeExpr) string {
+	name := attr.Name
+	label := fieldLabel(name)
+	value := ".Form." + ToCamelCase(name)
+
+	var required string
+	if attr.IsRequired() {
+		required = " required"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("        <div class=\"form-group\">\n")
+
+	dataType := attr.Type
+	if dataType == nil {
+		dataType = expr.String
+	}
+	enum, isEnum := attr.Enum()
+
+	switch {
+	case isEnum && (dataType.Kind() == expr.StringKind || isNumeric(dataType)):
+		selected := value
+		if attr.DefaultValue != nil {
+			selected = fmt.Sprintf("(or %s %q)", value, fmt.Sprint(attr.DefaultValue))
+		}
+		// Numbers are formatted to compare them with the option values
+		if isNumeric(dataType) {
+			selected = fmt.Sprintf("(print %s)", value)
+			if attr.DefaultValue != nil {
+				selected = fmt.Sprintf("(print (or %s %v))", value, attr.DefaultValue)
+			}
+		}
+		buf.WriteString(fmt.Sprintf("            <label for=\"%s\">%s</label>\n", name, label))
+		buf.WriteString(fmt.Sprintf("            <select id=\"%s\" name=\"%s\"%s>\n", name, name, required))
+		if attr.DefaultValue == nil && !attr.IsRequired() {
+			buf.WriteString("                <option value=\"\"></option>\n")
+		}
+		for _, option := range enum {
+			buf.WriteString(fmt.Sprintf("                <option value=\"%s\"{{if eq %s %q}} selected{{end}}>%s</option>\n",
+				html.EscapeString(option), selected, option, html.EscapeString(ToTitle(option))))
+		}
+		buf.WriteString("            </select>\n")
+	case dataType.Kind() == expr.BooleanKind:
+		// The hidden input submits false when the checkbox is unchecked
+		buf.WriteString(fmt.Sprintf("            <input type=\"hidden\" name=\"%s\" value=\"false\">\n", name))
+		buf.WriteString(fmt.Sprintf("            <label><input type=\"checkbox\" id=\"%s\" name=\"%s\" value=\"true\"{{if %s}} checked{{end}}> %s</label>\n",
+			name, name, value, label))
+	case dataType.Kind() == expr.ArrayKind:
+		// One input per stored element plus an empty one for a new element,
+		// submitted as repeated keys; blank inputs are ignored when decoding
+		elemType := g.inputType(&expr.AttributeExpr{Name: name, Type: dataType.(*expr.ArrayType).ElemType})
+		buf.WriteString(fmt.Sprintf("            <label for=\"%s\">%s</label>\n", name, label))
+		buf.WriteString(fmt.Sprintf("            {{range %s}}<input type=\"%s\" name=\"%s\" value=\"{{.}}\">\n            {{end}}<input type=\"%s\" id=\"%s\" name=\"%s\" value=\"\">\n",
+			value, elemType, name, elemType, name, name))
+	case dataType.Kind() == expr.MapKind:
+		// Stored entries are submitted as bracket keys so they survive an update
+		buf.WriteString(fmt.Sprintf("            <label>%s</label>\n", label))
+		buf.WriteString(fmt.Sprintf("            {{range $key, $value := %s}}<label>{{$key}} <input type=\"text\" name=\"%s[{{$key}}]\" value=\"{{$value}}\"></label>\n            {{end}}\n",
+			value, name))
+	case g.isTextArea(attr):
+		buf.WriteString(fmt.Sprintf("            <label for=\"%s\">%s</label>\n", name, label))
+		buf.WriteString(fmt.Sprintf("            <textarea id=\"%s\" name=\"%s\"%s>{{%s}}</textarea>\n",
+			name, name, required, value))
+	default:
+		buf.WriteString(fmt.Sprintf("            <label for=\"%s\">%s</label>\n", name, label))
+		buf.WriteString(fmt.Sprintf("            <input type=\"%s\" id=\"%s\" name=\"%s\" value=\"{{%s}}\"%s%s>\n",
+			g.inputType(attr), name, name, value, g.inputBounds(attr), required))
+	}
+
+	buf.WriteString("        </div>\n\n")
+	return buf.String()
+}
+
+// inputType returns the HTML input type for an attribute.
+func (g *ViewsGenerator) inputType(attr *expr.AttributeExpr) string {
+	if format, ok := attr.Format(); ok {
+		switch format {
+		case expr.FormatEmail:
+			return "email"
+		case expr.FormatURL:
+			return "url"
+		case expr.FormatDate:
+			return "date"
+		case expr.FormatDateTime:
+			return "datetime-local"
+		}
+	}
+	if attr.Type != nil && (attr.Type.Kind() == expr.IntKind || attr.Type.Kind() == expr.FloatKind) {
+		return "number"
+	}
+	if strings.Contains(attr.Name, "password") {
+		return "password"
+	}
+	return "text"
+}
+
+// inputBounds returns the HTML length or range attributes for an input.
+func (g *ViewsGenerator) inputBounds(attr *expr.AttributeExpr) string {
+	var bounds string
+	if attr.Type != nil && (attr.Type.Kind() == expr.IntKind || attr.Type.Kind() == expr.FloatKind) {
+		if attr.Type.Kind() == expr.FloatKind {
+			bounds += ` step="any"`
+		}
+		if min, ok := attr.Min(); ok {
+			bounds += fmt.Sprintf(` min="%d"`, min)
+		}
+		if max, ok := attr.Max(); ok {
+			bounds += fmt.Sprintf(` max="%d"`, max)
+		}
+		return bounds
+	}
+	if max, ok := attr.MaxLength(); ok {
+		bounds += fmt.Sprintf(` maxlength="%d"`, max)
+	}
+	return bounds
+}
+
+// isTextArea returns true if a string attribute holds long text.
+func (g *ViewsGenerator) isTextArea(attr *expr.AttributeExpr) bool {
+	if attr.Type != nil && attr.Type.Kind() != expr.StringKind {
+		return false
+	}
+	if max, ok := attr.MaxLength(); ok {
+		return max > 255
+	}
+	switch attr.Name {
+	case "body", "content", "description", "message", "bio", "notes":
+		return true
+	}
+	return false
+}
+
+// listFields returns the model fields shown as index columns: up to four
+// scalar fields besides the primary key.
+func listFields(model *expr.ModelExpr) []*expr.AttributeExpr {
+	var fields []*expr.AttributeExpr
+	for _, field := range model.Fields {
+		if field.IsPrimaryKey() || field.Type == nil {
+			continue
+		}
+		if kind := field.Type.Kind(); kind == expr.ArrayKind || kind == expr.MapKind || kind == expr.ObjectKind {
+			continue
+		}
+		fields = append(fields, field)
+		if len(fields) == 4 {
+			break
+		}
+	}
+	return fields
+}
+
+// fieldLabel returns the human-readable label for a field name.
+func fieldLabel(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if word == "id" {
+			words[i] = "ID"
+		} else {
+			words[i] = ToTitle(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
 // toSingular converts a plural resource name to singular.
 func (g *ViewsGenerator) toSingular(plural string) string {
 	return ToSingular(plural)
@@ -1,8 +1,6 @@
 package dsl
 
 import (
-	"fmt"
-
 	"github.com/gobijan/gluey/eval"
 	"github.com/gobijan/gluey/expr"
 )
@@ -54,27 +52,71 @@ func Description(desc string) {
 	case *expr.AttributeExpr:
 		// Handle attribute description
 		e.Description = desc
+	case *expr.ParamExpr:
+		e.Description = desc
 	default:
 		eval.IncompatibleDSL()
 	}
 }
 
-// Use adds middleware to the application stack.
+// Use adds middleware to the application stack, or to the handlers of a
+// resource, action or page. The generated MountRoutes wraps the routes with
+// the application middleware in declaration order, the first one being
+// outermost. Resource middleware wrap every action of the resource, around
+// the middleware of the action itself; both run after the Auth checks of
+// the route. Built-in middleware are "Logger", "Recover", "RequestID",
+// "Gzip", "SecurityHeaders", "RealIP", "CSRF" and "MethodOverride"; other
+// names must be declared with CustomMiddleware. Unknown names are reported
+// when the code is generated.
 //
-// Use must appear in a WebApp expression.
+// Use must appear in a WebApp, Resource, action configuration or Page
+// expression.
 //
 // Example:
 //
 //	WebApp("myapp", func() {
 //	    Use("RequestID", "Logger", "Recover")
+//
+//	    Resource("sessions", func() {
+//	        Create(func() {
+//	            Use("RateLimiter")
+//	        })
+//	    })
 //	})
 func Use(middleware ...string) {
+	switch e := eval.Current().(type) {
+	case *expr.AppExpr:
+		e.Middleware = append(e.Middleware, middleware...)
+	case *expr.ResourceExpr:
+		e.Middleware = append(e.Middleware, middleware...)
+	case *expr.ActionConfig:
+		e.Middleware = append(e.Middleware, middleware...)
+	case *expr.PageExpr:
+		e.Middleware = append(e.Middleware, middleware...)
+	default:
+		eval.IncompatibleDSL()
+	}
+}
+
+// CustomMiddleware declares middleware supplied by the application through
+// the Middleware map of the generated Controllers, so that Use may name
+// them.
+//
+// CustomMiddleware must appear in a WebApp expression.
+//
+// Example:
+//
+//	WebApp("myapp", func() {
+//	    CustomMiddleware("RateLimiter")
+//	    Use("Logger", "RateLimiter")
+//	})
+func CustomMiddleware(names ...string) {
 	app, ok := eval.Current().(*expr.AppExpr)
 	if !ok {
 		eval.IncompatibleDSL()
 		return
 	}
-	app.Middleware = append(app.Middleware, middleware...)
+	app.CustomMiddleware = append(app.CustomMiddleware, names...)
 }
 
 // Sessions configures session storage.
@@ -99,14 +141,17 @@ func Sessions(fn func()) {
 	}
 }
 
-// Store sets the session store type.
+// Store sets the session store type: "cookie" keeps sessions encrypted in
+// the session cookie, "memory" in the server process and "filesystem" in
+// files under tmp/sessions. The generated router loads and saves the
+// session of every request.
 //
 // Store must appear in a Sessions expression.
 //
 // Example:
 //
 //	Sessions(func() {
-//	    Store("cookie")  // or "redis", "memory"
+//	    Store("cookie")  // or "memory", "filesystem"
 //	})
 func Store(store string) {
 	app, ok := eval.Current().(*expr.AppExpr)
@@ -181,15 +226,22 @@ func Layouts(fn func()) {
 	}
 }
 
-// Default sets the default layout.
+// Default sets the default layout or the default value of an attribute or
+// query parameter.
 //
-// Default must appear in a Layouts expression.
+// Default must appear in a Layouts, Attribute or Param expression. Values
+// are type-checked against the attribute or parameter type at validation.
 //
 // Example:
 //
 //	Layouts(func() {
 //	    Default("application")
 //	})
+//
+//	Attribute("status", String, func() {
+//	    Enum("draft", "published")
+//	    Default("draft")
+//	})
 func Default(value interface{}) {
 	switch e := eval.Current().(type) {
 	case *expr.AppExpr:
@@ -198,42 +250,48 @@ func Default(value interface{}) {
 			e.DefaultLayout = name
 		}
 	case *expr.AttributeExpr:
-		// Default value for attribute - store in description for now
-		// TODO: Add proper Default field to AttributeExpr
-		if e.Description != "" {
-			e.Description += " "
-		}
-		e.Description += fmt.Sprintf("(default: %v)", value)
+		e.DefaultValue = value
+	case *expr.ParamExpr:
+		e.Default = value
 	default:
 		eval.IncompatibleDSL()
 	}
 }
 
-// Layout defines a layout.
+// Layout defines a layout, or picks the layout of a resource or page.
+// Other resources and pages use the default layout.
 //
-// Layout must appear in a Layouts or WebApp expression.
+// Layout must appear in a Layouts, WebApp, Resource or Page expression.
+// Resources and pages may only pick declared layouts.
 //
 // Example:
 //
 //	Layouts(func() {
 //	    Layout("admin")
 //	})
+//
+//	Resource("users", func() {
+//	    Layout("admin")
+//	})
 func Layout(name string, fn ...func()) {
-	app, ok := eval.Current().(*expr.AppExpr)
-	if !ok {
-		eval.IncompatibleDSL()
-		return
-	}
+	switch e := eval.Current().(type) {
+	case *expr.AppExpr:
+		layout := &expr.LayoutExpr{
+			Name:     name,
+			Template: "layouts/" + name + ".html",
+		}
 
-	layout := &expr.LayoutExpr{
-		Name:     name,
-		Template: "layouts/" + name + ".html",
-	}
+		if len(fn) > 0 {
+			layout.DSLFunc = fn[0]
+			eval.Execute(fn[0], layout)
+		}
 
-	if len(fn) > 0 {
-		layout.DSLFunc = fn[0]
-		eval.Execute(fn[0], layout)
+		e.Layouts = append(e.Layouts, layout)
+	case *expr.ResourceExpr:
+		e.Layout = name
+	case *expr.PageExpr:
+		e.Layout = name
+	default:
+		eval.IncompatibleDSL()
 	}
-
-	app.Layouts = append(app.Layouts, layout)
 }
@@ -1,6 +1,8 @@
 package dsl_test
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/gobijan/gluey/dsl"
@@ -263,7 +265,10 @@ func TestNestedResource(t *testing.T) {
 	dsl.WebApp("testapp", func() {
 		dsl.Resource("posts", func() {
 			dsl.Actions("index", "show")
-			dsl.Resource("comments") // Nested resource
+			dsl.Resource("comments", func() { // Nested resource
+				dsl.Actions("index", "create")
+				dsl.Resource("likes")
+			})
 		})
 	})
 
@@ -274,9 +279,9 @@ func TestNestedResource(t *testing.T) {
 
 	app := expr.Root
 
-	// Should have 2 resources (posts and comments)
-	if len(app.Resources) != 2 {
-		t.Errorf("Expected 2 resources, got %d", len(app.Resources))
+	// Should have 3 resources (posts, comments and likes)
+	if len(app.Resources) != 3 {
+		t.Errorf("Expected 3 resources, got %d", len(app.Resources))
 	}
 
 	posts := app.Resource("posts")
@@ -296,4 +301,571 @@ func TestNestedResource(t *testing.T) {
 	if comments.Parent != posts {
 		t.Error("comments should have posts as parent")
 	}
+
+	// The DSL of nested resources runs too
+	if len(comments.Actions) != 2 {
+		t.Errorf("Expected 2 actions for comments, got %v", comments.Actions)
+	}
+	likes := app.Resource("likes")
+	if likes == nil || likes.Parent != comments {
+		t.Fatal("likes should be nested in comments")
+	}
+	if got := likes.Path(); got != "/posts/{post_id}/comments/{comment_id}/likes" {
+		t.Errorf("likes path = %q", got)
+	}
+}
+
+func TestBelongsTo(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("comments", func() {
+			dsl.BelongsTo("post")
+			dsl.Actions("index", "create", "destroy")
+		})
+		dsl.Resource("posts")
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	comments := expr.Root.Resource("comments")
+	if comments.BelongsTo != "post" || comments.Parent != expr.Root.Resource("posts") || !comments.Shallow {
+		t.Fatalf("comments should belong to posts shallowly, got %+v", comments)
+	}
+	if got := comments.Path(); got != "/posts/{post_id}/comments" {
+		t.Errorf("Path() = %q", got)
+	}
+}
+
+func TestDesignTimeValidationErrors(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Type("RegisterForm", func() {
+			dsl.Attribute("password", dsl.String, func() {
+				dsl.Pattern("^(?=.*[a-z]).*$")
+			})
+		})
+		dsl.Resource("products", func() {
+			dsl.Form("ProductForm", func() {
+				dsl.Attribute("quantity", dsl.Int, dsl.Min(10), dsl.Max(1))
+			})
+		})
+	})
+
+	err := eval.RunDSL()
+	if err == nil {
+		t.Fatal("RunDSL() should fail for invalid validations")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`form "RegisterForm": attribute "password": invalid pattern`,
+		`form "ProductForm": attribute "quantity": min (10) is greater than max (1)`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("RunDSL() error should contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Type("PostForm", func() {
+			dsl.Attribute("status", dsl.String, "Post status", func() {
+				dsl.Enum("draft", "published")
+				dsl.Default("draft")
+			})
+		})
+		dsl.Resource("posts", func() {
+			dsl.Index(func() {
+				dsl.Params(func() {
+					dsl.Param("page", dsl.Int, func() {
+						dsl.Default(1)
+					})
+					dsl.Param("per_page", dsl.Int, dsl.Max(100))
+				})
+			})
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	attr := expr.Root.Form("PostForm").Attributes[0]
+	if attr.DefaultValue != "draft" {
+		t.Errorf("DefaultValue = %v, want draft", attr.DefaultValue)
+	}
+	if attr.Description != "Post status" {
+		t.Errorf("Default should not change the description, got %q", attr.Description)
+	}
+
+	params := expr.Root.Resources[0].ActionConfigs["index"].Params
+	if len(params) != 2 {
+		t.Fatalf("expected 2 params, got %d", len(params))
+	}
+	if params[0].Default != 1 {
+		t.Errorf("page default = %v, want 1", params[0].Default)
+	}
+	if params[1].Max != 100 {
+		t.Errorf("per_page max = %v, want 100", params[1].Max)
+	}
+}
+
+func TestDefaultTypeMismatch(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Type("TodoForm", func() {
+			dsl.Attribute("completed", dsl.Boolean, func() {
+				dsl.Default("no")
+			})
+		})
+		dsl.Resource("todos", func() {
+			dsl.Index(func() {
+				dsl.Params(func() {
+					dsl.Param("page", dsl.Int, func() {
+						dsl.Default("first")
+					})
+				})
+			})
+		})
+	})
+
+	err := eval.RunDSL()
+	if err == nil {
+		t.Fatal("RunDSL() should fail for defaults of the wrong type")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`attribute "completed": default value: "no" is not a valid boolean`,
+		`param "page": default value: "first" is not a valid int`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("RunDSL() error should contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestCustomValidationDSL(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Type("RegisterForm", func() {
+			dsl.Attribute("email", dsl.String, dsl.Validation("unique"))
+			dsl.Attribute("password", dsl.String)
+			dsl.Attribute("password_confirmation", dsl.String, func() {
+				dsl.Validation("matches", "password")
+			})
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	form := expr.Root.Form("RegisterForm")
+	email := form.Attribute("email").CustomValidations()
+	if len(email) != 1 || email[0].Rule != "unique" {
+		t.Errorf("email custom validations = %v", email)
+	}
+	confirm := form.Attribute("password_confirmation").CustomValidations()
+	if len(confirm) != 1 || confirm[0].Rule != "matches" || confirm[0].Args[0] != "password" {
+		t.Errorf("password_confirmation custom validations = %v", confirm)
+	}
+}
+
+func TestModel(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Model("Post", func() {
+				dsl.Field("title", dsl.String, dsl.Required(), dsl.MaxLength(200))
+				dsl.Field("views", dsl.Int, "Number of views")
+			})
+		})
+		dsl.Resource("tags", func() {
+			dsl.Model("Tag", func() {
+				dsl.Field("slug", dsl.String, dsl.PrimaryKey())
+				dsl.Field("name", dsl.String)
+			})
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	post := expr.Root.Resource("posts").Model
+	if post == nil || post.Name != "Post" {
+		t.Fatalf("posts model = %+v, want Post", post)
+	}
+	if pk := post.PrimaryKey(); pk == nil || pk.Name != "id" || pk.Type != expr.Int64 {
+		t.Errorf("Post primary key = %+v, want id Int64", pk)
+	}
+	if title := post.Field("title"); title == nil || len(title.Validations) != 2 {
+		t.Errorf("title field = %+v, want 2 validations", title)
+	}
+	if views := post.Field("views"); views == nil || views.Description != "Number of views" {
+		t.Errorf("views field = %+v, want description", views)
+	}
+
+	tag := expr.Root.Resource("tags").Model
+	if pk := tag.PrimaryKey(); pk == nil || pk.Name != "slug" {
+		t.Errorf("Tag primary key = %+v, want slug", pk)
+	}
+	if expr.Root.Model("Tag") != tag {
+		t.Error("Model() should find models by name")
+	}
+}
+
+func TestModelIncompatibleDSL(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Model("Post", func() {})
+	})
+
+	if err := eval.RunDSL(); err == nil {
+		t.Error("RunDSL() should fail for Model outside a Resource")
+	}
+}
+
+func TestIndexOptions(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Model("Post", func() {
+				dsl.Field("title", dsl.String)
+				dsl.Field("status", dsl.String)
+			})
+			dsl.Index(func() {
+				dsl.Paginate(20)
+				dsl.Searchable("title")
+				dsl.Filterable("status")
+			})
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	resource := expr.Root.Resource("posts")
+	if resource.Pagination["index"] != 20 {
+		t.Errorf("Pagination = %v, want index: 20", resource.Pagination)
+	}
+	if fields := resource.SearchableFields["index"]; len(fields) != 1 || fields[0] != "title" {
+		t.Errorf("SearchableFields = %v", resource.SearchableFields)
+	}
+	if fields := resource.FilterableFields["index"]; len(fields) != 1 || fields[0] != "status" {
+		t.Errorf("FilterableFields = %v", resource.FilterableFields)
+	}
+
+	config := resource.ActionConfigs["index"]
+	for _, name := range []string{expr.ParamSearch, "status", expr.ParamPage, expr.ParamPerPage} {
+		if config.Param(name) == nil {
+			t.Errorf("index params should include %q", name)
+		}
+	}
+	if perPage := config.Param(expr.ParamPerPage); perPage.Default != 20 || perPage.Max != 20 {
+		t.Errorf("per_page param = %+v, want default and max 20", perPage)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Auth("authenticated").Except("index", "show")
+			dsl.Auth("admin").Only("destroy")
+		})
+		dsl.Page("dashboard", func() {
+			dsl.Route("GET", "/dashboard")
+			dsl.Auth("authenticated")
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	posts := expr.Root.Resource("posts")
+	if reqs := posts.AuthRequirements["index"]; len(reqs) != 0 {
+		t.Errorf("index requirements = %v, want none", reqs)
+	}
+	if reqs := posts.AuthRequirements["destroy"]; len(reqs) != 2 || reqs[0] != "authenticated" || reqs[1] != "admin" {
+		t.Errorf("destroy requirements = %v, want [authenticated admin]", reqs)
+	}
+	if reqs := expr.Root.Pages[0].AuthRequirements; len(reqs) != 1 || reqs[0] != "authenticated" {
+		t.Errorf("page requirements = %v, want [authenticated]", reqs)
+	}
+}
+
+func TestAuthAllActions(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Auth("user")
+			dsl.Auth("admin").Only("destroy")
+			dsl.Member("publish", "POST")
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	// A bare Auth covers every action, custom ones declared after it too
+	posts := expr.Root.Resource("posts")
+	for action, want := range map[string][]string{
+		"index":   {"user"},
+		"update":  {"user"},
+		"destroy": {"user", "admin"},
+		"publish": {"user"},
+	} {
+		if reqs := posts.AuthRequirements[action]; !slices.Equal(reqs, want) {
+			t.Errorf("%s requirements = %v, want %v", action, reqs, want)
+		}
+	}
+}
+
+func TestCustomActions(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Auth("authenticated").Except("index", "show", "search")
+			dsl.Form("PublishForm", func() {
+				dsl.Attribute("note", dsl.String)
+			})
+			dsl.Member("publish", "POST", func() {
+				dsl.UseForm("PublishForm")
+				dsl.Use("Logger")
+			})
+			dsl.Collection("search", "GET", func() {
+				dsl.Params(func() {
+					dsl.Param("q", dsl.String)
+				})
+			})
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	posts := expr.Root.Resource("posts")
+	if len(posts.CustomActions) != 2 || posts.CustomActions[0] != "publish" || posts.CustomActions[1] != "search" {
+		t.Fatalf("CustomActions = %v, want [publish search]", posts.CustomActions)
+	}
+	publish := posts.CustomAction("publish")
+	if publish.Method != "POST" || !publish.Member || publish.FormName != "PublishForm" || len(publish.Middleware) != 1 {
+		t.Errorf("publish config = %+v", publish)
+	}
+	search := posts.CustomAction("search")
+	if search.Method != "GET" || search.Member || search.Param("q") == nil {
+		t.Errorf("search config = %+v", search)
+	}
+	// Except covers custom actions declared after it
+	if reqs := posts.AuthRequirements["publish"]; len(reqs) != 1 || reqs[0] != "authenticated" {
+		t.Errorf("publish requirements = %v, want [authenticated]", reqs)
+	}
+	if reqs := posts.AuthRequirements["search"]; len(reqs) != 0 {
+		t.Errorf("search requirements = %v, want none", reqs)
+	}
+}
+
+func TestAuthPageActions(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Page("dashboard", func() {
+			dsl.Auth("admin").Only("show")
+		})
+	})
+
+	if err := eval.RunDSL(); err == nil {
+		t.Error("RunDSL() should fail for Auth().Only in a Page")
+	}
+}
+
+func TestUse(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.CustomMiddleware("RateLimiter")
+		dsl.Use("Logger", "Recover")
+		dsl.Use("RateLimiter")
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	if got := strings.Join(expr.Root.Middleware, ","); got != "Logger,Recover,RateLimiter" {
+		t.Errorf("Middleware = %s, want Logger,Recover,RateLimiter", got)
+	}
+	if got := expr.Root.CustomMiddleware; len(got) != 1 || got[0] != "RateLimiter" {
+		t.Errorf("CustomMiddleware = %v, want [RateLimiter]", got)
+	}
+}
+
+func TestUseScopes(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.CustomMiddleware("RateLimiter", "Cache", "AuditLog")
+
+		dsl.Resource("sessions", func() {
+			dsl.Actions("new", "create")
+			dsl.Create(func() {
+				dsl.Use("RateLimiter")
+			})
+		})
+
+		dsl.Resource("users", func() {
+			dsl.Use("Gzip")
+			dsl.Destroy(func() {
+				dsl.Use("AuditLog")
+			})
+		})
+
+		dsl.Page("about", func() {
+			dsl.Use("Cache")
+		})
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	sessions := expr.Root.Resource("sessions")
+	if got := sessions.ActionConfigs["create"].Middleware; len(got) != 1 || got[0] != "RateLimiter" {
+		t.Errorf("sessions#create middleware = %v, want [RateLimiter]", got)
+	}
+	if len(sessions.Middleware) != 0 {
+		t.Errorf("sessions middleware = %v, want none", sessions.Middleware)
+	}
+
+	users := expr.Root.Resource("users")
+	if got := users.Middleware; len(got) != 1 || got[0] != "Gzip" {
+		t.Errorf("users middleware = %v, want [Gzip]", got)
+	}
+	if got := users.ActionConfigs["destroy"].Middleware; len(got) != 1 || got[0] != "AuditLog" {
+		t.Errorf("users#destroy middleware = %v, want [AuditLog]", got)
+	}
+
+	if got := expr.Root.Pages[0].Middleware; len(got) != 1 || got[0] != "Cache" {
+		t.Errorf("about middleware = %v, want [Cache]", got)
+	}
+	if len(expr.Root.Middleware) != 0 {
+		t.Errorf("app middleware = %v, want none", expr.Root.Middleware)
+	}
+}
+
+func TestSkipCSRF(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Page("stripe_webhook", func() {
+			dsl.Route("POST", "/webhooks/stripe")
+			dsl.SkipCSRF()
+		})
+		dsl.Page("contact", "/contact")
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	if !expr.Root.Pages[0].SkipCSRF {
+		t.Error("stripe_webhook should skip CSRF checks")
+	}
+	if expr.Root.Pages[1].SkipCSRF {
+		t.Error("contact should not skip CSRF checks")
+	}
+}
+
+func TestLayoutScopes(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Layouts(func() {
+			dsl.Layout("admin")
+		})
+		dsl.Resource("users", func() {
+			dsl.Layout("admin")
+		})
+		dsl.Page("dashboard", func() {
+			dsl.Route("GET", "/dashboard")
+			dsl.Layout("admin")
+		})
+		dsl.Resource("posts")
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	app := expr.Root
+	if len(app.Layouts) != 1 || app.Layouts[0].Name != "admin" {
+		t.Errorf("Layouts = %v, want only admin", app.Layouts)
+	}
+	if app.Resources[0].Layout != "admin" || app.Pages[0].Layout != "admin" {
+		t.Errorf("users and dashboard layouts = %q, %q, want admin", app.Resources[0].Layout, app.Pages[0].Layout)
+	}
+	if app.Resources[1].Layout != "" {
+		t.Errorf("posts layout = %q, want the default", app.Resources[1].Layout)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	expr.Reset()
+	eval.Context.Reset()
+
+	dsl.WebApp("testapp", func() {
+		dsl.Resource("posts", func() {
+			dsl.Formats("html", "json")
+		})
+		dsl.Resource("tags")
+	})
+
+	if err := eval.RunDSL(); err != nil {
+		t.Fatalf("RunDSL() failed: %v", err)
+	}
+
+	app := expr.Root
+	if got := app.Resources[0].Formats; len(got) != 2 || got[0] != "html" || got[1] != "json" {
+		t.Errorf("posts formats = %v, want html and json", got)
+	}
+	if got := app.Resources[1].Formats; len(got) != 1 || got[0] != "html" {
+		t.Errorf("tags formats = %v, want html only", got)
+	}
 }
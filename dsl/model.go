@@ -0,0 +1,119 @@
+package dsl
+
+import (
+	"fmt"
+
+	"github.com/gobijan/gluey/eval"
+	"github.com/gobijan/gluey/expr"
+)
+
+// Model defines the record behind a resource.
+//
+// Model must appear in a Resource expression. It generates an entity struct,
+// a repository interface and mapping helpers from the resource's forms. A
+// model without a PrimaryKey field uses its "id" field, or gets an Int64 one.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    Model("Post", func() {
+//	        Field("id", Int64, PrimaryKey())
+//	        Field("title", String, Required(), MaxLength(200))
+//	        Field("status", String, Enum("draft", "published"))
+//	    })
+//	})
+func Model(name string, fn func()) {
+	resource, ok := eval.Current().(*expr.ResourceExpr)
+	if !ok {
+		eval.IncompatibleDSL()
+		return
+	}
+	if resource.Model != nil {
+		eval.ReportError(fmt.Errorf("resource %q already has model %q", resource.Name, resource.Model.Name))
+		return
+	}
+
+	model := &expr.ModelExpr{
+		Name:     name,
+		DSLFunc:  fn,
+		Resource: resource,
+	}
+
+	if fn != nil {
+		eval.Execute(fn, model)
+	}
+
+	resource.Model = model
+}
+
+// Field defines a field of a model.
+//
+// Field must appear in a Model expression. It accepts the same arguments as
+// Attribute: a type, validations, a description and a DSL function.
+//
+// Example:
+//
+//	Model("Post", func() {
+//	    Field("title", String, Required())
+//	    Field("published_at", String, Format(FormatDateTime))
+//	})
+func Field(name string, args ...interface{}) {
+	model, ok := eval.Current().(*expr.ModelExpr)
+	if !ok {
+		eval.IncompatibleDSL()
+		return
+	}
+
+	field := &expr.AttributeExpr{
+		Name: name,
+	}
+
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case expr.DataType:
+			field.Type = v
+		case expr.Validation:
+			field.Validations = append(field.Validations, v)
+		case string:
+			field.Description = v
+		case func():
+			eval.Execute(v, field)
+		default:
+			eval.InvalidArgError("type, validation, or description", arg)
+		}
+	}
+
+	model.Fields = append(model.Fields, field)
+}
+
+// PrimaryKey marks a model field as the primary key.
+//
+// PrimaryKey must appear in a Field expression.
+//
+// Example:
+//
+//	Field("id", Int64, PrimaryKey())
+//	// Or in nested form:
+//	Field("slug", String, func() {
+//	    PrimaryKey()
+//	})
+func PrimaryKey() func() {
+	mark := func() {
+		field, ok := eval.Current().(*expr.AttributeExpr)
+		if !ok {
+			eval.IncompatibleDSL()
+			return
+		}
+		if field.Meta == nil {
+			field.Meta = make(map[string]interface{})
+		}
+		field.Meta[expr.MetaPrimaryKey] = true
+	}
+
+	// Check if we're in nested context
+	if _, ok := eval.Current().(*expr.AttributeExpr); ok {
+		mark()
+		return func() {}
+	}
+	return mark
+}
@@ -79,3 +79,24 @@ func Route(method, path string) {
 		Path:   path,
 	})
 }
+
+// SkipCSRF exempts the routes of a page from the CSRF middleware, for pages
+// posted to by other sites such as webhooks. Such pages must authenticate
+// requests by other means, for example by checking a signature.
+//
+// SkipCSRF must appear in a Page expression.
+//
+// Example:
+//
+//	Page("stripe_webhook", func() {
+//	    Route("POST", "/webhooks/stripe")
+//	    SkipCSRF()
+//	})
+func SkipCSRF() {
+	page, ok := eval.Current().(*expr.PageExpr)
+	if !ok {
+		eval.IncompatibleDSL()
+		return
+	}
+	page.SkipCSRF = true
+}
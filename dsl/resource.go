@@ -1,6 +1,9 @@
 package dsl
 
 import (
+	"fmt"
+	"slices"
+
 	"github.com/gobijan/gluey/eval"
 	"github.com/gobijan/gluey/expr"
 )
@@ -86,53 +89,129 @@ func Actions(actions ...string) {
 	resource.Actions = actions
 }
 
-// Auth specifies authentication requirements for the resource.
+// Formats specifies the formats the resource responds in: "html", "json"
+// or both. The first is the default; runtime.UseFormats negotiates the
+// others from the Accept header or a .json path suffix. Resources respond
+// in HTML only by default.
 //
-// Auth must appear in a Resource expression.
+// Formats must appear in a Resource expression.
 //
 // Example:
 //
 //	Resource("posts", func() {
-//	    Auth("authenticated").Except("index", "show")
-//	    Auth("admin").Only("destroy")
+//	    Formats("html", "json")
 //	})
-func Auth(requirement string) *authBuilder {
+func Formats(formats ...string) {
 	resource, ok := eval.Current().(*expr.ResourceExpr)
 	if !ok {
 		eval.IncompatibleDSL()
-		return nil
-	}
-
-	if resource.AuthRequirements == nil {
-		resource.AuthRequirements = make(map[string][]string)
+		return
 	}
+	resource.Formats = formats
+}
 
-	return &authBuilder{
-		resource:    resource,
-		requirement: requirement,
+// Auth specifies authentication requirements for the resource or page. The
+// generated router checks them with the Authorizer of its Controllers before
+// calling the handler.
+//
+// Auth must appear in a Resource or Page expression. In a Resource it
+// applies to every action, custom actions included, unless narrowed with
+// Except or Only; in a Page it applies to all routes of the page.
+//
+// Example:
+//
+//	Resource("drafts", func() {
+//	    Auth("authenticated")
+//	})
+//
+//	Resource("posts", func() {
+//	    Auth("authenticated").Except("index", "show")
+//	    Auth("admin").Only("destroy")
+//	})
+//
+//	Page("dashboard", func() {
+//	    Route("GET", "/dashboard")
+//	    Auth("authenticated")
+//	})
+func Auth(requirement string) *authBuilder {
+	switch e := eval.Current().(type) {
+	case *expr.ResourceExpr:
+		if e.AuthRequirements == nil {
+			e.AuthRequirements = make(map[string][]string)
+		}
+		// Every action is protected until Except or Only narrow it
+		for _, action := range restfulActions {
+			e.AuthRequirements[action] = append(e.AuthRequirements[action], requirement)
+		}
+		e.AuthAll = append(e.AuthAll, requirement)
+		return &authBuilder{
+			resource:    e,
+			requirement: requirement,
+		}
+	case *expr.PageExpr:
+		e.AuthRequirements = append(e.AuthRequirements, requirement)
+		return &authBuilder{
+			page:        e,
+			requirement: requirement,
+		}
+	default:
+		eval.IncompatibleDSL()
+		return nil
 	}
 }
 
+// restfulActions lists the actions a bare Auth applies to right away.
+// Custom actions get it when the resource is prepared.
+var restfulActions = []string{"index", "show", "new", "create", "edit", "update", "destroy"}
+
 // authBuilder helps build authentication requirements.
 type authBuilder struct {
 	resource    *expr.ResourceExpr
+	page        *expr.PageExpr
 	requirement string
 }
 
-// Except applies the requirement to all actions except the specified ones.
+// narrow withdraws the requirement Auth applied to every action of the
+// resource, before Except or Only apply it to some.
+func (a *authBuilder) narrow() {
+	for _, action := range restfulActions {
+		a.resource.AuthRequirements[action] = removeLast(a.resource.AuthRequirements[action], a.requirement)
+	}
+	a.resource.AuthAll = removeLast(a.resource.AuthAll, a.requirement)
+}
+
+// removeLast removes the last occurrence of s from list.
+func removeLast(list []string, s string) []string {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] == s {
+			return slices.Delete(list, i, i+1)
+		}
+	}
+	return list
+}
+
+// Except applies the requirement to all actions except the specified ones,
+// custom actions included.
 func (a *authBuilder) Except(actions ...string) *authBuilder {
-	if a == nil || a.resource == nil {
+	if a == nil {
+		return a
+	}
+	if a.page != nil {
+		eval.ReportError(fmt.Errorf("page %q: Auth(%q).Except is only supported in resources", a.page.Name, a.requirement))
+		return a
+	}
+	if a.resource == nil {
 		return a
 	}
+	a.narrow()
 
 	// Apply to all actions except specified
-	allActions := []string{"index", "show", "new", "create", "edit", "update", "destroy"}
 	excluded := make(map[string]bool)
 	for _, action := range actions {
 		excluded[action] = true
 	}
 
-	for _, action := range allActions {
+	for _, action := range restfulActions {
 		if !excluded[action] {
 			a.resource.AuthRequirements[action] = append(
 				a.resource.AuthRequirements[action],
@@ -141,14 +220,29 @@ func (a *authBuilder) Except(actions ...string) *authBuilder {
 		}
 	}
 
+	// Custom actions may be declared later, so they get the requirement
+	// when the resource is prepared
+	if a.resource.AuthExcept == nil {
+		a.resource.AuthExcept = make(map[string][]string)
+	}
+	a.resource.AuthExcept[a.requirement] = append(a.resource.AuthExcept[a.requirement], actions...)
+
 	return a
 }
 
 // Only applies the requirement only to the specified actions.
 func (a *authBuilder) Only(actions ...string) *authBuilder {
-	if a == nil || a.resource == nil {
+	if a == nil {
+		return a
+	}
+	if a.page != nil {
+		eval.ReportError(fmt.Errorf("page %q: Auth(%q).Only is only supported in resources", a.page.Name, a.requirement))
+		return a
+	}
+	if a.resource == nil {
 		return a
 	}
+	a.narrow()
 
 	for _, action := range actions {
 		a.resource.AuthRequirements[action] = append(
@@ -160,25 +254,34 @@ func (a *authBuilder) Only(actions ...string) *authBuilder {
 	return a
 }
 
-// BelongsTo specifies that this resource belongs to a parent resource.
+// BelongsTo nests the resource in a parent resource, named in singular or
+// plural form.
 //
-// BelongsTo must appear in a nested Resource expression.
+// BelongsTo must appear in a Resource expression. In a resource declared at
+// the top level it nests the resource shallowly, as Rails does: the
+// collection routes are nested in the parent while the member routes are
+// not, and the model gets a foreign key to the parent. Inside a nested
+// Resource it must name the enclosing resource.
 //
 // Example:
 //
-//	Resource("users", func() {
-//	    Resource("posts", func() {
-//	        BelongsTo("user")
-//	    })
+//	Resource("posts")
+//
+//	Resource("comments", func() {
+//	    BelongsTo("post")
+//	    // GET  /posts/{post_id}/comments      (index)
+//	    // GET  /posts/{post_id}/comments/new  (new)
+//	    // POST /posts/{post_id}/comments      (create)
+//	    // GET  /comments/{id}                 (show)
+//	    // ...
 //	})
 func BelongsTo(parent string) {
-	_, ok := eval.Current().(*expr.ResourceExpr)
+	resource, ok := eval.Current().(*expr.ResourceExpr)
 	if !ok {
 		eval.IncompatibleDSL()
 		return
 	}
-	// Parent relationship is already set during Resource() call
-	// This is mainly for clarity and potential future use
+	resource.BelongsTo = parent
 }
 
 // Index configures the index action.
@@ -195,24 +298,7 @@ func BelongsTo(parent string) {
 //	    })
 //	})
 func Index(fn func()) {
-	res, ok := eval.Current().(*expr.ResourceExpr)
-	if !ok {
-		eval.IncompatibleDSL()
-		return
-	}
-
-	// Create or get action config
-	if res.ActionConfigs == nil {
-		res.ActionConfigs = make(map[string]*expr.ActionConfig)
-	}
-	if res.ActionConfigs["index"] == nil {
-		res.ActionConfigs["index"] = &expr.ActionConfig{Action: "index"}
-	}
-
-	if fn != nil {
-		// Execute in the context of the action config
-		eval.Execute(fn, res.ActionConfigs["index"])
-	}
+	configureAction("index", fn)
 }
 
 // Paginate sets pagination for an action.
@@ -225,7 +311,7 @@ func Index(fn func()) {
 //	    Paginate(20)
 //	})
 func Paginate(perPage int) {
-	resource, ok := eval.Current().(*expr.ResourceExpr)
+	resource, action, ok := actionResource()
 	if !ok {
 		eval.IncompatibleDSL()
 		return
@@ -233,7 +319,7 @@ func Paginate(perPage int) {
 	if resource.Pagination == nil {
 		resource.Pagination = make(map[string]int)
 	}
-	resource.Pagination["index"] = perPage
+	resource.Pagination[action] = perPage
 }
 
 // Searchable marks fields as searchable.
@@ -246,7 +332,7 @@ func Paginate(perPage int) {
 //	    Searchable("title", "content", "author")
 //	})
 func Searchable(fields ...string) {
-	resource, ok := eval.Current().(*expr.ResourceExpr)
+	resource, action, ok := actionResource()
 	if !ok {
 		eval.IncompatibleDSL()
 		return
@@ -254,7 +340,7 @@ func Searchable(fields ...string) {
 	if resource.SearchableFields == nil {
 		resource.SearchableFields = make(map[string][]string)
 	}
-	resource.SearchableFields["index"] = fields
+	resource.SearchableFields[action] = fields
 }
 
 // Filterable marks fields as filterable.
@@ -267,7 +353,7 @@ func Searchable(fields ...string) {
 //	    Filterable("status", "category", "author")
 //	})
 func Filterable(fields ...string) {
-	resource, ok := eval.Current().(*expr.ResourceExpr)
+	resource, action, ok := actionResource()
 	if !ok {
 		eval.IncompatibleDSL()
 		return
@@ -275,7 +361,21 @@ func Filterable(fields ...string) {
 	if resource.FilterableFields == nil {
 		resource.FilterableFields = make(map[string][]string)
 	}
-	resource.FilterableFields["index"] = fields
+	resource.FilterableFields[action] = fields
+}
+
+// actionResource returns the resource and action configured by the current
+// expression. Settings made directly in a Resource apply to its index action.
+func actionResource() (*expr.ResourceExpr, string, bool) {
+	switch e := eval.Current().(type) {
+	case *expr.ResourceExpr:
+		return e, "index", true
+	case *expr.ActionConfig:
+		if e.Resource != nil {
+			return e.Resource, e.Action, true
+		}
+	}
+	return nil, "", false
 }
 
 // Create configures the create action.
@@ -290,24 +390,7 @@ func Filterable(fields ...string) {
 //	    })
 //	})
 func Create(fn func()) {
-	res, ok := eval.Current().(*expr.ResourceExpr)
-	if !ok {
-		eval.IncompatibleDSL()
-		return
-	}
-
-	// Create or get action config
-	if res.ActionConfigs == nil {
-		res.ActionConfigs = make(map[string]*expr.ActionConfig)
-	}
-	if res.ActionConfigs["create"] == nil {
-		res.ActionConfigs["create"] = &expr.ActionConfig{Action: "create"}
-	}
-
-	if fn != nil {
-		// Execute in the context of the action config
-		eval.Execute(fn, res.ActionConfigs["create"])
-	}
+	configureAction("create", fn)
 }
 
 // Update configures the update action.
@@ -322,6 +405,133 @@ func Create(fn func()) {
 //	    })
 //	})
 func Update(fn func()) {
+	configureAction("update", fn)
+}
+
+// Show configures the show action.
+//
+// Show must appear in a Resource expression.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    Show(func() {
+//	        Use("Cache")
+//	    })
+//	})
+func Show(fn func()) {
+	configureAction("show", fn)
+}
+
+// New configures the new action.
+//
+// New must appear in a Resource expression.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    New(func() {
+//	        UseForm("PostForm")
+//	    })
+//	})
+func New(fn func()) {
+	configureAction("new", fn)
+}
+
+// Edit configures the edit action.
+//
+// Edit must appear in a Resource expression.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    Edit(func() {
+//	        UseForm("EditPostForm")
+//	    })
+//	})
+func Edit(fn func()) {
+	configureAction("edit", fn)
+}
+
+// Destroy configures the destroy action.
+//
+// Destroy must appear in a Resource expression.
+//
+// Example:
+//
+//	Resource("users", func() {
+//	    Destroy(func() {
+//	        Use("AuditLog")
+//	    })
+//	})
+func Destroy(fn func()) {
+	configureAction("destroy", fn)
+}
+
+// Member declares a custom action on a single record of the resource,
+// routed with method under the path of the record. It adds a method named
+// after the action to the controller interface.
+//
+// Member must appear in a Resource expression. The optional DSL function
+// configures the action like Create or Index: UseForm binds a declared
+// form, Params declares query parameters and Use adds middleware. GET
+// actions get a view.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    Member("publish", "POST")  // POST /posts/{id}/publish
+//	    Member("preview", "GET", func() {
+//	        Params(func() {
+//	            Param("theme", String)
+//	        })
+//	    })
+//	})
+func Member(name, method string, fn ...func()) {
+	customAction(name, method, true, fn)
+}
+
+// Collection declares a custom action on the collection of the resource,
+// routed with method under the path of the collection. It adds a method
+// named after the action to the controller interface.
+//
+// Collection must appear in a Resource expression and accepts the same DSL
+// function as Member.
+//
+// Example:
+//
+//	Resource("posts", func() {
+//	    Collection("search", "GET", func() {  // GET /posts/search
+//	        Params(func() {
+//	            Param("q", String)
+//	        })
+//	    })
+//	})
+func Collection(name, method string, fn ...func()) {
+	customAction(name, method, false, fn)
+}
+
+// customAction declares a custom action of the current resource and
+// configures it with fn.
+func customAction(name, method string, member bool, fn []func()) {
+	res, ok := eval.Current().(*expr.ResourceExpr)
+	if !ok {
+		eval.IncompatibleDSL()
+		return
+	}
+	res.CustomActions = append(res.CustomActions, name)
+	var config func()
+	if len(fn) > 0 {
+		config = fn[0]
+	}
+	configureAction(name, config)
+	res.ActionConfigs[name].Method = method
+	res.ActionConfigs[name].Member = member
+}
+
+// configureAction creates the configuration of an action of the current
+// resource if needed and runs fn in its context.
+func configureAction(action string, fn func()) {
 	res, ok := eval.Current().(*expr.ResourceExpr)
 	if !ok {
 		eval.IncompatibleDSL()
@@ -332,13 +542,13 @@ func Update(fn func()) {
 	if res.ActionConfigs == nil {
 		res.ActionConfigs = make(map[string]*expr.ActionConfig)
 	}
-	if res.ActionConfigs["update"] == nil {
-		res.ActionConfigs["update"] = &expr.ActionConfig{Action: "update"}
+	if res.ActionConfigs[action] == nil {
+		res.ActionConfigs[action] = &expr.ActionConfig{Action: action, Resource: res}
 	}
 
 	if fn != nil {
 		// Execute in the context of the action config
-		eval.Execute(fn, res.ActionConfigs["update"])
+		eval.Execute(fn, res.ActionConfigs[action])
 	}
 }
 
@@ -434,7 +644,9 @@ func Singular() {
 //	Index(func() {
 //	    Params(func() {
 //	        Param("search", String)
-//	        Param("page", Int, Default(1))
+//	        Param("page", Int, func() {
+//	            Default(1)
+//	        })
 //	    })
 //	})
 func Params(fn func()) {
@@ -450,12 +662,16 @@ func Params(fn func()) {
 
 // Param defines a query parameter.
 //
-// Param must appear in a Params expression.
+// Param must appear in a Params expression. Besides the type it accepts a
+// description, a Max validation and a DSL function for Default.
 //
 // Example:
 //
-//	Param("page", Int, Default(1), Max(100))
-func Param(name string, dataType interface{}, fns ...func()) {
+//	Param("page", Int, func() {
+//	    Default(1)
+//	})
+//	Param("per_page", Int, "Items per page", Max(100))
+func Param(name string, dataType interface{}, args ...interface{}) {
 	config, ok := eval.Current().(*expr.ActionConfig)
 	if !ok {
 		eval.IncompatibleDSL()
@@ -464,12 +680,25 @@ func Param(name string, dataType interface{}, fns ...func()) {
 
 	param := &expr.ParamExpr{
 		Name: name,
-		Type: dataType.(expr.DataType),
+	}
+	if t, ok := dataType.(expr.DataType); ok {
+		param.Type = t
+	} else {
+		eval.InvalidArgError("type", dataType)
+		return
 	}
 
-	// Process functions like Default, Max, etc.
-	for _, fn := range fns {
-		fn() // These would set context on param
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case *expr.MaxValidation:
+			param.Max = v.Max
+		case string:
+			param.Description = v
+		case func():
+			eval.Execute(v, param)
+		default:
+			eval.InvalidArgError("description, Max, or DSL function", arg)
+		}
 	}
 
 	if config.Params == nil {
@@ -104,13 +104,16 @@ func Pattern(pattern string) expr.Validation {
 	return &expr.PatternValidation{Pattern: pattern}
 }
 
-// Enum specifies allowed values for an attribute.
+// Enum specifies allowed values for an attribute. It applies to strings,
+// numbers and lists of strings; numeric values are written as fmt prints them,
+// such as "2".
 //
 // Enum must appear in an Attribute expression.
 //
 // Example:
 //
 //	Attribute("status", String, Enum("draft", "published", "archived"))
+//	Attribute("priority", Int, Enum("1", "2", "3"))
 func Enum(values ...string) expr.Validation {
 	// Check if we're in nested context
 	if attr, ok := eval.Current().(*expr.AttributeExpr); ok {
@@ -140,33 +143,45 @@ func Min(min int) expr.Validation {
 
 // Max sets the maximum value for numeric attributes.
 //
-// Max must appear in an Attribute expression.
+// Max must appear in an Attribute or Param expression.
 //
 // Example:
 //
 //	Attribute("quantity", Int, Max(100))
 func Max(max int) expr.Validation {
 	// Check if we're in nested context
-	if attr, ok := eval.Current().(*expr.AttributeExpr); ok {
+	switch e := eval.Current().(type) {
+	case *expr.AttributeExpr:
 		v := &expr.MaxValidation{Max: max}
-		attr.Validations = append(attr.Validations, v)
+		e.Validations = append(e.Validations, v)
 		return v
+	case *expr.ParamExpr:
+		e.Max = max
 	}
 	return &expr.MaxValidation{Max: max}
 }
 
-// Validation sets a custom validation function.
+// Validation declares a named custom validation.
 //
-// Validation must appear in an Attribute expression.
+// Validation must appear in an Attribute expression. The built-in rules are
+// "matches", which compares the attribute to another one, and "accepted",
+// which requires a boolean attribute to be true. Any other name becomes a
+// hook method on the form's generated validator interface, named after the
+// attribute and the rule.
 //
 // Example:
 //
 //	Attribute("password_confirmation", String, func() {
-//	    Validation(func() {
-//	        // Custom validation logic
-//	    })
+//	    Validation("matches", "password")
 //	})
-func Validation(fn func()) {
-	// This is a placeholder for custom validation logic
-	// In a real implementation, this would register the validation
+//	Attribute("terms", Boolean, Validation("accepted"))
+//	// Generates ValidateEmailUnique(f *RegisterForm) error
+//	Attribute("email", String, Validation("unique"))
+func Validation(name string, args ...string) expr.Validation {
+	v := &expr.CustomValidation{Rule: name, Args: args}
+	// Check if we're in nested context
+	if attr, ok := eval.Current().(*expr.AttributeExpr); ok {
+		attr.Validations = append(attr.Validations, v)
+	}
+	return v
 }
@@ -18,13 +18,12 @@ var _ = WebApp("blog", func() {
 		Auth("authenticated").Except("index", "show")
 	})
 
-	// Comments nested under posts
-	Resource("posts", func() {
-		Resource("comments", func() {
-			BelongsTo("post")
-			Actions("index", "create", "destroy")
-			Auth("authenticated").Only("create", "destroy")
-		})
+	// Comments of posts: listed and created under /posts/{post_id}/comments,
+	// deleted at /comments/{id}
+	Resource("comments", func() {
+		BelongsTo("post")
+		Actions("index", "create", "destroy")
+		Auth("authenticated").Only("create", "destroy")
 	})
 
 	// User management
@@ -123,22 +122,22 @@ var _ = WebApp("blog", func() {
 		Attribute("email", String, func() {
 			Required()
 			Format(FormatEmail)
+			Validation("unique") // ValidateEmailUnique on RegisterFormValidator
 		})
 		Attribute("password", String, func() {
 			Required()
 			MinLength(8)
-			Pattern("^(?=.*[a-z])(?=.*[A-Z])(?=.*\\d).*$")
-			Description("Must contain uppercase, lowercase, and number")
+			Pattern("^\\S+$") // Go regexps are RE2: no lookaheads
+			Description("Must not contain whitespace")
 		})
 		Attribute("password_confirmation", String, func() {
 			Required()
+			Validation("matches", "password")
 			Description("Must match password")
 		})
 		Attribute("terms_accepted", Boolean, func() {
 			Required()
-			Validation(func() {
-				// Custom validation - must be true
-			})
+			Validation("accepted")
 		})
 	})
 
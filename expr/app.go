@@ -1,6 +1,8 @@
 package expr
 
 import (
+	"fmt"
+
 	"github.com/gobijan/gluey/eval"
 )
 
@@ -24,7 +26,11 @@ type AppExpr struct {
 	DefaultLayout string
 	// Middleware stack.
 	Middleware []string
-	// Session configuration.
+	// CustomMiddleware lists the middleware names supplied by the
+	// application rather than built into the runtime.
+	CustomMiddleware []string
+	// SessionStore is the session store: "cookie", "memory" or
+	// "filesystem".
 	SessionStore string
 	// Assets path.
 	AssetsPath string
@@ -42,9 +48,10 @@ func (a *AppExpr) DSL() func() {
 
 // WalkSets walks through the expression sets.
 func (a *AppExpr) WalkSets(walker eval.SetWalker) {
-	// Walk resources
-	for _, r := range a.Resources {
-		walker(eval.ExpressionSet{r})
+	// Walk resources by index: running the DSL of a resource appends the
+	// resources nested in it, which must be walked too
+	for i := 0; i < len(a.Resources); i++ {
+		walker(eval.ExpressionSet{a.Resources[i]})
 	}
 	// Walk pages
 	for _, p := range a.Pages {
@@ -72,6 +79,37 @@ func (a *AppExpr) Prepare() {
 	if a.AssetsPath == "" {
 		a.AssetsPath = "/static"
 	}
+
+	a.resolveBelongsTo()
+}
+
+// resolveBelongsTo nests the top-level resources declaring BelongsTo in
+// their parent, shallowly. Parents are looked up among the top-level
+// resources by singular or plural name. Unknown parents and parents that
+// would nest a resource in itself are left for Validate to report.
+func (a *AppExpr) resolveBelongsTo() {
+	for _, r := range a.Resources {
+		if r.BelongsTo == "" || r.Parent != nil {
+			continue
+		}
+		parent := a.topLevelResource(r.BelongsTo)
+		if parent == nil || parent.nestedIn(r) {
+			continue
+		}
+		r.Parent = parent
+		r.Shallow = true
+	}
+}
+
+// topLevelResource returns the resource declared at the top level whose
+// singular or plural name is name.
+func (a *AppExpr) topLevelResource(name string) *ResourceExpr {
+	for _, r := range a.Resources {
+		if (r.Parent == nil || r.Shallow) && (r.Name == name || singularize(r.Name) == name) {
+			return r
+		}
+	}
+	return nil
 }
 
 // Validate validates the application expression.
@@ -79,9 +117,111 @@ func (a *AppExpr) Validate() error {
 	if a.Name == "" {
 		return eval.Context.Errors
 	}
+
+	switch a.SessionStore {
+	case "", "cookie", "memory", "filesystem":
+	default:
+		return &ValidationError{
+			Message: fmt.Sprintf("unknown session store %q: use \"cookie\", \"memory\" or \"filesystem\"", a.SessionStore),
+		}
+	}
+
+	// Resources and pages render inside declared layouts
+	for _, r := range a.Resources {
+		if r.Layout != "" && !a.HasLayout(r.Layout) {
+			return &ValidationError{
+				Message: fmt.Sprintf("resource %q uses undeclared layout %q", r.Name, r.Layout),
+			}
+		}
+	}
+	for _, p := range a.Pages {
+		if p.Layout != "" && !a.HasLayout(p.Layout) {
+			return &ValidationError{
+				Message: fmt.Sprintf("page %q uses undeclared layout %q", p.Name, p.Layout),
+			}
+		}
+	}
+
+	// Nested resources need a known parent and a name of their own
+	names := make(map[string]bool)
+	for _, r := range a.Resources {
+		if r.BelongsTo != "" {
+			switch {
+			case r.Parent != nil && r.Parent.Name != r.BelongsTo && singularize(r.Parent.Name) != r.BelongsTo:
+				return &ValidationError{
+					Message: fmt.Sprintf("resource %q belongs to %q but is nested in %q", r.Name, r.BelongsTo, r.Parent.Name),
+				}
+			case r.Parent == nil && a.topLevelResource(r.BelongsTo) != nil:
+				return &ValidationError{
+					Message: fmt.Sprintf("resource %q cannot belong to %q, which belongs to it", r.Name, r.BelongsTo),
+				}
+			case r.Parent == nil:
+				return &ValidationError{
+					Message: fmt.Sprintf("resource %q belongs to unknown resource %q", r.Name, r.BelongsTo),
+				}
+			}
+		}
+		if names[r.QualifiedName()] {
+			return &ValidationError{
+				Message: fmt.Sprintf("resource %q is declared twice: nest resources with BelongsTo instead of repeating their parent", r.QualifiedName()),
+			}
+		}
+		names[r.QualifiedName()] = true
+	}
+
+	// Custom actions bind declared forms only, as there is no convention
+	// to generate one from
+	for _, r := range a.Resources {
+		for _, action := range r.CustomActions {
+			name := r.ActionConfigs[action].FormName
+			if name == "" || r.Forms[name] != nil || a.Form(name) != nil {
+				continue
+			}
+			return &ValidationError{
+				Message: fmt.Sprintf("resource %q: action %q uses undeclared form %q", r.Name, action, name),
+			}
+		}
+	}
+
+	// Models and forms share the generated types package
+	seen := make(map[string]string)
+	for _, f := range a.Forms {
+		seen[f.Name] = "form"
+	}
+	for _, r := range a.Resources {
+		for name := range r.Forms {
+			seen[name] = "form"
+		}
+	}
+	for _, r := range a.Resources {
+		if r.Model == nil {
+			continue
+		}
+		if kind, ok := seen[r.Model.Name]; ok {
+			return &ValidationError{
+				Message: fmt.Sprintf("model %q of resource %q conflicts with a %s of the same name",
+					r.Model.Name, r.Name, kind),
+			}
+		}
+		seen[r.Model.Name] = "model"
+	}
 	return nil
 }
 
+// HasLayout returns true if name is declared with Layout or is the default
+// layout. The application layout always exists.
+func (a *AppExpr) HasLayout(name string) bool {
+	if name == "application" || name == a.DefaultLayout {
+		return true
+	}
+	for _, l := range a.Layouts {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Resource returns a resource by name.
 func (a *AppExpr) Resource(name string) *ResourceExpr {
 	for _, r := range a.Resources {
@@ -92,6 +232,16 @@ func (a *AppExpr) Resource(name string) *ResourceExpr {
 	return nil
 }
 
+// Model returns a model by name.
+func (a *AppExpr) Model(name string) *ModelExpr {
+	for _, r := range a.Resources {
+		if r.Model != nil && r.Model.Name == name {
+			return r.Model
+		}
+	}
+	return nil
+}
+
 // Form returns a form by name.
 func (a *AppExpr) Form(name string) *FormExpr {
 	for _, f := range a.Forms {
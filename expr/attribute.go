@@ -1,5 +1,7 @@
 package expr
 
+import "fmt"
+
 // AttributeExpr represents a form field or type attribute.
 type AttributeExpr struct {
 	// Name is the attribute name.
@@ -82,6 +84,61 @@ func (a *AttributeExpr) Validate() error {
 		return &ValidationError{Message: "attribute type cannot be nil"}
 	}
 
+	// Patterns must compile with Go's RE2 syntax, rules must apply to the
+	// attribute's type and custom rules must be well formed
+	for _, v := range a.Validations {
+		switch v := v.(type) {
+		case *PatternValidation:
+			if a.Type.Kind() != StringKind {
+				return &ValidationError{Message: "pattern requires a string attribute"}
+			}
+			if _, err := v.Compile(); err != nil {
+				return err
+			}
+		case *FormatValidation:
+			if a.Type.Kind() != StringKind {
+				return &ValidationError{Message: "format requires a string attribute"}
+			}
+		case *EnumValidation:
+			if err := v.Check(a.Type); err != nil {
+				return err
+			}
+		case *CustomValidation:
+			if err := v.Check(a.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bounds must not contradict each other
+	if min, ok := a.Min(); ok {
+		if max, ok := a.Max(); ok && min > max {
+			return &ValidationError{
+				Message: fmt.Sprintf("min (%d) is greater than max (%d)", min, max),
+			}
+		}
+	}
+	if min, ok := a.MinLength(); ok {
+		if max, ok := a.MaxLength(); ok && min > max {
+			return &ValidationError{
+				Message: fmt.Sprintf("min length (%d) is greater than max length (%d)", min, max),
+			}
+		}
+	}
+
+	// The default value must have the attribute's type and satisfy its
+	// own validations
+	if a.DefaultValue != nil {
+		if err := checkValue(a.Type, a.DefaultValue); err != nil {
+			return &ValidationError{Message: "default value: " + err.Error()}
+		}
+		for _, v := range a.Validations {
+			if err := v.Validate(a.DefaultValue); err != nil {
+				return &ValidationError{Message: "default value: " + err.Error()}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -95,6 +152,12 @@ func (a *AttributeExpr) IsRequired() bool {
 	return false
 }
 
+// IsPrimaryKey returns true if the attribute is a model's primary key.
+func (a *AttributeExpr) IsPrimaryKey() bool {
+	pk, _ := a.Meta[MetaPrimaryKey].(bool)
+	return pk
+}
+
 // MaxLength returns the maximum length validation if any.
 func (a *AttributeExpr) MaxLength() (int, bool) {
 	for _, v := range a.Validations {
@@ -124,3 +187,55 @@ func (a *AttributeExpr) Format() (string, bool) {
 	}
 	return "", false
 }
+
+// Pattern returns the pattern validation if any.
+func (a *AttributeExpr) Pattern() (string, bool) {
+	for _, v := range a.Validations {
+		if p, ok := v.(*PatternValidation); ok {
+			return p.Pattern, true
+		}
+	}
+	return "", false
+}
+
+// Enum returns the allowed values if any.
+func (a *AttributeExpr) Enum() ([]string, bool) {
+	for _, v := range a.Validations {
+		if e, ok := v.(*EnumValidation); ok {
+			return e.Values, true
+		}
+	}
+	return nil, false
+}
+
+// CustomValidations returns the custom validations declared with
+// dsl.Validation.
+func (a *AttributeExpr) CustomValidations() []*CustomValidation {
+	var customs []*CustomValidation
+	for _, v := range a.Validations {
+		if c, ok := v.(*CustomValidation); ok {
+			customs = append(customs, c)
+		}
+	}
+	return customs
+}
+
+// Min returns the minimum value validation if any.
+func (a *AttributeExpr) Min() (int, bool) {
+	for _, v := range a.Validations {
+		if m, ok := v.(*MinValidation); ok {
+			return m.Min, true
+		}
+	}
+	return 0, false
+}
+
+// Max returns the maximum value validation if any.
+func (a *AttributeExpr) Max() (int, bool) {
+	for _, v := range a.Validations {
+		if m, ok := v.(*MaxValidation); ok {
+			return m.Max, true
+		}
+	}
+	return 0, false
+}
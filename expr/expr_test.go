@@ -1,6 +1,8 @@
 package expr_test
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/gobijan/gluey/expr"
@@ -58,6 +60,40 @@ func TestAppExpr(t *testing.T) {
 	}
 }
 
+func TestAppSessionStore(t *testing.T) {
+	for _, store := range []string{"", "cookie", "memory", "filesystem"} {
+		app := &expr.AppExpr{Name: "testapp", SessionStore: store}
+		if err := app.Validate(); err != nil {
+			t.Errorf("Validate() with store %q returned error: %v", store, err)
+		}
+	}
+
+	app := &expr.AppExpr{Name: "testapp", SessionStore: "redis"}
+	err := app.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown session store "redis"`) {
+		t.Errorf("Validate() error = %v, want unknown session store error", err)
+	}
+}
+
+func TestAppLayouts(t *testing.T) {
+	app := &expr.AppExpr{
+		Name:          "testapp",
+		Layouts:       []*expr.LayoutExpr{{Name: "admin"}},
+		DefaultLayout: "main",
+		Resources:     []*expr.ResourceExpr{{Name: "users", Layout: "admin"}, {Name: "posts", Layout: "main"}},
+		Pages:         []*expr.PageExpr{{Name: "home", Layout: "application"}},
+	}
+	if err := app.Validate(); err != nil {
+		t.Errorf("Validate() returned error: %v", err)
+	}
+
+	app.Pages[0].Layout = "marketing"
+	err := app.Validate()
+	if err == nil || !strings.Contains(err.Error(), `page "home" uses undeclared layout "marketing"`) {
+		t.Errorf("Validate() error = %v, want undeclared layout error", err)
+	}
+}
+
 func TestResourceExpr(t *testing.T) {
 	resource := &expr.ResourceExpr{
 		Name:    "posts",
@@ -278,3 +314,674 @@ func TestReset(t *testing.T) {
 		t.Error("Reset() should set Root to nil")
 	}
 }
+
+func TestValidationTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       expr.Validation
+		value   interface{}
+		wantErr bool
+	}{
+		{"email valid", &expr.FormatValidation{Format: expr.FormatEmail}, "a@example.com", false},
+		{"email invalid", &expr.FormatValidation{Format: expr.FormatEmail}, "nope", true},
+		{"url valid", &expr.FormatValidation{Format: expr.FormatURL}, "https://example.com", false},
+		{"url invalid", &expr.FormatValidation{Format: expr.FormatURL}, "example", true},
+		{"date valid", &expr.FormatValidation{Format: expr.FormatDate}, "2024-01-31", false},
+		{"date invalid", &expr.FormatValidation{Format: expr.FormatDate}, "31/01/2024", true},
+		{"datetime valid", &expr.FormatValidation{Format: expr.FormatDateTime}, "2024-01-31T10:00:00Z", false},
+		{"datetime invalid", &expr.FormatValidation{Format: expr.FormatDateTime}, "tomorrow", true},
+		{"uuid valid", &expr.FormatValidation{Format: expr.FormatUUID}, "123e4567-e89b-12d3-a456-426614174000", false},
+		{"uuid invalid", &expr.FormatValidation{Format: expr.FormatUUID}, "123", true},
+		{"unknown format", &expr.FormatValidation{Format: "color"}, "red", true},
+		{"pattern match", &expr.PatternValidation{Pattern: "^[a-z]+$"}, "abc", false},
+		{"pattern mismatch", &expr.PatternValidation{Pattern: "^[a-z]+$"}, "ABC", true},
+		{"pattern invalid", &expr.PatternValidation{Pattern: "^(?=a)"}, "a", true},
+		{"enum member", &expr.EnumValidation{Values: []string{"draft", "published"}}, "draft", false},
+		{"enum non-member", &expr.EnumValidation{Values: []string{"draft", "published"}}, "deleted", true},
+		{"min ok", &expr.MinValidation{Min: 1}, 1, false},
+		{"min fails", &expr.MinValidation{Min: 1}, 0, true},
+		{"max ok", &expr.MaxValidation{Max: 10}, 9.5, false},
+		{"max fails", &expr.MaxValidation{Max: 10}, int64(11), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.Validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttributeDesignTimeChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		attr *expr.AttributeExpr
+	}{
+		{
+			name: "non-RE2 pattern",
+			attr: &expr.AttributeExpr{
+				Name: "password", Type: expr.String,
+				Validations: []expr.Validation{&expr.PatternValidation{Pattern: "^(?=.*[A-Z]).*$"}},
+			},
+		},
+		{
+			name: "min greater than max",
+			attr: &expr.AttributeExpr{
+				Name: "age", Type: expr.Int,
+				Validations: []expr.Validation{&expr.MinValidation{Min: 10}, &expr.MaxValidation{Max: 5}},
+			},
+		},
+		{
+			name: "min length greater than max length",
+			attr: &expr.AttributeExpr{
+				Name: "title", Type: expr.String,
+				Validations: []expr.Validation{&expr.MinLengthValidation{Min: 10}, &expr.MaxLengthValidation{Max: 5}},
+			},
+		},
+		{
+			name: "default outside enum",
+			attr: &expr.AttributeExpr{
+				Name: "status", Type: expr.String, DefaultValue: "deleted",
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"draft"}}},
+			},
+		},
+		{
+			name: "pattern on int",
+			attr: &expr.AttributeExpr{
+				Name: "count", Type: expr.Int,
+				Validations: []expr.Validation{&expr.PatternValidation{Pattern: "^[0-9]+$"}},
+			},
+		},
+		{
+			name: "format on array",
+			attr: &expr.AttributeExpr{
+				Name: "emails", Type: &expr.ArrayType{ElemType: expr.String},
+				Validations: []expr.Validation{&expr.FormatValidation{Format: expr.FormatEmail}},
+			},
+		},
+		{
+			name: "enum on boolean",
+			attr: &expr.AttributeExpr{
+				Name: "done", Type: expr.Boolean,
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"true"}}},
+			},
+		},
+		{
+			name: "non-numeric enum on int",
+			attr: &expr.AttributeExpr{
+				Name: "priority", Type: expr.Int,
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"1", "high"}}},
+			},
+		},
+		{
+			name: "non-canonical enum on int",
+			attr: &expr.AttributeExpr{
+				Name: "priority", Type: expr.Int,
+				Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"01"}}},
+			},
+		},
+		{
+			name: "string default on boolean",
+			attr: &expr.AttributeExpr{Name: "completed", Type: expr.Boolean, DefaultValue: "false"},
+		},
+		{
+			name: "float default on int",
+			attr: &expr.AttributeExpr{Name: "page", Type: expr.Int, DefaultValue: 1.5},
+		},
+		{
+			name: "default overflows int32",
+			attr: &expr.AttributeExpr{Name: "count", Type: expr.Int32, DefaultValue: int64(1) << 40},
+		},
+		{
+			name: "wrong array element type",
+			attr: &expr.AttributeExpr{
+				Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}, DefaultValue: []int{1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.attr.Validate(); err == nil {
+				t.Error("Validate() should return an error")
+			}
+		})
+	}
+
+	valid := &expr.AttributeExpr{
+		Name: "status", Type: expr.String, DefaultValue: "draft",
+		Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"draft", "published"}}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() returned error for a valid attribute: %v", err)
+	}
+
+	for _, attr := range []*expr.AttributeExpr{
+		{Name: "priority", Type: expr.Int, DefaultValue: 2, Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"1", "2", "3"}}}},
+		{Name: "scale", Type: expr.Float32, Validations: []expr.Validation{&expr.EnumValidation{Values: []string{"0.5", "1.5"}}}},
+		{Name: "ratio", Type: expr.Float64, DefaultValue: 1},
+		{Name: "raw", Type: expr.Bytes, DefaultValue: "abc"},
+		{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}, DefaultValue: []string{"go"}},
+		{Name: "limits", Type: &expr.MapType{KeyType: expr.String, ElemType: expr.Int}, DefaultValue: map[string]int{"max": 10}},
+	} {
+		if err := attr.Validate(); err != nil {
+			t.Errorf("Validate() returned error for %s default: %v", attr.Name, err)
+		}
+	}
+}
+
+func TestCustomValidation(t *testing.T) {
+	form := &expr.FormExpr{
+		Name: "RegisterForm",
+		Attributes: []*expr.AttributeExpr{
+			{Name: "email", Type: expr.String, Validations: []expr.Validation{&expr.CustomValidation{Rule: "unique"}}},
+			{Name: "password", Type: expr.String},
+			{
+				Name: "password_confirmation", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleMatches, Args: []string{"password"}}},
+			},
+			{Name: "terms", Type: expr.Boolean, Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleAccepted}}},
+		},
+	}
+	if err := form.Validate(); err != nil {
+		t.Fatalf("Validate() returned error for valid custom validations: %v", err)
+	}
+	if !form.Attribute("email").CustomValidations()[0].IsHook() {
+		t.Error("unique should be a hook")
+	}
+	if form.Attribute("terms").CustomValidations()[0].IsHook() {
+		t.Error("accepted should be built in")
+	}
+
+	tests := []struct {
+		name string
+		attr *expr.AttributeExpr
+		want string
+	}{
+		{
+			name: "invalid rule name",
+			attr: &expr.AttributeExpr{Name: "email", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: "Not Valid"}}},
+			want: "invalid validation name",
+		},
+		{
+			name: "accepted on a string",
+			attr: &expr.AttributeExpr{Name: "terms", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleAccepted}}},
+			want: "requires a boolean attribute",
+		},
+		{
+			name: "matches without argument",
+			attr: &expr.AttributeExpr{Name: "confirm", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleMatches}}},
+			want: "takes the name of one attribute",
+		},
+		{
+			name: "matches unknown attribute",
+			attr: &expr.AttributeExpr{Name: "confirm", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: expr.RuleMatches, Args: []string{"missing"}}}},
+			want: `unknown attribute "missing"`,
+		},
+		{
+			name: "hook with arguments",
+			attr: &expr.AttributeExpr{Name: "email", Type: expr.String,
+				Validations: []expr.Validation{&expr.CustomValidation{Rule: "unique", Args: []string{"users"}}}},
+			want: "takes no arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &expr.FormExpr{Name: "TestForm", Attributes: []*expr.AttributeExpr{tt.attr}}
+			err := f.Validate()
+			if err == nil {
+				t.Fatal("Validate() should return an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q should contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelExpr(t *testing.T) {
+	t.Run("adds id primary key", func(t *testing.T) {
+		model := &expr.ModelExpr{Name: "Post", Fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}}}
+		model.Prepare()
+
+		pk := model.PrimaryKey()
+		if pk == nil || pk.Name != "id" || pk.Type != expr.Int64 {
+			t.Fatalf("PrimaryKey() = %+v, want id Int64", pk)
+		}
+		if model.Fields[0] != pk {
+			t.Error("generated primary key should be the first field")
+		}
+	})
+
+	t.Run("uses existing id field", func(t *testing.T) {
+		model := &expr.ModelExpr{Name: "Post", Fields: []*expr.AttributeExpr{
+			{Name: "title", Type: expr.String},
+			{Name: "id", Type: expr.String},
+		}}
+		model.Prepare()
+
+		if len(model.Fields) != 2 || model.PrimaryKey() != model.Field("id") {
+			t.Errorf("Prepare() should mark the existing id field as primary key")
+		}
+	})
+
+	t.Run("keeps explicit primary key", func(t *testing.T) {
+		model := &expr.ModelExpr{Name: "Tag", Fields: []*expr.AttributeExpr{
+			{Name: "slug", Type: expr.String, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+		}}
+		model.Prepare()
+
+		if len(model.Fields) != 1 || model.PrimaryKey().Name != "slug" {
+			t.Errorf("PrimaryKey() = %+v, want slug", model.PrimaryKey())
+		}
+		if err := model.Validate(); err != nil {
+			t.Errorf("Validate() returned error for valid model: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		fields []*expr.AttributeExpr
+		want   string
+	}{
+		{
+			name:   "duplicate field",
+			fields: []*expr.AttributeExpr{{Name: "title", Type: expr.String}, {Name: "title", Type: expr.String}},
+			want:   `duplicate field "title"`,
+		},
+		{
+			name: "invalid primary key type",
+			fields: []*expr.AttributeExpr{
+				{Name: "id", Type: expr.Boolean, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+			},
+			want: `primary key "id" must be an integer or a string`,
+		},
+		{
+			name: "two primary keys",
+			fields: []*expr.AttributeExpr{
+				{Name: "id", Type: expr.Int64, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+				{Name: "slug", Type: expr.String, Meta: map[string]interface{}{expr.MetaPrimaryKey: true}},
+			},
+			want: "only one field can be the primary key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &expr.ModelExpr{Name: "Post", Fields: tt.fields}
+			model.Prepare()
+			err := model.Validate()
+			if err == nil {
+				t.Fatal("Validate() should return an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceIndexOptions(t *testing.T) {
+	newResource := func() *expr.ResourceExpr {
+		r := &expr.ResourceExpr{
+			Name:             "posts",
+			Pagination:       map[string]int{"index": 10},
+			SearchableFields: map[string][]string{"index": {"title"}},
+			FilterableFields: map[string][]string{"index": {"status"}},
+		}
+		r.Model = &expr.ModelExpr{Name: "Post", Resource: r, Fields: []*expr.AttributeExpr{
+			{Name: "title", Type: expr.String},
+			{Name: "status", Type: expr.String},
+			{Name: "tags", Type: &expr.ArrayType{ElemType: expr.String}},
+			{Name: "views", Type: expr.Int},
+		}}
+		return r
+	}
+
+	t.Run("declared params are kept", func(t *testing.T) {
+		r := newResource()
+		r.ActionConfigs = map[string]*expr.ActionConfig{"index": {
+			Action: "index",
+			Params: []*expr.ParamExpr{{Name: "page", Type: expr.Int, Default: 2}},
+		}}
+		r.Prepare()
+
+		config := r.ActionConfigs["index"]
+		if len(config.Params) != 4 {
+			t.Fatalf("index params = %d, want 4", len(config.Params))
+		}
+		if page := config.Param("page"); page.Default != 2 {
+			t.Errorf("declared page param was replaced: %+v", page)
+		}
+		if err := r.Validate(); err != nil {
+			t.Errorf("Validate() returned error: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(r *expr.ResourceExpr)
+		want   string
+	}{
+		{
+			name:   "unknown searchable field",
+			modify: func(r *expr.ResourceExpr) { r.SearchableFields["index"] = []string{"body"} },
+			want:   `searchable field "body" is not a field of model "Post"`,
+		},
+		{
+			name:   "searchable non-string field",
+			modify: func(r *expr.ResourceExpr) { r.SearchableFields["index"] = []string{"views"} },
+			want:   `searchable field "views" must be a String`,
+		},
+		{
+			name:   "filterable array field",
+			modify: func(r *expr.ResourceExpr) { r.FilterableFields["index"] = []string{"tags"} },
+			want:   `filterable field "tags" must be a scalar`,
+		},
+		{
+			name:   "invalid page size",
+			modify: func(r *expr.ResourceExpr) { r.Pagination["index"] = -5 },
+			want:   "Paginate requires a positive page size",
+		},
+		{
+			name: "paginated with string page param",
+			modify: func(r *expr.ResourceExpr) {
+				r.ActionConfigs = map[string]*expr.ActionConfig{"index": {
+					Action: "index",
+					Params: []*expr.ParamExpr{{Name: "page", Type: expr.String}},
+				}}
+			},
+			want: `param "page" must be an Int`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResource()
+			tt.modify(r)
+			r.Prepare()
+			err := r.Validate()
+			if err == nil {
+				t.Fatal("Validate() should return an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceActionMiddleware(t *testing.T) {
+	r := &expr.ResourceExpr{
+		Name:    "users",
+		Actions: []string{"index", "show"},
+		ActionConfigs: map[string]*expr.ActionConfig{
+			"index": {Action: "index", Middleware: []string{"Cache"}},
+		},
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+
+	r.ActionConfigs["destroy"] = &expr.ActionConfig{Action: "destroy", Middleware: []string{"AuditLog"}}
+	err := r.Validate()
+	if err == nil || !strings.Contains(err.Error(), `action "destroy" uses middleware but is not an action of the resource`) {
+		t.Errorf("Validate() error = %v, want middleware on missing action error", err)
+	}
+}
+
+func TestResourceFormats(t *testing.T) {
+	r := &expr.ResourceExpr{Name: "posts"}
+	r.Prepare()
+	if !slices.Equal(r.Formats, []string{"html"}) || !r.HasFormat("html") || r.HasFormat("json") {
+		t.Errorf("Formats = %v, want html by default", r.Formats)
+	}
+
+	r.Formats = []string{"json", "html"}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+
+	r.Formats = []string{"html", "xml", "html"}
+	err := r.Validate()
+	if err == nil || !strings.Contains(err.Error(), `invalid format "xml"`) || !strings.Contains(err.Error(), `format "html" is declared twice`) {
+		t.Errorf("Validate() error = %v, want invalid and duplicate format errors", err)
+	}
+}
+
+func TestNestedResourceNames(t *testing.T) {
+	posts := &expr.ResourceExpr{Name: "posts"}
+	comments := &expr.ResourceExpr{Name: "comments", Parent: posts}
+	likes := &expr.ResourceExpr{Name: "likes", Parent: comments}
+	profile := &expr.ResourceExpr{Name: "profiles", Singular: true}
+	photos := &expr.ResourceExpr{Name: "photos", Parent: profile}
+
+	tests := []struct {
+		resource  *expr.ResourceExpr
+		qualified string
+		path      string
+		params    []string
+		newForm   string
+	}{
+		{posts, "posts", "/posts", nil, "NewPostsForm"},
+		{comments, "post_comments", "/posts/{post_id}/comments", []string{"post_id"}, "NewPostCommentsForm"},
+		{likes, "post_comment_likes", "/posts/{post_id}/comments/{comment_id}/likes", []string{"post_id", "comment_id"}, "NewPostCommentLikesForm"},
+		{photos, "profile_photos", "/profile/photos", nil, "NewProfilePhotosForm"},
+	}
+	for _, tt := range tests {
+		if got := tt.resource.QualifiedName(); got != tt.qualified {
+			t.Errorf("QualifiedName() = %q, want %q", got, tt.qualified)
+		}
+		if got := tt.resource.Path(); got != tt.path {
+			t.Errorf("Path() = %q, want %q", got, tt.path)
+		}
+		if got := tt.resource.ParentParams(); !slices.Equal(got, tt.params) {
+			t.Errorf("%s: ParentParams() = %v, want %v", tt.qualified, got, tt.params)
+		}
+		if got := tt.resource.NewFormName(); got != tt.newForm {
+			t.Errorf("NewFormName() = %q, want %q", got, tt.newForm)
+		}
+	}
+	if ancestors := likes.Ancestors(); len(ancestors) != 2 || ancestors[0] != posts || ancestors[1] != comments {
+		t.Errorf("Ancestors() = %v, want posts and comments", ancestors)
+	}
+}
+
+func TestBelongsTo(t *testing.T) {
+	newApp := func(resources ...*expr.ResourceExpr) *expr.AppExpr {
+		app := &expr.AppExpr{Name: "testapp", Resources: resources}
+		app.Prepare()
+		for _, r := range resources {
+			r.Prepare()
+		}
+		return app
+	}
+
+	t.Run("nests shallowly", func(t *testing.T) {
+		posts := &expr.ResourceExpr{Name: "posts"}
+		posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts}
+		comments := &expr.ResourceExpr{Name: "comments", BelongsTo: "post"}
+		comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "body", Type: expr.String}}}
+		likes := &expr.ResourceExpr{Name: "likes", Parent: comments}
+		app := newApp(comments, posts, likes)
+		if err := app.Validate(); err != nil {
+			t.Fatalf("Validate() returned error: %v", err)
+		}
+
+		if comments.Parent != posts || !comments.Shallow {
+			t.Fatalf("comments should be nested shallowly in posts, got parent %v", comments.Parent)
+		}
+		if got := comments.QualifiedName(); got != "comments" {
+			t.Errorf("QualifiedName() = %q, want comments", got)
+		}
+		if got := comments.Path(); got != "/posts/{post_id}/comments" {
+			t.Errorf("Path() = %q", got)
+		}
+		if got := comments.MemberPath(); got != "/comments" {
+			t.Errorf("MemberPath() = %q, want /comments", got)
+		}
+		if got := likes.Path(); got != "/comments/{comment_id}/likes" {
+			t.Errorf("likes Path() = %q", got)
+		}
+		if got := likes.QualifiedName(); got != "comment_likes" {
+			t.Errorf("likes QualifiedName() = %q, want comment_likes", got)
+		}
+
+		fk := comments.ForeignKey()
+		if fk == nil || fk.Name != "post_id" || fk.Type != expr.Int64 {
+			t.Fatalf("ForeignKey() = %+v, want post_id Int64", fk)
+		}
+		if comments.Model.Fields[1] != fk {
+			t.Error("foreign key should follow the primary key")
+		}
+		if fk := likes.ForeignKey(); fk != nil {
+			t.Errorf("resources without a model have no foreign key, got %+v", fk)
+		}
+	})
+
+	t.Run("reports invalid parents", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			resources []*expr.ResourceExpr
+			want      string
+		}{
+			{"unknown", []*expr.ResourceExpr{{Name: "comments", BelongsTo: "article"}},
+				`resource "comments" belongs to unknown resource "article"`},
+			{"cycle", []*expr.ResourceExpr{{Name: "posts", BelongsTo: "comment"}, {Name: "comments", BelongsTo: "post"}},
+				`cannot belong to "post", which belongs to it`},
+			{"duplicate", []*expr.ResourceExpr{{Name: "posts"}, {Name: "posts"}},
+				`resource "posts" is declared twice`},
+		}
+		for _, tt := range tests {
+			err := newApp(tt.resources...).Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: Validate() error = %v, want %q", tt.name, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("checks declared foreign key", func(t *testing.T) {
+		posts := &expr.ResourceExpr{Name: "posts"}
+		posts.Model = &expr.ModelExpr{Name: "Post", Resource: posts}
+		comments := &expr.ResourceExpr{Name: "comments", BelongsTo: "posts"}
+		comments.Model = &expr.ModelExpr{Name: "Comment", Resource: comments, Fields: []*expr.AttributeExpr{{Name: "post_id", Type: expr.String}}}
+		newApp(posts, comments)
+
+		err := comments.Validate()
+		if err == nil || !strings.Contains(err.Error(), `foreign key "post_id" must be of type int64`) {
+			t.Errorf("Validate() error = %v, want foreign key type error", err)
+		}
+	})
+}
+
+func TestCustomActions(t *testing.T) {
+	newResource := func(name string, configs ...*expr.ActionConfig) *expr.ResourceExpr {
+		r := &expr.ResourceExpr{Name: name, ActionConfigs: map[string]*expr.ActionConfig{}}
+		for _, config := range configs {
+			config.Resource = r
+			r.CustomActions = append(r.CustomActions, config.Action)
+			r.ActionConfigs[config.Action] = config
+		}
+		return r
+	}
+
+	t.Run("paths", func(t *testing.T) {
+		posts := newResource("posts",
+			&expr.ActionConfig{Action: "publish", Method: "POST", Member: true},
+			&expr.ActionConfig{Action: "search", Method: "GET"})
+		comments := newResource("comments", &expr.ActionConfig{Action: "approve", Method: "PATCH", Member: true})
+		comments.Parent = posts
+		profile := newResource("profile", &expr.ActionConfig{Action: "reset", Method: "POST", Member: true})
+		profile.Singular = true
+		for _, r := range []*expr.ResourceExpr{posts, comments, profile} {
+			r.Prepare()
+			if err := r.Validate(); err != nil {
+				t.Fatalf("Validate() returned error: %v", err)
+			}
+		}
+
+		for config, want := range map[*expr.ActionConfig]string{
+			posts.CustomAction("publish"):    "/posts/{id}/publish",
+			posts.CustomAction("search"):     "/posts/search",
+			comments.CustomAction("approve"): "/posts/{post_id}/comments/{id}/approve",
+			profile.CustomAction("reset"):    "/profile/reset",
+		} {
+			if got := config.Path(); got != want {
+				t.Errorf("%s Path() = %q, want %q", config.Action, got, want)
+			}
+		}
+		if !posts.HasAction("publish") || posts.CustomAction("index") != nil {
+			t.Error("HasAction and CustomAction should know custom actions only")
+		}
+		if got := posts.ActionNames(); len(got) != 9 || got[7] != "publish" || got[8] != "search" {
+			t.Errorf("ActionNames() = %v, want RESTful actions then publish, search", got)
+		}
+	})
+
+	t.Run("Auth Except", func(t *testing.T) {
+		posts := newResource("posts",
+			&expr.ActionConfig{Action: "publish", Method: "POST", Member: true},
+			&expr.ActionConfig{Action: "search", Method: "GET"})
+		posts.AuthExcept = map[string][]string{"authenticated": {"index", "search"}}
+		posts.Prepare()
+		posts.Prepare()
+		if got := posts.AuthRequirements["publish"]; len(got) != 1 || got[0] != "authenticated" {
+			t.Errorf("publish requirements = %v, want [authenticated]", got)
+		}
+		if got := posts.AuthRequirements["search"]; len(got) != 0 {
+			t.Errorf("excluded search requirements = %v, want none", got)
+		}
+	})
+
+	t.Run("reports invalid actions", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			resource *expr.ResourceExpr
+			want     string
+		}{
+			{"name", newResource("posts", &expr.ActionConfig{Action: "Publish", Method: "POST", Member: true}),
+				`resource "posts": invalid custom action name "Publish", use lowercase letters, digits and underscores`},
+			{"RESTful", newResource("posts", &expr.ActionConfig{Action: "show", Method: "GET", Member: true}),
+				`resource "posts": custom action "show" conflicts with the RESTful action of the same name`},
+			{"twice", newResource("posts", &expr.ActionConfig{Action: "publish", Method: "POST", Member: true}, &expr.ActionConfig{Action: "publish", Method: "POST", Member: true}),
+				`resource "posts": custom action "publish" is declared twice`},
+			{"method", newResource("posts", &expr.ActionConfig{Action: "publish", Method: "SEND", Member: true}),
+				`resource "posts": custom action "publish" has invalid method "SEND", must be one of GET, POST, PUT, PATCH, DELETE`},
+		}
+		profile := newResource("profile", &expr.ActionConfig{Action: "search", Method: "GET"})
+		profile.Singular = true
+		tests = append(tests, struct {
+			name     string
+			resource *expr.ResourceExpr
+			want     string
+		}{"singular", profile, `resource "profile": singular resources have no collection, declare "search" with Member`})
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.resource.Prepare()
+				err := tt.resource.Validate()
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("Validate() = %v, want %q", err, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("reports undeclared forms", func(t *testing.T) {
+		posts := newResource("posts", &expr.ActionConfig{Action: "publish", Method: "POST", Member: true, FormName: "PublishForm"})
+		app := &expr.AppExpr{Name: "testapp", Resources: []*expr.ResourceExpr{posts}}
+		app.Prepare()
+		posts.Prepare()
+		want := `resource "posts": action "publish" uses undeclared form "PublishForm"`
+		if err := app.Validate(); err == nil || err.Error() != want {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	})
+}
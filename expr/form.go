@@ -1,5 +1,10 @@
 package expr
 
+import (
+	"errors"
+	"fmt"
+)
+
 // FormExpr represents a form type.
 type FormExpr struct {
 	// Name is the form name.
@@ -34,13 +39,42 @@ func (f *FormExpr) Validate() error {
 		return &ValidationError{Message: "form name cannot be empty"}
 	}
 
-	// Validate attributes
+	// Validate attributes, reporting every invalid one
+	var errs []error
 	for _, attr := range f.Attributes {
-		if err := attr.Validate(); err != nil {
-			return err
+		err := attr.Validate()
+		if err == nil {
+			err = f.validateCrossField(attr)
+		}
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("form %q: attribute %q: %s", f.Name, attr.Name, err),
+			})
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// validateCrossField checks that "matches" validations refer to another
+// string attribute of the form.
+func (f *FormExpr) validateCrossField(attr *AttributeExpr) error {
+	for _, c := range attr.CustomValidations() {
+		if c.Rule != RuleMatches {
+			continue
+		}
+		other := f.Attribute(c.Args[0])
+		if other == nil || other == attr {
+			return &ValidationError{
+				Message: fmt.Sprintf("validation \"matches\": unknown attribute %q", c.Args[0]),
+			}
+		}
+		if other.Type != nil && other.Type.Kind() != StringKind {
+			return &ValidationError{
+				Message: fmt.Sprintf("validation \"matches\": attribute %q is not a string", c.Args[0]),
+			}
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MetaPrimaryKey is the attribute meta key that marks a model's primary key.
+const MetaPrimaryKey = "primary_key"
+
+// ModelExpr represents the record behind a resource.
+type ModelExpr struct {
+	// Name is the model name (e.g., "Post").
+	Name string
+	// DSLFunc contains the DSL function.
+	DSLFunc func()
+	// Fields are the model fields.
+	Fields []*AttributeExpr
+	// Resource is the resource the model belongs to.
+	Resource *ResourceExpr
+}
+
+// EvalName returns the name of the model.
+func (m *ModelExpr) EvalName() string {
+	return m.Name
+}
+
+// Prepare prepares the model expression. A model without an explicit
+// primary key uses its "id" field, adding an Int64 one if needed.
+func (m *ModelExpr) Prepare() {
+	for _, field := range m.Fields {
+		field.Prepare()
+	}
+
+	if m.PrimaryKey() != nil {
+		return
+	}
+	if id := m.Field("id"); id != nil {
+		id.Meta[MetaPrimaryKey] = true
+		return
+	}
+
+	id := &AttributeExpr{Name: "id", Type: Int64}
+	id.Prepare()
+	id.Meta[MetaPrimaryKey] = true
+	m.Fields = append([]*AttributeExpr{id}, m.Fields...)
+}
+
+// Validate validates the model expression.
+func (m *ModelExpr) Validate() error {
+	if m.Name == "" {
+		return &ValidationError{Message: "model name cannot be empty"}
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	keys := 0
+	for _, field := range m.Fields {
+		if seen[field.Name] {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("model %q: duplicate field %q", m.Name, field.Name),
+			})
+		}
+		seen[field.Name] = true
+
+		if err := field.Validate(); err != nil {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("model %q: field %q: %s", m.Name, field.Name, err),
+			})
+		}
+
+		if field.IsPrimaryKey() {
+			keys++
+			switch field.Type {
+			case Int, Int32, Int64, String:
+			default:
+				errs = append(errs, &ValidationError{
+					Message: fmt.Sprintf("model %q: primary key %q must be an integer or a string, got %s",
+						m.Name, field.Name, field.Type.Name()),
+				})
+			}
+		}
+	}
+
+	if keys > 1 {
+		errs = append(errs, &ValidationError{
+			Message: fmt.Sprintf("model %q: only one field can be the primary key", m.Name),
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Field returns a field by name.
+func (m *ModelExpr) Field(name string) *AttributeExpr {
+	for _, field := range m.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// PrimaryKey returns the primary key field, or nil before Prepare if none
+// was declared.
+func (m *ModelExpr) PrimaryKey() *AttributeExpr {
+	for _, field := range m.Fields {
+		if field.IsPrimaryKey() {
+			return field
+		}
+	}
+	return nil
+}
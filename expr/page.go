@@ -14,6 +14,10 @@ type PageExpr struct {
 	Layout string
 	// Auth requirements.
 	AuthRequirements []string
+	// Middleware wraps the handlers of the page routes.
+	Middleware []string
+	// SkipCSRF exempts the page routes from CSRF checks.
+	SkipCSRF bool
 }
 
 // RouteExpr represents an HTTP route.
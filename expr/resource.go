@@ -1,5 +1,37 @@
 package expr
 
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Response formats of resources.
+const (
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+// Names of the index params added for Searchable and Paginate.
+const (
+	ParamSearch  = "q"
+	ParamPage    = "page"
+	ParamPerPage = "per_page"
+)
+
+// restfulActions lists the actions generated by default.
+var restfulActions = []string{"index", "show", "new", "create", "edit", "update", "destroy"}
+
+// customActionMethods lists the HTTP methods of custom actions.
+var customActionMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// actionNamePattern matches valid custom action names.
+var actionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
 // ActionConfig holds configuration for a resource action.
 type ActionConfig struct {
 	// Action name (for identification)
@@ -8,6 +40,16 @@ type ActionConfig struct {
 	FormName string
 	// Params holds query parameter definitions for index/show actions
 	Params []*ParamExpr
+	// Resource is the resource the action belongs to
+	Resource *ResourceExpr
+	// Middleware wraps the handlers of the action only
+	Middleware []string
+	// Method is the HTTP method of the custom actions declared with Member
+	// or Collection, empty for the RESTful ones
+	Method string
+	// Member is true for custom actions on a single record, routed under
+	// its id, and false for those on the collection
+	Member bool
 }
 
 // EvalName returns the name of the action config.
@@ -17,7 +59,13 @@ func (a *ActionConfig) EvalName() string {
 
 // Validate validates the action config.
 func (a *ActionConfig) Validate() error {
-	return nil
+	var errs []error
+	for _, param := range a.Params {
+		if err := param.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("action %q: param %q: %w", a.Action, param.Name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // Prepare prepares the action config.
@@ -25,6 +73,36 @@ func (a *ActionConfig) Prepare() {
 	// Nothing to prepare yet
 }
 
+// Custom returns true for the actions declared with Member or Collection.
+func (a *ActionConfig) Custom() bool {
+	return a.Method != ""
+}
+
+// Path returns the path of a custom action: "/posts/{id}/publish" for a
+// member action, "/posts/search" for a collection one. Member actions of
+// singular resources have no id.
+func (a *ActionConfig) Path() string {
+	r := a.Resource
+	switch {
+	case !a.Member:
+		return r.Path() + "/" + a.Action
+	case r.Singular:
+		return r.MemberPath() + "/" + a.Action
+	default:
+		return r.MemberPath() + "/{id}/" + a.Action
+	}
+}
+
+// Param returns a parameter by name.
+func (a *ActionConfig) Param(name string) *ParamExpr {
+	for _, param := range a.Params {
+		if param.Name == name {
+			return param
+		}
+	}
+	return nil
+}
+
 // ParamExpr represents a query parameter.
 type ParamExpr struct {
 	// Name is the parameter name
@@ -39,6 +117,24 @@ type ParamExpr struct {
 	Description string
 }
 
+// EvalName returns the name of the parameter.
+func (p *ParamExpr) EvalName() string {
+	return p.Name
+}
+
+// Validate validates the parameter expression.
+func (p *ParamExpr) Validate() error {
+	if p.Name == "" {
+		return &ValidationError{Message: "param name cannot be empty"}
+	}
+	if p.Default != nil {
+		if err := checkValue(p.Type, p.Default); err != nil {
+			return &ValidationError{Message: "default value: " + err.Error()}
+		}
+	}
+	return nil
+}
+
 // ResourceExpr represents a RESTful resource.
 type ResourceExpr struct {
 	// Name is the resource name (e.g., "posts").
@@ -49,6 +145,12 @@ type ResourceExpr struct {
 	Actions []string
 	// Parent resource for nested resources.
 	Parent *ResourceExpr
+	// BelongsTo names the parent of a resource declared at the top level,
+	// such as "post", resolved to Parent when the app is prepared.
+	BelongsTo string
+	// Shallow is true for resources with a BelongsTo parent: their
+	// collection routes are nested in the parent, not their member routes.
+	Shallow bool
 	// Auth requirements.
 	AuthRequirements map[string][]string // action -> requirements
 	// Pagination settings.
@@ -67,6 +169,22 @@ type ResourceExpr struct {
 	Singular bool
 	// Action configurations
 	ActionConfigs map[string]*ActionConfig
+	// Model is the record behind the resource, if defined
+	Model *ModelExpr
+	// Middleware wraps the handlers of all actions of the resource
+	Middleware []string
+	// Formats the actions respond in, the first being the default
+	// (default: html)
+	Formats []string
+	// CustomActions lists the actions declared with Member and Collection
+	// in declaration order. Their configurations are in ActionConfigs.
+	CustomActions []string
+	// AuthExcept holds the actions excluded by Auth(...).Except for each
+	// requirement, which also applies to custom actions.
+	AuthExcept map[string][]string
+	// AuthAll holds the requirements of Auth calls narrowed with neither
+	// Except nor Only, which apply to every action, custom ones included.
+	AuthAll []string
 }
 
 // EvalName returns the name of the resource.
@@ -83,7 +201,10 @@ func (r *ResourceExpr) DSL() func() {
 func (r *ResourceExpr) Prepare() {
 	// Set default actions if not specified
 	if len(r.Actions) == 0 {
-		r.Actions = []string{"index", "show", "new", "create", "edit", "update", "destroy"}
+		r.Actions = slices.Clone(restfulActions)
+	}
+	if len(r.Formats) == 0 {
+		r.Formats = []string{FormatHTML}
 	}
 
 	// Initialize maps if needed
@@ -108,6 +229,79 @@ func (r *ResourceExpr) Prepare() {
 	if r.CustomForms == nil {
 		r.CustomForms = make(map[string]string)
 	}
+
+	// Prepare forms defined within the resource
+	for _, form := range r.Forms {
+		form.Prepare()
+	}
+
+	if r.Model != nil {
+		r.Model.Prepare()
+	}
+	r.prepareForeignKey()
+
+	r.prepareIndexParams()
+	r.prepareCustomAuth()
+}
+
+// prepareCustomAuth adds the requirements of Auth and Auth(...).Except to
+// the custom actions they do not exclude, wherever they were declared.
+func (r *ResourceExpr) prepareCustomAuth() {
+	for _, requirement := range r.AuthAll {
+		for _, action := range r.CustomActions {
+			if !slices.Contains(r.AuthRequirements[action], requirement) {
+				r.AuthRequirements[action] = append(r.AuthRequirements[action], requirement)
+			}
+		}
+	}
+	for _, requirement := range slices.Sorted(maps.Keys(r.AuthExcept)) {
+		for _, action := range r.CustomActions {
+			if slices.Contains(r.AuthExcept[requirement], action) || slices.Contains(r.AuthRequirements[action], requirement) {
+				continue
+			}
+			r.AuthRequirements[action] = append(r.AuthRequirements[action], requirement)
+		}
+	}
+}
+
+// prepareIndexParams adds the index params implied by Searchable, Filterable
+// and Paginate. Params declared explicitly with Params are kept as is.
+func (r *ResourceExpr) prepareIndexParams() {
+	var implied []*ParamExpr
+	if fields := r.SearchableFields["index"]; len(fields) > 0 {
+		implied = append(implied, &ParamExpr{
+			Name:        ParamSearch,
+			Type:        String,
+			Description: "Search term",
+		})
+	}
+	for _, field := range r.FilterableFields["index"] {
+		implied = append(implied, &ParamExpr{
+			Name:        field,
+			Type:        String,
+			Description: "Filter by " + field,
+		})
+	}
+	if perPage := r.Pagination["index"]; perPage > 0 {
+		implied = append(implied,
+			&ParamExpr{Name: ParamPage, Type: Int, Default: 1, Description: "Page number"},
+			&ParamExpr{Name: ParamPerPage, Type: Int, Default: perPage, Max: perPage, Description: "Results per page"},
+		)
+	}
+	if len(implied) == 0 {
+		return
+	}
+
+	config, ok := r.ActionConfigs["index"]
+	if !ok {
+		config = &ActionConfig{Action: "index", Resource: r}
+		r.ActionConfigs["index"] = config
+	}
+	for _, param := range implied {
+		if config.Param(param.Name) == nil {
+			config.Params = append(config.Params, param)
+		}
+	}
 }
 
 // Validate validates the resource expression.
@@ -117,29 +311,217 @@ func (r *ResourceExpr) Validate() error {
 	}
 
 	// Validate action names
-	validActions := map[string]bool{
-		"index": true, "show": true, "new": true, "create": true,
-		"edit": true, "update": true, "destroy": true,
-	}
-
 	for _, action := range r.Actions {
-		if !validActions[action] {
+		if !slices.Contains(restfulActions, action) {
 			return &ValidationError{
 				Message: "invalid action: " + action,
 			}
 		}
 	}
 
-	return nil
+	var errs []error
+	errs = append(errs, r.validateCustomActions()...)
+
+	seen := make(map[string]bool)
+	for _, format := range r.Formats {
+		switch {
+		case format != FormatHTML && format != FormatJSON:
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: invalid format %q, must be %q or %q", r.Name, format, FormatHTML, FormatJSON),
+			})
+		case seen[format]:
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: format %q is declared twice", r.Name, format),
+			})
+		}
+		seen[format] = true
+	}
+
+	// Validate forms defined within the resource
+	names := make([]string, 0, len(r.Forms))
+	for name := range r.Forms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := r.Forms[name].Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Validate action configurations and their params
+	actions := make([]string, 0, len(r.ActionConfigs))
+	for action := range r.ActionConfigs {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for _, action := range actions {
+		config := r.ActionConfigs[action]
+		if err := config.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if len(config.Middleware) > 0 && !r.HasAction(action) {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: action %q uses middleware but is not an action of the resource", r.Name, action),
+			})
+		}
+	}
+
+	if r.Model != nil {
+		if err := r.Model.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if fk := r.ForeignKey(); fk != nil && fk.Type != r.Parent.keyType() {
+		errs = append(errs, &ValidationError{
+			Message: fmt.Sprintf("resource %q: foreign key %q must be of type %s like the ids of %q",
+				r.Name, fk.Name, r.Parent.keyType().Name(), r.Parent.Name),
+		})
+	}
+
+	errs = append(errs, r.validateIndexOptions()...)
+
+	return errors.Join(errs...)
+}
+
+// validateCustomActions checks the names and methods of the actions
+// declared with Member and Collection.
+func (r *ResourceExpr) validateCustomActions() []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, action := range r.CustomActions {
+		config := r.ActionConfigs[action]
+		switch {
+		case !actionNamePattern.MatchString(action):
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: invalid custom action name %q, use lowercase letters, digits and underscores", r.Name, action),
+			})
+		case slices.Contains(restfulActions, action):
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: custom action %q conflicts with the RESTful action of the same name", r.Name, action),
+			})
+		case seen[action]:
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: custom action %q is declared twice", r.Name, action),
+			})
+		}
+		seen[action] = true
+		if !slices.Contains(customActionMethods, config.Method) {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: custom action %q has invalid method %q, must be one of %s",
+					r.Name, action, config.Method, strings.Join(customActionMethods, ", ")),
+			})
+		}
+		if r.Singular && !config.Member {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: singular resources have no collection, declare %q with Member", r.Name, action),
+			})
+		}
+	}
+	return errs
+}
+
+// validateIndexOptions checks the Searchable, Filterable and Paginate
+// settings of the index action.
+func (r *ResourceExpr) validateIndexOptions() []error {
+	var errs []error
+
+	if perPage, ok := r.Pagination["index"]; ok && perPage <= 0 {
+		errs = append(errs, &ValidationError{
+			Message: fmt.Sprintf("resource %q: Paginate requires a positive page size, got %d", r.Name, perPage),
+		})
+	}
+	if config, ok := r.ActionConfigs["index"]; ok {
+		if r.Pagination["index"] > 0 {
+			for _, name := range []string{ParamPage, ParamPerPage} {
+				if param := config.Param(name); param != nil && param.Type != Int {
+					errs = append(errs, &ValidationError{
+						Message: fmt.Sprintf("resource %q: param %q must be an Int when the index is paginated", r.Name, name),
+					})
+				}
+			}
+		}
+		var names []string
+		if len(r.SearchableFields["index"]) > 0 {
+			names = append(names, ParamSearch)
+		}
+		names = append(names, r.FilterableFields["index"]...)
+		for _, name := range names {
+			if param := config.Param(name); param != nil && param.Type != String {
+				errs = append(errs, &ValidationError{
+					Message: fmt.Sprintf("resource %q: param %q must be a String to search or filter by it", r.Name, name),
+				})
+			}
+		}
+	}
+
+	// Without a model there is nothing to check the field names against
+	if r.Model == nil {
+		return errs
+	}
+	for _, name := range r.SearchableFields["index"] {
+		field := r.Model.Field(name)
+		if field == nil {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: searchable field %q is not a field of model %q", r.Name, name, r.Model.Name),
+			})
+		} else if field.Type.Kind() != StringKind {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: searchable field %q must be a String", r.Name, name),
+			})
+		}
+	}
+	for _, name := range r.FilterableFields["index"] {
+		field := r.Model.Field(name)
+		if field == nil {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: filterable field %q is not a field of model %q", r.Name, name, r.Model.Name),
+			})
+		} else if kind := field.Type.Kind(); kind == ArrayKind || kind == MapKind {
+			errs = append(errs, &ValidationError{
+				Message: fmt.Sprintf("resource %q: filterable field %q must be a scalar", r.Name, name),
+			})
+		}
+	}
+
+	return errs
 }
 
-// HasAction returns true if the resource has the specified action.
+// HasAction returns true if the resource has the specified action, RESTful
+// or custom.
 func (r *ResourceExpr) HasAction(action string) bool {
 	for _, a := range r.Actions {
 		if a == action {
 			return true
 		}
 	}
+	return slices.Contains(r.CustomActions, action)
+}
+
+// ActionNames returns the RESTful actions of the resource followed by its
+// custom actions.
+func (r *ResourceExpr) ActionNames() []string {
+	return append(slices.Clone(r.Actions), r.CustomActions...)
+}
+
+// CustomAction returns the configuration of the custom action with the
+// given name, or nil if the resource has none.
+func (r *ResourceExpr) CustomAction(name string) *ActionConfig {
+	if !slices.Contains(r.CustomActions, name) {
+		return nil
+	}
+	return r.ActionConfigs[name]
+}
+
+// HasFormat returns true if the resource responds in the specified format.
+func (r *ResourceExpr) HasFormat(format string) bool {
+	for _, f := range r.Formats {
+		if f == format {
+			return true
+		}
+	}
 	return false
 }
 
@@ -160,7 +542,7 @@ func (r *ResourceExpr) NewFormName() string {
 		return form
 	}
 	// Convention: New{Resource}Form
-	return "New" + capitalize(r.Name) + "Form"
+	return "New" + r.typeName() + "Form"
 }
 
 // EditFormName returns the form name for the edit/update actions.
@@ -180,7 +562,155 @@ func (r *ResourceExpr) EditFormName() string {
 		return form
 	}
 	// Convention: Edit{Resource}Form
-	return "Edit" + capitalize(r.Name) + "Form"
+	return "Edit" + r.typeName() + "Form"
+}
+
+// Ancestors returns the resources in the paths of the collection routes of
+// a nested resource, outermost first. Paths start at the first shallow
+// ancestor, whose member routes are not nested. It is empty for top-level
+// resources.
+func (r *ResourceExpr) Ancestors() []*ResourceExpr {
+	if r.Parent == nil {
+		return nil
+	}
+	var ancestors []*ResourceExpr
+	if !r.Parent.Shallow {
+		ancestors = r.Parent.Ancestors()
+	}
+	return append(ancestors, r.Parent)
+}
+
+// QualifiedName returns the name of the resource prefixed with the singular
+// names of its ancestors, such as "post_comments" for comments nested in
+// posts. Generated files and views use it so that nested resources do not
+// collide with resources of the same name elsewhere. Shallow resources are
+// declared at the top level and keep their name.
+func (r *ResourceExpr) QualifiedName() string {
+	if r.Parent == nil || r.Shallow {
+		return r.Name
+	}
+	return singularize(r.Parent.QualifiedName()) + "_" + r.Name
+}
+
+// IDParam returns the path parameter holding the id of the resource in the
+// paths of the resources nested in it, such as "post_id". It is also the
+// name of the foreign key of their models.
+func (r *ResourceExpr) IDParam() string {
+	return singularize(r.Name) + "_id"
+}
+
+// ParentParams returns the id path parameters of the ancestors of the
+// resource, outermost first. Singular ancestors have no id.
+func (r *ResourceExpr) ParentParams() []string {
+	var params []string
+	for _, ancestor := range r.Ancestors() {
+		if !ancestor.Singular {
+			params = append(params, ancestor.IDParam())
+		}
+	}
+	return params
+}
+
+// Path returns the base path of the collection routes of the resource, such
+// as "/posts" or "/posts/{post_id}/comments" for comments nested in posts.
+// Singular resources drop a trailing "s": "/profile".
+func (r *ResourceExpr) Path() string {
+	if r.Parent == nil {
+		return "/" + r.pathName()
+	}
+	path := r.Parent.MemberPath()
+	if !r.Parent.Singular {
+		path += "/{" + r.Parent.IDParam() + "}"
+	}
+	return path + "/" + r.pathName()
+}
+
+// MemberPath returns the base path of the member routes of the resource,
+// which add the id: Path, except for shallow resources whose members are
+// mounted at the top level, such as "/comments" for /comments/{id}.
+func (r *ResourceExpr) MemberPath() string {
+	if r.Shallow {
+		return "/" + r.pathName()
+	}
+	return r.Path()
+}
+
+// pathName returns the path segment of the resource.
+func (r *ResourceExpr) pathName() string {
+	if r.Singular {
+		return strings.TrimSuffix(r.Name, "s")
+	}
+	return r.Name
+}
+
+// nestedIn returns true if the resource is other or is nested in it.
+func (r *ResourceExpr) nestedIn(other *ResourceExpr) bool {
+	for ; r != nil; r = r.Parent {
+		if r == other {
+			return true
+		}
+	}
+	return false
+}
+
+// ForeignKey returns the field of the model of a nested resource holding
+// the id of its parent, or nil if there is none.
+func (r *ResourceExpr) ForeignKey() *AttributeExpr {
+	if r.Model == nil || r.Parent == nil || r.Parent.Singular {
+		return nil
+	}
+	return r.Model.Field(r.Parent.IDParam())
+}
+
+// prepareForeignKey adds the foreign key of the parent to the model of a
+// nested resource, after its primary key, unless the model declares it. It
+// has the type of the primary key of the parent model, String without one.
+func (r *ResourceExpr) prepareForeignKey() {
+	if r.Model == nil || r.Parent == nil || r.Parent.Singular || r.ForeignKey() != nil {
+		return
+	}
+	fk := &AttributeExpr{Name: r.Parent.IDParam(), Type: r.Parent.keyType()}
+	fk.Prepare()
+	fields := slices.Clone(r.Model.Fields)
+	at := slices.IndexFunc(fields, (*AttributeExpr).IsPrimaryKey) + 1
+	r.Model.Fields = slices.Insert(fields, at, fk)
+}
+
+// keyType returns the type of the ids of the resource: the type of the
+// primary key of its model, String without a model.
+func (r *ResourceExpr) keyType() DataType {
+	if r.Model == nil {
+		return String
+	}
+	if pk := r.Model.PrimaryKey(); pk != nil {
+		return pk.Type
+	}
+	if id := r.Model.Field("id"); id != nil && id.Type != nil {
+		return id.Type
+	}
+	return Int64
+}
+
+// typeName returns the resource name used in the names of its types, such as
+// "Posts", or "PostComments" for comments nested in posts.
+func (r *ResourceExpr) typeName() string {
+	if r.Parent == nil || r.Shallow {
+		return capitalize(r.Name)
+	}
+	return singularize(r.Parent.typeName()) + capitalize(r.Name)
+}
+
+// singularize returns the singular form of a plural resource name.
+func singularize(plural string) string {
+	switch {
+	case strings.HasSuffix(plural, "ies"):
+		return strings.TrimSuffix(plural, "ies") + "y"
+	case strings.HasSuffix(plural, "ses"), strings.HasSuffix(plural, "xes"), strings.HasSuffix(plural, "zes"),
+		strings.HasSuffix(plural, "ches"), strings.HasSuffix(plural, "shes"):
+		return strings.TrimSuffix(plural, "es")
+	default:
+		return strings.TrimSuffix(plural, "s")
+	}
 }
 
 // capitalize capitalizes the first letter of a string.
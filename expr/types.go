@@ -1,6 +1,16 @@
 package expr
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Primitive types
 var (
@@ -61,6 +71,79 @@ func (m *MapType) Kind() TypeKind {
 	return MapKind
 }
 
+// checkValue checks that a design-time value, such as a default, can be
+// assigned to a field of the given type.
+func checkValue(dataType DataType, value interface{}) error {
+	if dataType == nil {
+		dataType = String
+	}
+	invalid := &ValidationError{
+		Message: fmt.Sprintf("%#v is not a valid %s", value, dataType.Name()),
+	}
+
+	switch dataType.Kind() {
+	case BooleanKind:
+		if _, ok := value.(bool); !ok {
+			return invalid
+		}
+	case IntKind:
+		n, ok := toInt(value)
+		if !ok {
+			return invalid
+		}
+		if dataType == Int32 && (n < math.MinInt32 || n > math.MaxInt32) {
+			return &ValidationError{
+				Message: fmt.Sprintf("%d overflows %s", n, dataType.Name()),
+			}
+		}
+	case FloatKind:
+		if _, ok := toFloat(value); !ok {
+			return invalid
+		}
+	case StringKind:
+		if _, ok := value.(string); !ok {
+			return invalid
+		}
+	case BytesKind:
+		switch value.(type) {
+		case string, []byte:
+		default:
+			return invalid
+		}
+	case ArrayKind:
+		arrayType, ok := dataType.(*ArrayType)
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return invalid
+		}
+		if ok {
+			for i := 0; i < rv.Len(); i++ {
+				if err := checkValue(arrayType.ElemType, rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	case MapKind:
+		mapType, ok := dataType.(*MapType)
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map {
+			return invalid
+		}
+		if ok {
+			iter := rv.MapRange()
+			for iter.Next() {
+				if err := checkValue(mapType.KeyType, iter.Key().Interface()); err != nil {
+					return err
+				}
+				if err := checkValue(mapType.ElemType, iter.Value().Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // LayoutExpr represents a layout definition.
 type LayoutExpr struct {
 	// Name is the layout name.
@@ -161,10 +244,47 @@ func (f *FormatValidation) Name() string {
 
 // Validate checks if the value matches the format.
 func (f *FormatValidation) Validate(value interface{}) error {
-	// TODO: Implement format validation (email, date, etc.)
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return nil
+	}
+
+	valid := true
+	switch f.Format {
+	case FormatEmail:
+		_, err := mail.ParseAddress(s)
+		valid = err == nil
+	case FormatURL:
+		u, err := url.Parse(s)
+		valid = err == nil && u.Scheme != "" && u.Host != ""
+	case FormatDate:
+		_, err := time.Parse(time.DateOnly, s)
+		valid = err == nil
+	case FormatDateTime:
+		valid = false
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+			if _, err := time.Parse(layout, s); err == nil {
+				valid = true
+				break
+			}
+		}
+	case FormatUUID:
+		valid = uuidPattern.MatchString(s)
+	default:
+		return &ValidationError{Message: "unknown format: " + f.Format}
+	}
+
+	if !valid {
+		return &ValidationError{
+			Message: fmt.Sprintf("value %q is not a valid %s", s, f.Format),
+		}
+	}
 	return nil
 }
 
+// uuidPattern matches canonical hyphenated UUIDs.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 // PatternValidation validates a value against a regex pattern.
 type PatternValidation struct {
 	Pattern string
@@ -177,10 +297,34 @@ func (p *PatternValidation) Name() string {
 
 // Validate checks if the value matches the pattern.
 func (p *PatternValidation) Validate(value interface{}) error {
-	// TODO: Implement pattern validation
+	re, err := p.Compile()
+	if err != nil {
+		return err
+	}
+
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	if !re.MatchString(s) {
+		return &ValidationError{
+			Message: fmt.Sprintf("value %q does not match pattern %s", s, p.Pattern),
+		}
+	}
 	return nil
 }
 
+// Compile compiles the pattern with Go's RE2 syntax.
+func (p *PatternValidation) Compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return nil, &ValidationError{
+			Message: fmt.Sprintf("invalid pattern %q: %v", p.Pattern, err),
+		}
+	}
+	return re, nil
+}
+
 // EnumValidation validates a value is one of the allowed values.
 type EnumValidation struct {
 	Values []string
@@ -193,8 +337,69 @@ func (e *EnumValidation) Name() string {
 
 // Validate checks if the value is in the allowed list.
 func (e *EnumValidation) Validate(value interface{}) error {
-	// TODO: Implement enum validation
-	return nil
+	if value == nil {
+		return nil
+	}
+
+	s := fmt.Sprint(value)
+	if s == "" {
+		return nil
+	}
+	for _, v := range e.Values {
+		if v == s {
+			return nil
+		}
+	}
+	return &ValidationError{
+		Message: fmt.Sprintf("value %q must be one of: %s", s, strings.Join(e.Values, ", ")),
+	}
+}
+
+// Check checks the values can be enforced on an attribute of the given type.
+// Enums apply to strings, numbers and lists of strings. Numeric values must be
+// written the way the number is formatted, such as "2" rather than "02".
+func (e *EnumValidation) Check(dataType DataType) error {
+	if arrayType, ok := dataType.(*ArrayType); ok {
+		if arrayType.ElemType.Kind() != StringKind {
+			return &ValidationError{Message: "enum requires a string, numeric or string list attribute"}
+		}
+		return nil
+	}
+
+	switch dataType.Kind() {
+	case StringKind:
+		return nil
+	case IntKind, FloatKind:
+		for _, v := range e.Values {
+			if formatted, ok := formatNumber(dataType, v); !ok || formatted != v {
+				return &ValidationError{
+					Message: fmt.Sprintf("enum value %q is not a valid %s", v, dataType.Name()),
+				}
+			}
+		}
+		return nil
+	default:
+		return &ValidationError{Message: "enum requires a string, numeric or string list attribute"}
+	}
+}
+
+// formatNumber parses s as a number of the given type and formats it back
+// with fmt.Sprint, as generated code does.
+func formatNumber(dataType DataType, s string) (string, bool) {
+	switch dataType {
+	case Int32:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return fmt.Sprint(int32(n)), err == nil
+	case Float32:
+		f, err := strconv.ParseFloat(s, 32)
+		return fmt.Sprint(float32(f)), err == nil
+	case Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		return fmt.Sprint(f), err == nil
+	default:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return fmt.Sprint(n), err == nil
+	}
 }
 
 // MinValidation validates a minimum numeric value.
@@ -209,7 +414,15 @@ func (m *MinValidation) Name() string {
 
 // Validate checks if the value meets the minimum.
 func (m *MinValidation) Validate(value interface{}) error {
-	// TODO: Implement min validation
+	n, ok := toFloat(value)
+	if !ok {
+		return nil
+	}
+	if n < float64(m.Min) {
+		return &ValidationError{
+			Message: fmt.Sprintf("value %v is less than minimum of %d", value, m.Min),
+		}
+	}
 	return nil
 }
 
@@ -225,10 +438,148 @@ func (m *MaxValidation) Name() string {
 
 // Validate checks if the value meets the maximum.
 func (m *MaxValidation) Validate(value interface{}) error {
-	// TODO: Implement max validation
+	n, ok := toFloat(value)
+	if !ok {
+		return nil
+	}
+	if n > float64(m.Max) {
+		return &ValidationError{
+			Message: fmt.Sprintf("value %v exceeds maximum of %d", value, m.Max),
+		}
+	}
+	return nil
+}
+
+// Built-in custom validation rules. Any other rule name is a hook that the
+// application implements.
+const (
+	// RuleMatches requires the value to equal another attribute.
+	RuleMatches = "matches"
+	// RuleAccepted requires a boolean attribute to be true.
+	RuleAccepted = "accepted"
+)
+
+// ruleNamePattern matches valid custom validation rule names.
+var ruleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// CustomValidation is a named validation declared with dsl.Validation.
+// Built-in rules map to runtime.Validator methods; other rules generate
+// hook methods on a per-form validator interface.
+type CustomValidation struct {
+	// Rule is the rule name, e.g. "matches" or "unique".
+	Rule string
+	// Args are the rule arguments, e.g. the attribute "matches" compares to.
+	Args []string
+}
+
+// Name returns the validation name.
+func (c *CustomValidation) Name() string {
+	return "custom"
+}
+
+// Validate does nothing: custom rules are only checked at runtime.
+func (c *CustomValidation) Validate(value interface{}) error {
+	return nil
+}
+
+// IsHook returns true if the rule is implemented by the application
+// rather than built in.
+func (c *CustomValidation) IsHook() bool {
+	return c.Rule != RuleMatches && c.Rule != RuleAccepted
+}
+
+// Check checks the rule is well formed for an attribute of the given type.
+func (c *CustomValidation) Check(dataType DataType) error {
+	if !ruleNamePattern.MatchString(c.Rule) {
+		return &ValidationError{
+			Message: fmt.Sprintf("invalid validation name %q: use lower_snake_case", c.Rule),
+		}
+	}
+
+	switch c.Rule {
+	case RuleMatches:
+		if len(c.Args) != 1 {
+			return &ValidationError{Message: `validation "matches" takes the name of one attribute`}
+		}
+		if dataType != nil && dataType.Kind() != StringKind {
+			return &ValidationError{Message: `validation "matches" requires a string attribute`}
+		}
+	case RuleAccepted:
+		if len(c.Args) != 0 {
+			return &ValidationError{Message: `validation "accepted" takes no arguments`}
+		}
+		if dataType == nil || dataType.Kind() != BooleanKind {
+			return &ValidationError{Message: `validation "accepted" requires a boolean attribute`}
+		}
+	default:
+		if len(c.Args) != 0 {
+			return &ValidationError{
+				Message: fmt.Sprintf("custom validation %q takes no arguments", c.Rule),
+			}
+		}
+	}
 	return nil
 }
 
+// toInt converts an integer value to int64. Floats are rejected so that
+// a default such as 1.5 is never silently truncated.
+func toInt(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), uint64(n) <= math.MaxInt64
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	}
+	return 0, false
+}
+
+// toFloat converts a numeric value to float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
 // Common formats
 const (
 	FormatEmail    = "email"
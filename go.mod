@@ -6,4 +6,4 @@ toolchain go1.24.6
 
 require golang.org/x/mod v0.27.0
 
-require golang.org/x/text v0.28.0 // indirect
+require golang.org/x/text v0.28.0
@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrUnauthenticated is returned by an Authorizer when the request has no
+// authenticated user. It is answered with 401, or a redirect to the login
+// page.
+var ErrUnauthenticated = errors.New("authentication required")
+
+// ErrForbidden is returned by an Authorizer when the user may not perform
+// the action. Any error other than ErrUnauthenticated is answered with 403.
+var ErrForbidden = errors.New("forbidden")
+
+// Authorizer checks the Auth requirements of generated routes.
+type Authorizer interface {
+	// Authorize returns nil if the request meets requirement for the action
+	// of resource. Pages are checked with the resource "pages" and the page
+	// name as action.
+	Authorize(r *http.Request, requirement string, resource string, action string) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(r *http.Request, requirement string, resource string, action string) error
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(r *http.Request, requirement string, resource string, action string) error {
+	return f(r, requirement, resource, action)
+}
+
+// AuthPolicy decides how failed authorization is answered. The zero value
+// responds with 401 or 403, as a problem document to JSON requests.
+type AuthPolicy struct {
+	// LoginURL, if set, is where unauthenticated GET and HEAD requests are
+	// redirected, unless they are answered in JSON. The requested URL is
+	// passed in the return_to query parameter.
+	LoginURL string
+	// OnDenied, if set, writes the response instead of a plain status
+	// error. status is 401 or 403 and err is the Authorizer error.
+	OnDenied func(w http.ResponseWriter, r *http.Request, status int, err error)
+}
+
+// RequireAuth returns a handler that calls next only if the authorizer
+// grants every requirement for the action of resource.
+func RequireAuth(a Authorizer, policy AuthPolicy, resource, action string, requirements []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, requirement := range requirements {
+			if err := a.Authorize(r, requirement, resource, action); err != nil {
+				policy.deny(w, r, err)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// deny answers a request that failed authorization.
+func (p AuthPolicy) deny(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusForbidden
+	if errors.Is(err, ErrUnauthenticated) {
+		status = http.StatusUnauthorized
+		if p.LoginURL != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) && !WantsJSON(r) {
+			http.Redirect(w, r, loginRedirect(p.LoginURL, r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+	}
+
+	if p.OnDenied != nil {
+		p.OnDenied(w, r, status, err)
+		return
+	}
+	if WantsJSON(r) {
+		WriteProblem(w, Problem{Status: status})
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// loginRedirect appends the return_to parameter to the login URL.
+func loginRedirect(loginURL, returnTo string) string {
+	sep := "?"
+	if strings.Contains(loginURL, "?") {
+		sep = "&"
+	}
+	return loginURL + sep + "return_to=" + url.QueryEscape(returnTo)
+}
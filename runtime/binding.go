@@ -0,0 +1,297 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// maxJSONBody is the size limit of JSON bodies read by RequestValues, that
+// of url-encoded bodies read by ParseForm.
+const maxJSONBody = 10 << 20
+
+// RequestValues returns the submitted values of r: its form and query
+// values, or for a JSON body the members of its object followed by the
+// query values. JSON arrays become repeated keys and objects "name[key]"
+// keys, so that both are decoded and validated as form input.
+func RequestValues(r *http.Request) (url.Values, error) {
+	if !HasJSONBody(r) {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return r.Form, nil
+	}
+
+	var body map[string]any
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxJSONBody))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	values := url.Values{}
+	for name, value := range body {
+		if fields, ok := value.(map[string]any); ok {
+			for key, value := range fields {
+				if err := addJSONValue(values, name+"["+key+"]", value); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := addJSONValue(values, name, value); err != nil {
+			return nil, err
+		}
+	}
+	for name, query := range r.URL.Query() {
+		values[name] = append(values[name], query...)
+	}
+	return values, nil
+}
+
+// HasJSONBody returns true if the body of r is JSON, as declared by its
+// Content-Type.
+func HasJSONBody(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// addJSONValue adds the form values of a JSON member: nothing for null,
+// the text of a scalar, and a value per element of an array of scalars.
+func addJSONValue(values url.Values, name string, value any) error {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		values.Add(name, v)
+	case json.Number:
+		values.Add(name, v.String())
+	case bool:
+		values.Add(name, fmt.Sprint(v))
+	case []any:
+		// An empty array is kept as a key without values, so that it
+		// counts as submitted
+		if _, ok := values[name]; !ok {
+			values[name] = []string{}
+		}
+		for _, element := range v {
+			if _, ok := element.([]any); ok {
+				return fmt.Errorf("invalid JSON body: %s holds nested arrays", name)
+			}
+			if _, ok := element.(map[string]any); ok {
+				return fmt.Errorf("invalid JSON body: %s holds objects", name)
+			}
+			if err := addJSONValue(values, name, element); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid JSON body: %s holds nested objects", name)
+	}
+	return nil
+}
+
+// BindValues populates dest from url.Values using `form:"..."` struct tags.
+//
+// dest must be a non-nil pointer to a struct. Fields without a form tag use
+// their Go name; fields tagged `form:"-"` are skipped. Slices are filled from
+// repeated keys or from "name[]" keys, and maps from "name[key]" keys.
+// Conversion failures are collected and returned as ValidationErrors keyed by
+// the form field name.
+func BindValues(values url.Values, dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("bind destination must be a non-nil pointer to a struct")
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bind destination must be a pointer to a struct, got %s", rv.Kind())
+	}
+
+	var errs ValidationErrors
+	bindStruct(values, rv, &errs)
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// bindStruct binds every exported field of a struct value.
+func bindStruct(values url.Values, rv reflect.Value, errs *ValidationErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := formFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		if err := bindField(values, name, rv.Field(i)); err != nil {
+			*errs = append(*errs, ValidationError{
+				Field:   name,
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+// formFieldName returns the form key for a struct field.
+func formFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("form")
+	if tag == "" {
+		return field.Name
+	}
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		tag = tag[:idx]
+	}
+	if tag == "" {
+		return field.Name
+	}
+	return tag
+}
+
+// bindField binds a single field. Missing keys leave the field untouched.
+func bindField(values url.Values, name string, fv reflect.Value) error {
+	switch fv.Kind() {
+	case reflect.Slice:
+		// []byte is a scalar, not a repeated value
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			raw, ok := firstValue(values, name)
+			if !ok {
+				return nil
+			}
+			fv.SetBytes([]byte(raw))
+			return nil
+		}
+		return bindSlice(values, name, fv)
+	case reflect.Map:
+		return bindMap(values, name, fv)
+	case reflect.Bool:
+		// Checkboxes are commonly paired with a hidden "false" input placed
+		// before them, so the last submitted value wins.
+		raws := values[name]
+		if len(raws) == 0 {
+			return nil
+		}
+		return setScalar(fv, raws[len(raws)-1])
+	default:
+		raw, ok := firstValue(values, name)
+		if !ok {
+			return nil
+		}
+		return setScalar(fv, raw)
+	}
+}
+
+// bindSlice binds repeated keys ("tags=a&tags=b") or bracket keys ("tags[]=a").
+func bindSlice(values url.Values, name string, fv reflect.Value) error {
+	slice := reflect.MakeSlice(fv.Type(), 0, len(values[name])+len(values[name+"[]"]))
+	for _, raw := range append(append([]string{}, values[name]...), values[name+"[]"]...) {
+		// Blank inputs, such as the empty one after a list, add no element
+		if raw == "" {
+			continue
+		}
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if err := setScalar(elem, raw); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	if slice.Len() == 0 {
+		return nil
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// bindMap binds bracket keys ("settings[theme]=dark").
+func bindMap(values url.Values, name string, fv reflect.Value) error {
+	prefix := name + "["
+	mt := fv.Type()
+
+	for key, raws := range values {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, "]") || len(raws) == 0 {
+			continue
+		}
+		sub := key[len(prefix) : len(key)-1]
+
+		mk := reflect.New(mt.Key()).Elem()
+		if err := setScalar(mk, sub); err != nil {
+			return fmt.Errorf("invalid key %q: %w", sub, err)
+		}
+		mv := reflect.New(mt.Elem()).Elem()
+		if err := setScalar(mv, raws[0]); err != nil {
+			return err
+		}
+
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(mt))
+		}
+		fv.SetMapIndex(mk, mv)
+	}
+	return nil
+}
+
+// firstValue returns the first value for a key, if present.
+func firstValue(values url.Values, name string) (string, bool) {
+	raws, ok := values[name]
+	if !ok || len(raws) == 0 {
+		return "", false
+	}
+	return raws[0], true
+}
+
+// setScalar converts raw into the kind of fv and assigns it.
+func setScalar(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseInt(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseUint(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := parseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		fv.SetBytes([]byte(raw))
+	case reflect.Interface:
+		if fv.NumMethod() != 0 {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(raw))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
@@ -3,15 +3,14 @@ package runtime
 import (
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"net/http"
-	"path/filepath"
-	"strings"
+
+	"github.com/gobijan/gluey/runtime/session"
 )
 
 // BaseController provides common functionality for all controllers.
 type BaseController struct {
-	templates *template.Template
+	views     *TemplateEngine
 	viewsPath string
 }
 
@@ -28,49 +27,59 @@ func (c *BaseController) LoadTemplates() error {
 		c.viewsPath = "gen/webapp/views"
 	}
 
-	pattern := filepath.Join(c.viewsPath, "**/*.html")
-	tmpl, err := template.ParseGlob(pattern)
-	if err != nil {
-		// Try with single level glob
-		pattern = filepath.Join(c.viewsPath, "*/*.html")
-		tmpl, err = template.ParseGlob(pattern)
-		if err != nil {
-			return fmt.Errorf("failed to load templates: %w", err)
-		}
+	views := NewTemplateEngine()
+	if err := views.LoadTemplates(c.viewsPath); err != nil {
+		return err
 	}
-
-	c.templates = tmpl
+	c.views = views
 	return nil
 }
 
-// Render renders an HTML template with the given data.
-func (c *BaseController) Render(w http.ResponseWriter, templateName string, data any) error {
-	if c.templates == nil {
-		if err := c.LoadTemplates(); err != nil {
-			http.Error(w, "Templates not loaded", http.StatusInternalServerError)
-			return err
-		}
+// Render renders a view inside the layout of the request, see the Render
+// function, with the templates of the views directory.
+func (c *BaseController) Render(w http.ResponseWriter, r *http.Request, view string, data any) error {
+	r, err := c.withViews(w, r)
+	if err != nil {
+		return err
 	}
+	return Render(w, r, view, data)
+}
 
-	// Ensure template name has .html extension
-	if !strings.HasSuffix(templateName, ".html") {
-		templateName = templateName + ".html"
+// Respond answers the request in its format, see the Respond function.
+func (c *BaseController) Respond(w http.ResponseWriter, r *http.Request, view string, data any) error {
+	// JSON answers need no templates
+	if !WantsJSON(r) {
+		var err error
+		if r, err = c.withViews(w, r); err != nil {
+			return err
+		}
 	}
+	return Respond(w, r, view, data)
+}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-
-	// Wrap data with flash messages if available
-	viewData := map[string]any{
-		"Data":  data,
-		"Flash": c.getFlash(w, nil),
+// RespondInvalid answers a request whose input failed validation, see the
+// RespondInvalid function.
+func (c *BaseController) RespondInvalid(w http.ResponseWriter, r *http.Request, view string, data map[string]any, errs ValidationErrors) error {
+	// JSON answers need no templates
+	if !WantsJSON(r) {
+		var err error
+		if r, err = c.withViews(w, r); err != nil {
+			return err
+		}
 	}
+	return RespondInvalid(w, r, view, data, errs)
+}
 
-	if err := c.templates.ExecuteTemplate(w, templateName, viewData); err != nil {
-		http.Error(w, "Template error", http.StatusInternalServerError)
-		return fmt.Errorf("failed to render template %s: %w", templateName, err)
+// withViews returns a copy of r rendered with the templates of the views
+// directory, loading them on first use.
+func (c *BaseController) withViews(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	if c.views == nil {
+		if err := c.LoadTemplates(); err != nil {
+			http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+			return nil, err
+		}
 	}
-
-	return nil
+	return WithViews(r, c.views), nil
 }
 
 // JSON sends a JSON response.
@@ -102,17 +111,16 @@ func (c *BaseController) RedirectBack(w http.ResponseWriter, r *http.Request, fa
 	http.Redirect(w, r, referer, http.StatusSeeOther)
 }
 
-// Bind binds form data to a struct.
+// Bind binds form and query data, or a JSON body, to a struct using its
+// `form` tags, see RequestValues. Conversion failures are returned as
+// ValidationErrors.
 func (c *BaseController) Bind(r *http.Request, dest any) error {
-	if err := r.ParseForm(); err != nil {
+	values, err := RequestValues(r)
+	if err != nil {
 		return fmt.Errorf("failed to parse form: %w", err)
 	}
 
-	// Simple form binding - in production, use a proper form binding library
-	// This is a basic implementation for the MVP
-	// TODO: Implement proper form binding with struct tags
-
-	return nil
+	return BindValues(values, dest)
 }
 
 // Param gets a URL parameter value.
@@ -136,45 +144,16 @@ func (c *BaseController) Params(r *http.Request) map[string]string {
 	return params
 }
 
-// Flash sets a flash message.
-func (c *BaseController) Flash(w http.ResponseWriter, r *http.Request, level, message string) {
-	cookie := &http.Cookie{
-		Name:     "flash_" + level,
-		Value:    message,
-		Path:     "/",
-		MaxAge:   0, // Session cookie
-		HttpOnly: true,
-	}
-	http.SetCookie(w, cookie)
+// Session returns the session of the request, installed by the session
+// manager of the router.
+func (c *BaseController) Session(r *http.Request) *session.Session {
+	return session.Get(r)
 }
 
-// getFlash retrieves and clears flash messages.
-func (c *BaseController) getFlash(w http.ResponseWriter, r *http.Request) map[string]string {
-	if r == nil {
-		return make(map[string]string)
-	}
-
-	flash := make(map[string]string)
-	levels := []string{"success", "error", "warning", "info"}
-
-	for _, level := range levels {
-		cookie, err := r.Cookie("flash_" + level)
-		if err == nil && cookie.Value != "" {
-			flash[level] = cookie.Value
-
-			// Clear the flash message
-			clearCookie := &http.Cookie{
-				Name:     "flash_" + level,
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			}
-			http.SetCookie(w, clearCookie)
-		}
-	}
-
-	return flash
+// Flash adds a flash message for the next request, such as the page a
+// successful form post redirects to.
+func (c *BaseController) Flash(w http.ResponseWriter, r *http.Request, level, message string) {
+	AddFlash(w, r, level, message)
 }
 
 // Error sends an error response.
@@ -0,0 +1,214 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CSRF names used by forms, scripts and the token cookie.
+const (
+	CSRFFieldName = "_csrf"
+	CSRFHeader    = "X-CSRF-Token"
+	CSRFCookie    = "_csrf"
+)
+
+// ErrCSRFOrigin is reported when an unsafe request comes from another site.
+var ErrCSRFOrigin = errors.New("cross-site request")
+
+// ErrCSRFToken is reported when an unsafe request lacks a valid CSRF token.
+var ErrCSRFToken = errors.New("invalid CSRF token")
+
+// csrfTokenLen is the number of random bytes of a CSRF token.
+const csrfTokenLen = 32
+
+// CSRFProtection rejects cross-site POST, PUT, PATCH and DELETE requests.
+// The browser's Sec-Fetch-Site or Origin header must name the same site,
+// and the request must send back CSRFToken in the _csrf form field or the
+// X-CSRF-Token header. Render the field with csrf_field. The _csrf cookie
+// holds the secret the token is checked against and is not readable by
+// scripts.
+//
+// API clients sending a JSON body without cookies need no token: they
+// carry no ambient credentials a forged request could use, and browsers
+// cannot send JSON to another site without a CORS preflight.
+type CSRFProtection struct {
+	// OnFailure, if set, writes the response to rejected requests, for
+	// example the application's 403 page. err is ErrCSRFOrigin or
+	// ErrCSRFToken. By default requests preferring JSON get a 403 problem
+	// document and the others a plain 403.
+	OnFailure func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// CSRF is CSRFProtection with the default 403 responses. It is the
+// built-in "CSRF" middleware.
+func CSRF(next http.Handler) http.Handler {
+	return CSRFProtection{}.Handler(next)
+}
+
+// csrfTokenKey is the context key of the CSRF token.
+type csrfTokenKey struct{}
+
+// csrfSkipKey is the context key marking requests exempt from CSRF checks.
+type csrfSkipKey struct{}
+
+// Handler is the CSRF middleware.
+func (p CSRFProtection) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := csrfCookieToken(r)
+		if token == nil {
+			// Set the cookie before the handler can start the response
+			token = make([]byte, csrfTokenLen)
+			rand.Read(token)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRFCookie,
+				Value:    base64.RawURLEncoding.EncodeToString(token),
+				Path:     "/",
+				Secure:   r.TLS != nil,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		r = r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token))
+
+		if !safeMethod(r.Method) && r.Context().Value(csrfSkipKey{}) == nil {
+			if err := checkCSRF(r, token); err != nil {
+				p.fail(w, r, err)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fail answers a rejected request.
+func (p CSRFProtection) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if p.OnFailure != nil {
+		p.OnFailure(w, r, err)
+		return
+	}
+	// Formats are negotiated by the routes, inside the middleware
+	if NegotiateFormat(r, FormatHTML, FormatJSON) == FormatJSON {
+		WriteProblem(w, Problem{Status: http.StatusForbidden, Detail: err.Error()})
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+// checkCSRF checks the origin and token of an unsafe request.
+func checkCSRF(r *http.Request, token []byte) error {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+	case "":
+		// Older browsers only send Origin
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r) {
+			return ErrCSRFOrigin
+		}
+	default:
+		return ErrCSRFOrigin
+	}
+
+	if HasJSONBody(r) && len(r.Cookies()) == 0 {
+		return nil
+	}
+	sent := r.Header.Get(CSRFHeader)
+	if sent == "" {
+		sent = r.PostFormValue(CSRFFieldName)
+	}
+	if !validCSRFToken(sent, token) {
+		return ErrCSRFToken
+	}
+	return nil
+}
+
+// sameOrigin reports whether origin is the host of the request.
+func sameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// safeMethod reports whether method cannot change state.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// csrfCookieToken returns the token of the _csrf cookie, or nil.
+func csrfCookieToken(r *http.Request) []byte {
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil {
+		return nil
+	}
+	token, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(token) != csrfTokenLen {
+		return nil
+	}
+	return token
+}
+
+// CSRFToken returns the token to send back with forms of the request, or an
+// empty string if the CSRF middleware is not used. Each call masks the
+// token differently so that it cannot be recovered from compressed pages.
+func CSRFToken(r *http.Request) string {
+	token, ok := r.Context().Value(csrfTokenKey{}).([]byte)
+	if !ok {
+		return ""
+	}
+	masked := make([]byte, 2*csrfTokenLen)
+	rand.Read(masked[:csrfTokenLen])
+	for i := range csrfTokenLen {
+		masked[csrfTokenLen+i] = masked[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validCSRFToken reports whether sent is a masked form of token.
+func validCSRFToken(sent string, token []byte) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(sent)
+	if err != nil || len(masked) != 2*csrfTokenLen {
+		return false
+	}
+	unmasked := make([]byte, csrfTokenLen)
+	for i := range csrfTokenLen {
+		unmasked[i] = masked[i] ^ masked[csrfTokenLen+i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
+
+// SkipCSRF returns a handler that exempts the requests matching any of
+// patterns on mux from CSRF checks, then calls next. patterns are the
+// patterns the routes were registered with, such as "POST /webhooks".
+func SkipCSRF(mux *http.ServeMux, next http.Handler, patterns ...string) http.Handler {
+	skip := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		skip[pattern] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); skip[pattern] {
+			r = r.WithContext(context.WithValue(r.Context(), csrfSkipKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRFField renders the hidden CSRF form field for token, as returned by
+// CSRFToken. It renders nothing without a token. It is the csrf_field
+// template function.
+func CSRFField(token string) template.HTML {
+	if token == "" {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + CSRFFieldName + `" value="` + template.HTMLEscapeString(token) + `">`)
+}
@@ -0,0 +1,269 @@
+package runtime
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FormDecoder decodes typed values from url.Values and collects conversion
+// errors. It backs the Bind and FromValues methods generated for form and
+// params types, so decoding stays compile-time typed without reflection.
+type FormDecoder struct {
+	values url.Values
+	errors ValidationErrors
+}
+
+// NewFormDecoder creates a decoder over the given values.
+func NewFormDecoder(values url.Values) *FormDecoder {
+	return &FormDecoder{values: values}
+}
+
+// Has returns true if the field was submitted, including as "name[]" or
+// "name[key]" keys.
+func (d *FormDecoder) Has(name string) bool {
+	if _, ok := d.values[name]; ok {
+		return true
+	}
+	prefix := name + "["
+	for key := range d.values {
+		if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, "]") {
+			return true
+		}
+	}
+	return false
+}
+
+// String decodes a string field.
+func (d *FormDecoder) String(name, def string) string {
+	return decodeValue(d, name, ParseString, def)
+}
+
+// Bool decodes a boolean field. The last submitted value wins so that a
+// checkbox can follow a hidden "false" input.
+func (d *FormDecoder) Bool(name string, def bool) bool {
+	raws := d.values[name]
+	if len(raws) == 0 || raws[len(raws)-1] == "" {
+		return def
+	}
+	b, err := ParseBool(raws[len(raws)-1])
+	if err != nil {
+		d.addError(name, err)
+		return def
+	}
+	return b
+}
+
+// Int decodes an int field.
+func (d *FormDecoder) Int(name string, def int) int {
+	return decodeValue(d, name, ParseInt, def)
+}
+
+// Int32 decodes an int32 field.
+func (d *FormDecoder) Int32(name string, def int32) int32 {
+	return decodeValue(d, name, ParseInt32, def)
+}
+
+// Int64 decodes an int64 field.
+func (d *FormDecoder) Int64(name string, def int64) int64 {
+	return decodeValue(d, name, ParseInt64, def)
+}
+
+// Float32 decodes a float32 field.
+func (d *FormDecoder) Float32(name string, def float32) float32 {
+	return decodeValue(d, name, ParseFloat32, def)
+}
+
+// Float64 decodes a float64 field.
+func (d *FormDecoder) Float64(name string, def float64) float64 {
+	return decodeValue(d, name, ParseFloat64, def)
+}
+
+// Bytes decodes a []byte field.
+func (d *FormDecoder) Bytes(name string, def []byte) []byte {
+	return decodeValue(d, name, ParseBytes, def)
+}
+
+// Errors returns the conversion errors collected so far.
+func (d *FormDecoder) Errors() ValidationErrors {
+	return d.errors
+}
+
+// Err returns the collected conversion errors, or nil if there are none.
+func (d *FormDecoder) Err() error {
+	if len(d.errors) == 0 {
+		return nil
+	}
+	return d.errors
+}
+
+// addError records a conversion error for a field.
+func (d *FormDecoder) addError(field string, err error) {
+	d.errors = append(d.errors, ValidationError{
+		Field:   field,
+		Message: err.Error(),
+	})
+}
+
+// decodeValue decodes the first value of a field, falling back to def when
+// the field is missing, empty or invalid.
+func decodeValue[T any](d *FormDecoder, name string, parse func(string) (T, error), def T) T {
+	raws := d.values[name]
+	// An input left blank submits "", which means no value like a missing one
+	if len(raws) == 0 || raws[0] == "" {
+		return def
+	}
+	v, err := parse(raws[0])
+	if err != nil {
+		d.addError(name, err)
+		return def
+	}
+	return v
+}
+
+// DecodeSlice decodes a slice field from repeated keys ("tags=a&tags=b") or
+// bracket keys ("tags[]=a"). Blank values are skipped, and it returns def
+// when the field is missing or only has blank values.
+func DecodeSlice[T any](d *FormDecoder, name string, parse func(string) (T, error), def []T) []T {
+	raws := append(append([]string{}, d.values[name]...), d.values[name+"[]"]...)
+	out := make([]T, 0, len(raws))
+	for _, raw := range raws {
+		if raw == "" {
+			continue
+		}
+		v, err := parse(raw)
+		if err != nil {
+			d.addError(name, err)
+			return def
+		}
+		out = append(out, v)
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
+// DecodeMap decodes a map field from bracket keys ("settings[theme]=dark").
+// It returns def when the field is missing.
+func DecodeMap[K comparable, V any](d *FormDecoder, name string, parseKey func(string) (K, error), parseValue func(string) (V, error), def map[K]V) map[K]V {
+	prefix := name + "["
+	var out map[K]V
+
+	for key, raws := range d.values {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, "]") || len(raws) == 0 {
+			continue
+		}
+		sub := key[len(prefix) : len(key)-1]
+
+		k, err := parseKey(sub)
+		if err != nil {
+			d.addError(name, err)
+			return def
+		}
+		v, err := parseValue(raws[0])
+		if err != nil {
+			d.addError(name, err)
+			return def
+		}
+
+		if out == nil {
+			out = make(map[K]V)
+		}
+		out[k] = v
+	}
+
+	if out == nil {
+		return def
+	}
+	return out
+}
+
+// ParseString returns raw unchanged.
+func ParseString(raw string) (string, error) {
+	return raw, nil
+}
+
+// ParseBytes returns raw as a byte slice.
+func ParseBytes(raw string) ([]byte, error) {
+	return []byte(raw), nil
+}
+
+// ParseBool parses HTML checkbox values as well as strconv booleans.
+func ParseBool(raw string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "0", "f", "false", "off", "no":
+		return false, nil
+	case "1", "t", "true", "on", "yes":
+		return true, nil
+	}
+	return false, errors.New("must be true or false")
+}
+
+// ParseInt parses an int. An empty string parses as zero.
+func ParseInt(raw string) (int, error) {
+	n, err := parseInt(raw, strconv.IntSize)
+	return int(n), err
+}
+
+// ParseInt32 parses an int32. An empty string parses as zero.
+func ParseInt32(raw string) (int32, error) {
+	n, err := parseInt(raw, 32)
+	return int32(n), err
+}
+
+// ParseInt64 parses an int64. An empty string parses as zero.
+func ParseInt64(raw string) (int64, error) {
+	return parseInt(raw, 64)
+}
+
+// ParseFloat32 parses a float32. An empty string parses as zero.
+func ParseFloat32(raw string) (float32, error) {
+	f, err := parseFloat(raw, 32)
+	return float32(f), err
+}
+
+// ParseFloat64 parses a float64. An empty string parses as zero.
+func ParseFloat64(raw string) (float64, error) {
+	return parseFloat(raw, 64)
+}
+
+// parseInt parses a signed integer of the given bit size.
+func parseInt(raw string, bits int) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, bits)
+	if err != nil {
+		return 0, errors.New("must be a whole number")
+	}
+	return n, nil
+}
+
+// parseUint parses an unsigned integer of the given bit size.
+func parseUint(raw string, bits int) (uint64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(raw, 10, bits)
+	if err != nil {
+		return 0, errors.New("must be a positive whole number")
+	}
+	return n, nil
+}
+
+// parseFloat parses a floating point number of the given bit size.
+func parseFloat(raw string, bits int) (float64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(raw, bits)
+	if err != nil {
+		return 0, errors.New("must be a number")
+	}
+	return f, nil
+}
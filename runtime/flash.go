@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gobijan/gluey/runtime/session"
+)
+
+// FlashCookie is the cookie carrying flash messages of requests without a
+// session. It is also the session key of the messages.
+const FlashCookie = "_flash"
+
+// FlashKey signs flash cookies. It is random by default, so a flash set by
+// one process cannot be read by another; set it to a shared secret of at
+// least 32 bytes when several instances serve the app.
+var FlashKey = newFlashKey()
+
+// Flash holds the messages of the next request by level, such as
+// "success" or "error", in the order they were added.
+type Flash map[string][]string
+
+// AddFlash adds a message for the next request. The messages are kept in the
+// session of the request when the router manages sessions, and in a signed
+// cookie otherwise. Call it before the response is written.
+func AddFlash(w http.ResponseWriter, r *http.Request, level, message string) {
+	if s, ok := session.FromContext(r.Context()); ok {
+		flash := decodeFlash(s.Get(FlashCookie))
+		flash[level] = append(flash[level], message)
+		s.Set(FlashCookie, encodeFlash(flash))
+		return
+	}
+
+	// Add to the flash already set on the response, or else to the unread
+	// flash of the request
+	flash, ok := responseFlash(w)
+	if !ok {
+		flash = requestFlash(r)
+	}
+	flash[level] = append(flash[level], message)
+	setFlashCookie(w, r, signFlash(encodeFlash(flash)), 0)
+}
+
+// Flashes returns the messages added by the previous request and removes
+// them, so that each message is shown once. Call it before the response is
+// written.
+func Flashes(w http.ResponseWriter, r *http.Request) Flash {
+	if s, ok := session.FromContext(r.Context()); ok {
+		return decodeFlash(s.Pop(FlashCookie))
+	}
+
+	flash := requestFlash(r)
+	if len(flash) > 0 {
+		setFlashCookie(w, r, "", -1)
+	}
+	return flash
+}
+
+// requestFlash returns the flash of the request cookie.
+func requestFlash(r *http.Request) Flash {
+	cookie, err := r.Cookie(FlashCookie)
+	if err != nil {
+		return Flash{}
+	}
+	payload, ok := verifyFlash(cookie.Value)
+	if !ok {
+		return Flash{}
+	}
+	return decodeFlash(payload)
+}
+
+// responseFlash returns the flash cookie already set on the response, and
+// removes it so that it can be replaced.
+func responseFlash(w http.ResponseWriter) (Flash, bool) {
+	header := w.Header()
+	cookies := header.Values("Set-Cookie")
+	for i, line := range cookies {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil || cookie.Name != FlashCookie {
+			continue
+		}
+		header.Del("Set-Cookie")
+		for j, other := range cookies {
+			if j != i {
+				header.Add("Set-Cookie", other)
+			}
+		}
+		payload, ok := verifyFlash(cookie.Value)
+		if !ok {
+			return Flash{}, true
+		}
+		return decodeFlash(payload), true
+	}
+	return nil, false
+}
+
+// setFlashCookie sets the flash cookie, or clears it for a negative maxAge.
+func setFlashCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     FlashCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// encodeFlash returns the JSON encoding of flash.
+func encodeFlash(flash Flash) string {
+	b, _ := json.Marshal(flash)
+	return string(b)
+}
+
+// decodeFlash parses an encoded flash, ignoring invalid ones.
+func decodeFlash(s string) Flash {
+	flash := Flash{}
+	if s != "" {
+		json.Unmarshal([]byte(s), &flash)
+	}
+	return flash
+}
+
+// signFlash returns the cookie value of an encoded flash: the base64
+// payload and its HMAC, separated by a dot.
+func signFlash(payload string) string {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(flashMAC(encoded))
+}
+
+// verifyFlash returns the payload of a signed cookie value.
+func verifyFlash(value string) (string, bool) {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, flashMAC(encoded)) {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(payload), true
+}
+
+// flashMAC returns the HMAC-SHA256 of s under FlashKey.
+func flashMAC(s string) []byte {
+	h := hmac.New(sha256.New, FlashKey)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// newFlashKey returns a random signing key.
+func newFlashKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
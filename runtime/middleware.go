@@ -0,0 +1,416 @@
+package runtime
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps an http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// builtinMiddleware maps normalized names to the built-in middleware.
+var builtinMiddleware = map[string]Middleware{
+	"logger":          Logger,
+	"recover":         Recover,
+	"requestid":       RequestID,
+	"gzip":            Gzip,
+	"securityheaders": SecurityHeaders,
+	"realip":          RealIP,
+	"csrf":            CSRF,
+	"methodoverride":  MethodOverride,
+}
+
+// middlewareAliases maps alternative names to built-in ones.
+var middlewareAliases = map[string]string{
+	"logging":       "logger",
+	"recovery":      "recover",
+	"secureheaders": "securityheaders",
+}
+
+// NormalizeMiddlewareName lowercases name, strips separators and resolves
+// aliases, so that "RequestID", "request_id" and "request-id" are the same
+// middleware. Built-in middleware are registered under their normalized
+// names.
+func NormalizeMiddlewareName(name string) string {
+	name = strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(name))
+	if alias, ok := middlewareAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// LookupMiddleware returns the built-in middleware with the given name.
+// Names are matched ignoring case and separators.
+func LookupMiddleware(name string) (Middleware, bool) {
+	m, ok := builtinMiddleware[NormalizeMiddlewareName(name)]
+	return m, ok
+}
+
+// MiddlewareNames returns the sorted names of the built-in middleware.
+func MiddlewareNames() []string {
+	names := make([]string, 0, len(builtinMiddleware))
+	for name := range builtinMiddleware {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolveMiddleware returns the named middleware in order. Names are looked
+// up in custom first, so applications can add or replace middleware, then
+// among the built-ins. It panics if a name is found in neither.
+func ResolveMiddleware(custom map[string]Middleware, names ...string) []Middleware {
+	stack := make([]Middleware, len(names))
+	for i, name := range names {
+		if m, ok := custom[name]; ok && m != nil {
+			stack[i] = m
+			continue
+		}
+		m, ok := LookupMiddleware(name)
+		if !ok {
+			panic(fmt.Sprintf("middleware %q is neither built in nor in Controllers.Middleware", name))
+		}
+		stack[i] = m
+	}
+	return stack
+}
+
+// Chain wraps h with middleware so that the first one is outermost.
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// responseRecorder records the status and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader records the status code.
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Logger logs every request with log/slog's default logger once it has
+// been served.
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", rec.size),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if id := RequestIDFrom(r.Context()); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// Recover turns panics in handlers into 500 responses and logs them with
+// their stack trace. http.ErrAbortHandler is re-panicked.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "panic serving request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Any("panic", v),
+				slog.String("stack", string(debug.Stack())),
+			)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestIDHeader is the header carrying request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// RequestID gives every request an ID, available through RequestIDFrom and
+// echoed in the X-Request-ID response header. A well-formed incoming
+// X-Request-ID is kept.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFrom returns the request ID set by RequestID, or an empty string.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether an incoming request ID is safe to reuse.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex ID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// gzipWriters pools gzip writers across responses.
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// gzipResponseWriter compresses the body unless the handler set its own
+// Content-Encoding or the response has no body.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// WriteHeader decides whether to compress before sending the headers.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status < http.StatusOK {
+		// Informational responses precede the real one
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write compresses b if compression is on.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush flushes the compressed data written so far.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the gzip stream and returns the writer to the pool.
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(io.Discard)
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
+
+// Gzip compresses responses for clients that accept gzip.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// SecurityHeaders sets conservative security headers. Handlers can
+// override them. Strict-Transport-Security is only sent over TLS.
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "SAMEORIGIN")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+		if r.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RealIP sets r.RemoteAddr to the client IP reported by a reverse proxy.
+// The X-Real-IP and X-Forwarded-For headers are only trusted when the
+// request comes from a loopback or private address; X-Forwarded-For is
+// read right to left, skipping such addresses.
+func RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := realIP(r); ip != "" {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// realIP returns the client IP forwarded by a trusted proxy, or an empty
+// string.
+func realIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if peer := net.ParseIP(host); peer == nil || !trustedProxy(peer) {
+		return ""
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	var first string
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			continue
+		}
+		if !trustedProxy(ip) {
+			return ip.String()
+		}
+		first = ip.String()
+	}
+	return first
+}
+
+// trustedProxy reports whether ip may be a reverse proxy.
+func trustedProxy(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate()
+}
+
+// MethodOverrideField is the form field carrying the method of a form.
+const MethodOverrideField = "_method"
+
+// MethodOverrideHeader is the header carrying the method of a script
+// request.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverride lets POST requests stand for PUT, PATCH and DELETE ones,
+// which HTML forms cannot send. The method is read from the _method form
+// field or the X-HTTP-Method-Override header. Generated routers install it
+// in front of the routes.
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			method := r.Header.Get(MethodOverrideHeader)
+			if method == "" {
+				method = r.PostFormValue(MethodOverrideField)
+			}
+			switch method = strings.ToUpper(method); method {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				r = r.WithContext(r.Context())
+				r.Method = method
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
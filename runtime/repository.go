@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+)
+
+// ErrNotFound is returned by generated repositories when no record has the
+// requested ID.
+var ErrNotFound = errors.New("record not found")
+
+// ErrExists is returned by generated repositories when creating a record
+// whose ID is already taken.
+var ErrExists = errors.New("record already exists")
+
+// NewID returns a random 128-bit hex ID, which generated repositories assign
+// to new records whose string primary key is empty.
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Page is a window into a list of records, built from the page and per_page
+// index params.
+type Page struct {
+	// Number is the 1-based page number.
+	Number int
+	// PerPage is the number of records per page.
+	PerPage int
+}
+
+// NewPage returns the page for the given params. Number is at least 1 and
+// PerPage is clamped to [1, maxPerPage]. A maxPerPage of zero or less means
+// no upper limit.
+func NewPage(number, perPage, maxPerPage int) Page {
+	if number < 1 {
+		number = 1
+	}
+	if maxPerPage > 0 && (perPage < 1 || perPage > maxPerPage) {
+		perPage = maxPerPage
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+	// Keep Offset from overflowing for huge page numbers
+	if number > math.MaxInt/perPage {
+		number = math.MaxInt / perPage
+	}
+	return Page{Number: number, PerPage: perPage}
+}
+
+// Offset returns the number of records before the page.
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.PerPage
+}
+
+// Bounds returns the start and end indexes of the page in a list of n
+// records, suitable for slicing. A Page without a size covers all records.
+func (p Page) Bounds(n int) (start, end int) {
+	if p.PerPage < 1 {
+		return 0, n
+	}
+	skipped := max(p.Number, 1) - 1
+	// Compare before multiplying so huge page numbers cannot overflow
+	if skipped > n/p.PerPage {
+		return n, n
+	}
+	start = min(skipped*p.PerPage, n)
+	end = min(start+p.PerPage, n)
+	return start, end
+}
@@ -0,0 +1,244 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Response formats of resources, declared with Formats in the design.
+const (
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+// JSONSuffix is the path suffix requesting the JSON format, as in
+// /posts.json or /posts/1.json.
+const JSONSuffix = ".json"
+
+// formatKey is the context key of the format of a request.
+type formatKey struct{}
+
+// UseFormats wraps the handler of a route with the format negotiated among
+// formats, the first being the default. The generated router uses it for
+// resources with more than one format. A .json suffix is removed from the
+// id path value so that /posts/1.json reaches the handler as id 1.
+func UseFormats(formats []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := NegotiateFormat(r, formats...)
+		if id := r.PathValue("id"); strings.HasSuffix(id, JSONSuffix) && format == FormatJSON {
+			r.SetPathValue("id", strings.TrimSuffix(id, JSONSuffix))
+		}
+		next(w, WithFormat(r, format))
+	}
+}
+
+// NegotiateFormat returns the format of formats requested by r: JSON for a
+// path ending in .json, else the one preferred by the Accept header. It
+// falls back to JSON for a request with a JSON body, else to the first
+// format, or HTML if formats is empty.
+func NegotiateFormat(r *http.Request, formats ...string) string {
+	if len(formats) == 0 {
+		formats = []string{FormatHTML}
+	}
+	if strings.HasSuffix(r.URL.Path, JSONSuffix) && slices.Contains(formats, FormatJSON) {
+		return FormatJSON
+	}
+
+	fallback := formats[0]
+	contentType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	if mediaFormat(strings.ToLower(strings.TrimSpace(contentType)), formats, "") == FormatJSON {
+		fallback = FormatJSON
+	}
+
+	best, bestQ := fallback, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(accepted, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		format := mediaFormat(strings.ToLower(strings.TrimSpace(mediaType)), formats, fallback)
+		// The first of equally preferred types wins
+		if format != "" && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// mediaFormat returns the format of formats served as mediaType, fallback
+// for */*, or an empty string if there is none.
+func mediaFormat(mediaType string, formats []string, fallback string) string {
+	switch {
+	case mediaType == "*/*":
+		return fallback
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if slices.Contains(formats, FormatJSON) {
+			return FormatJSON
+		}
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		if slices.Contains(formats, FormatHTML) {
+			return FormatHTML
+		}
+	}
+	return ""
+}
+
+// WithFormat returns a copy of r answered in format.
+func WithFormat(r *http.Request, format string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), formatKey{}, format))
+}
+
+// FormatFrom returns the format set by UseFormats or WithFormat, or HTML
+// if there is none.
+func FormatFrom(ctx context.Context) string {
+	if format, ok := ctx.Value(formatKey{}).(string); ok {
+		return format
+	}
+	return FormatHTML
+}
+
+// WantsJSON returns true if r is answered in JSON.
+func WantsJSON(r *http.Request) bool {
+	return FormatFrom(r.Context()) == FormatJSON
+}
+
+// viewsKey is the context key of the template engine of a request.
+type viewsKey struct{}
+
+// UseViews wraps next so that Render and Respond render its views with
+// engine, for handlers that don't embed BaseController.
+func UseViews(engine *TemplateEngine, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, WithViews(r, engine))
+	})
+}
+
+// WithViews returns a copy of r whose views Render renders with engine.
+func WithViews(r *http.Request, engine *TemplateEngine) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), viewsKey{}, engine))
+}
+
+// ViewsFrom returns the template engine set by UseViews or WithViews, or
+// nil if there is none.
+func ViewsFrom(ctx context.Context) *TemplateEngine {
+	engine, _ := ctx.Value(viewsKey{}).(*TemplateEngine)
+	return engine
+}
+
+// Render renders a view with the template engine of the request, see
+// UseViews, inside its layout, see TemplateEngine.RenderRequest. The view
+// and layout get the entries of data if it is a map, data itself as .Data,
+// and the flash messages and CSRF token of the request.
+func Render(w http.ResponseWriter, r *http.Request, view string, data any) error {
+	engine := ViewsFrom(r.Context())
+	if engine == nil {
+		http.Error(w, "Templates not loaded", http.StatusInternalServerError)
+		return errors.New("no template engine for the request, see UseViews")
+	}
+
+	// The flash messages must be read before the response is written
+	viewData := map[string]any{}
+	if m, ok := data.(map[string]any); ok {
+		maps.Copy(viewData, m)
+	}
+	viewData["Data"] = data
+	viewData["Flash"] = Flashes(w, r)
+	viewData["CSRFToken"] = CSRFToken(r)
+
+	// Render fully first so that template errors get a clean 500
+	var buf bytes.Buffer
+	if err := engine.RenderRequest(&buf, r, view, viewData); err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return fmt.Errorf("failed to render template %s: %w", view, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Respond answers the request in its format, see UseFormats: data as JSON,
+// or else the view rendered as with Render.
+func Respond(w http.ResponseWriter, r *http.Request, view string, data any) error {
+	if WantsJSON(r) {
+		return WriteJSON(w, http.StatusOK, data)
+	}
+	return Render(w, r, view, data)
+}
+
+// RespondInvalid answers a request whose input failed validation: with a
+// 422 problem document listing errs in JSON, or else the view rendered with
+// errs as .Errors, next to the entries of data.
+func RespondInvalid(w http.ResponseWriter, r *http.Request, view string, data map[string]any, errs ValidationErrors) error {
+	if WantsJSON(r) {
+		return WriteProblem(w, ValidationProblem(errs))
+	}
+	viewData := map[string]any{"Errors": errs}
+	maps.Copy(viewData, data)
+	return Render(w, r, view, viewData)
+}
+
+// WriteJSON writes v as a JSON response with status. v is encoded fully
+// first so that encoding errors leave the response unwritten.
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	return writeJSON(w, "application/json", status, v)
+}
+
+// Problem is an RFC 9457 problem document, the JSON answer of failed
+// requests.
+type Problem struct {
+	// Type is a URI identifying the problem, "about:blank" if empty
+	Type string `json:"type,omitempty"`
+	// Title is the summary of the problem, the status text by default
+	Title string `json:"title"`
+	// Status is the HTTP status of the response
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Errors holds the invalid fields of a validation problem
+	Errors ValidationErrors `json:"errors,omitempty"`
+}
+
+// ValidationProblem returns the 422 problem reporting errs.
+func ValidationProblem(errs ValidationErrors) Problem {
+	return Problem{
+		Title:  "Validation failed",
+		Status: http.StatusUnprocessableEntity,
+		Errors: errs,
+	}
+}
+
+// WriteProblem writes p as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, p Problem) error {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	return writeJSON(w, "application/problem+json", p.Status, p)
+}
+
+// writeJSON writes v as a JSON response of contentType with status.
+func writeJSON(w http.ResponseWriter, contentType string, status int, v any) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, "JSON encoding error", http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
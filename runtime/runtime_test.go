@@ -0,0 +1,1396 @@
+package runtime_test
+
+import (
+	"compress/gzip"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gobijan/gluey/runtime"
+	"github.com/gobijan/gluey/runtime/session"
+)
+
+type bindForm struct {
+	Title    string            `form:"title"`
+	Count    int               `form:"count"`
+	Small    int32             `form:"small"`
+	Big      int64             `form:"big"`
+	Ratio    float32           `form:"ratio"`
+	Price    float64           `form:"price"`
+	Agree    bool              `form:"agree"`
+	Raw      []byte            `form:"raw"`
+	Tags     []string          `form:"tags"`
+	Scores   []int             `form:"scores"`
+	Settings map[string]string `form:"settings"`
+	Limits   map[string]int    `form:"limits"`
+	Skipped  string            `form:"-"`
+	NoTag    string
+}
+
+func TestBindValues(t *testing.T) {
+	values := url.Values{
+		"title":           {"Hello"},
+		"count":           {"42"},
+		"small":           {"-7"},
+		"big":             {"9000000000"},
+		"ratio":           {"0.5"},
+		"price":           {"19.99"},
+		"agree":           {"false", "on"},
+		"raw":             {"bytes"},
+		"tags":            {"go"},
+		"tags[]":          {"web"},
+		"scores":          {"1", "2", "3"},
+		"settings[theme]": {"dark"},
+		"settings[lang]":  {"en"},
+		"limits[max]":     {"10"},
+		"Skipped":         {"nope"},
+		"-":               {"nope"},
+		"NoTag":           {"fallback"},
+	}
+
+	var form bindForm
+	if err := runtime.BindValues(values, &form); err != nil {
+		t.Fatalf("BindValues() returned error: %v", err)
+	}
+
+	if form.Title != "Hello" || form.Count != 42 || form.Small != -7 || form.Big != 9000000000 {
+		t.Errorf("scalar fields not bound: %+v", form)
+	}
+	if form.Ratio != 0.5 || form.Price != 19.99 {
+		t.Errorf("float fields not bound: %v, %v", form.Ratio, form.Price)
+	}
+	if !form.Agree {
+		t.Error("Agree should be true when the checkbox follows a hidden false input")
+	}
+	if string(form.Raw) != "bytes" {
+		t.Errorf("Raw = %q, want %q", form.Raw, "bytes")
+	}
+	if strings.Join(form.Tags, ",") != "go,web" {
+		t.Errorf("Tags = %v, want [go web]", form.Tags)
+	}
+	if len(form.Scores) != 3 || form.Scores[2] != 3 {
+		t.Errorf("Scores = %v, want [1 2 3]", form.Scores)
+	}
+	if form.Settings["theme"] != "dark" || form.Settings["lang"] != "en" {
+		t.Errorf("Settings = %v", form.Settings)
+	}
+	if form.Limits["max"] != 10 {
+		t.Errorf("Limits = %v", form.Limits)
+	}
+	if form.Skipped != "" {
+		t.Error("fields tagged form:\"-\" should be skipped")
+	}
+	if form.NoTag != "fallback" {
+		t.Error("untagged fields should bind by Go name")
+	}
+}
+
+func TestBindValuesErrors(t *testing.T) {
+	values := url.Values{
+		"count":       {"abc"},
+		"price":       {"1.2.3"},
+		"agree":       {"maybe"},
+		"scores":      {"1", "x"},
+		"limits[max]": {"lots"},
+		"title":       {"still bound"},
+	}
+
+	var form bindForm
+	err := runtime.BindValues(values, &form)
+	if err == nil {
+		t.Fatal("BindValues() should return an error for invalid input")
+	}
+
+	var verrs runtime.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("error should be ValidationErrors, got %T", err)
+	}
+
+	fields := make(map[string]bool)
+	for _, e := range verrs {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"count", "price", "agree", "scores", "limits"} {
+		if !fields[want] {
+			t.Errorf("expected a validation error for %q, got %v", want, verrs)
+		}
+	}
+
+	if form.Title != "still bound" {
+		t.Error("valid fields should still be bound when others fail")
+	}
+}
+
+func TestBindValuesInvalidDestination(t *testing.T) {
+	var form bindForm
+	if err := runtime.BindValues(url.Values{}, form); err == nil {
+		t.Error("BindValues() should reject a non-pointer destination")
+	}
+
+	var n int
+	if err := runtime.BindValues(url.Values{}, &n); err == nil {
+		t.Error("BindValues() should reject a pointer to a non-struct")
+	}
+}
+
+func TestBaseControllerBind(t *testing.T) {
+	body := strings.NewReader("title=Posted&tags=a&tags=b")
+	r := httptest.NewRequest(http.MethodPost, "/posts?count=3", body)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var form bindForm
+	c := runtime.NewBaseController("")
+	if err := c.Bind(r, &form); err != nil {
+		t.Fatalf("Bind() returned error: %v", err)
+	}
+
+	if form.Title != "Posted" || form.Count != 3 || len(form.Tags) != 2 {
+		t.Errorf("Bind() did not populate form: %+v", form)
+	}
+}
+
+func TestRequestValues(t *testing.T) {
+	body := `{"title":"Posted","count":3,"agree":true,"tags":["a","b"],"labels":[],"settings":{"theme":"dark"},"note":null}`
+	r := httptest.NewRequest(http.MethodPost, "/posts?page=2", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	values, err := runtime.RequestValues(r)
+	if err != nil {
+		t.Fatalf("RequestValues() returned error: %v", err)
+	}
+	want := url.Values{
+		"title":           {"Posted"},
+		"count":           {"3"},
+		"agree":           {"true"},
+		"tags":            {"a", "b"},
+		"labels":          {},
+		"settings[theme]": {"dark"},
+		"page":            {"2"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("RequestValues() = %v, want %v", values, want)
+	}
+
+	// JSON input is bound and reports conversion errors like form input
+	r = httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Posted","count":"many"}`))
+	r.Header.Set("Content-Type", "application/json")
+	var form bindForm
+	var errs runtime.ValidationErrors
+	if err := runtime.NewBaseController("").Bind(r, &form); !errors.As(err, &errs) || errs[0].Field != "count" {
+		t.Errorf("Bind() error = %v, want a count validation error", err)
+	}
+	if form.Title != "Posted" {
+		t.Errorf("Bind() title = %q, want Posted", form.Title)
+	}
+
+	for _, body := range []string{`{"title":`, `["a"]`, `{"tags":[["a"]]}`, `{"settings":{"theme":{"dark":true}}}`} {
+		r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if _, err := runtime.RequestValues(r); err == nil {
+			t.Errorf("RequestValues(%s) should fail", body)
+		}
+	}
+}
+
+func TestFormDecoder(t *testing.T) {
+	values := url.Values{
+		"title":           {"Hello"},
+		"count":           {"12"},
+		"limit":           {""},
+		"sort":            {""},
+		"ratio":           {"2.5"},
+		"agree":           {"false", "1"},
+		"tags[]":          {"a", "", "b"},
+		"labels":          {""},
+		"settings[theme]": {"dark"},
+	}
+
+	d := runtime.NewFormDecoder(values)
+
+	if got := d.String("title", ""); got != "Hello" {
+		t.Errorf("String() = %q, want %q", got, "Hello")
+	}
+	if got := d.String("missing", "fallback"); got != "fallback" {
+		t.Errorf("String() should return the default for missing keys, got %q", got)
+	}
+	if got := d.Int32("count", 0); got != 12 {
+		t.Errorf("Int32() = %d, want 12", got)
+	}
+	if got := d.Int("page", 1); got != 1 {
+		t.Errorf("Int() should return the default for missing keys, got %d", got)
+	}
+	if got := d.Int("limit", 20); got != 20 {
+		t.Errorf("Int() should return the default for empty values, got %d", got)
+	}
+	if got := d.String("sort", "newest"); got != "newest" {
+		t.Errorf("String() should return the default for empty values, got %q", got)
+	}
+	if got := d.Float64("ratio", 0); got != 2.5 {
+		t.Errorf("Float64() = %v, want 2.5", got)
+	}
+	if !d.Bool("agree", false) {
+		t.Error("Bool() should use the last submitted value")
+	}
+	if got := runtime.DecodeSlice(d, "tags", runtime.ParseString, nil); len(got) != 2 {
+		t.Errorf("DecodeSlice() = %v, want [a b]", got)
+	}
+	if got := runtime.DecodeSlice(d, "labels", runtime.ParseString, []string{"new"}); len(got) != 1 || got[0] != "new" {
+		t.Errorf("DecodeSlice() should return the default for blank values, got %v", got)
+	}
+	if got := runtime.DecodeMap(d, "settings", runtime.ParseString, runtime.ParseString, nil); got["theme"] != "dark" {
+		t.Errorf("DecodeMap() = %v", got)
+	}
+	if !d.Has("settings") || d.Has("nothing") {
+		t.Error("Has() should detect bracket keys and report missing ones")
+	}
+	if err := d.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+
+	bad := runtime.NewFormDecoder(url.Values{"count": {"many"}})
+	if got := bad.Int("count", 7); got != 7 {
+		t.Errorf("Int() should return the default on conversion failure, got %d", got)
+	}
+	errs := bad.Errors()
+	if len(errs) != 1 || errs[0].Field != "count" {
+		t.Errorf("Errors() = %v, want one error for count", errs)
+	}
+}
+
+type signupForm struct {
+	Name     string         `form:"name" validate:"required,min=2,max=10"`
+	Email    string         `form:"email" validate:"required,email"`
+	Website  string         `json:"website,omitempty" validate:"url"`
+	Status   string         `form:"status" validate:"oneof=draft published archived"`
+	Priority int            `form:"priority" validate:"oneof=1 2 3"`
+	Username string         `form:"username" validate:"pattern=^[a-z]{2,8}$"`
+	Age      int            `form:"age" validate:"min=18,max=130"`
+	Score    float64        `form:"score" validate:"omitempty,min=0.5"`
+	Token    string         `form:"token" validate:"uuid"`
+	Birthday string         `form:"birthday" validate:"date"`
+	Tags     []string       `form:"tags" validate:"max=2"`
+	Nickname *string        `form:"nickname" validate:"required"`
+	Extra    map[string]int `validate:"-"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	nick := "bob"
+	valid := signupForm{
+		Name:     "Alice",
+		Email:    "alice@example.com",
+		Website:  "https://example.com",
+		Status:   "draft",
+		Priority: 2,
+		Username: "alice",
+		Age:      30,
+		Token:    "123e4567-e89b-12d3-a456-426614174000",
+		Birthday: "1990-04-01",
+		Tags:     []string{"a"},
+		Nickname: &nick,
+	}
+	if errs := runtime.ValidateStruct(&valid); errs.HasErrors() {
+		t.Errorf("ValidateStruct() returned errors for a valid struct: %v", errs)
+	}
+
+	invalid := signupForm{
+		Name:     "A",
+		Website:  "not a url",
+		Status:   "deleted",
+		Priority: 9,
+		Username: "Alice_1",
+		Age:      12,
+		Token:    "nope",
+		Birthday: "01/04/1990",
+		Tags:     []string{"a", "b", "c"},
+	}
+	errs := runtime.ValidateStruct(invalid)
+
+	got := make(map[string]string)
+	for _, e := range errs {
+		got[e.Field] = e.Message
+	}
+	expected := map[string]string{
+		"name":     "must be at least 2 characters",
+		"email":    "is required",
+		"website":  "must be a valid URL",
+		"status":   "must be one of: draft, published, archived",
+		"priority": "must be one of: 1, 2, 3",
+		"username": "format is invalid",
+		"age":      "must be at least 18",
+		"token":    "must be a valid UUID",
+		"birthday": "must be a valid date (YYYY-MM-DD)",
+		"tags":     "must be at most 2 items",
+		"nickname": "is required",
+	}
+	for field, msg := range expected {
+		if got[field] != msg {
+			t.Errorf("error for %s = %q, want %q", field, got[field], msg)
+		}
+	}
+	if _, ok := got["score"]; ok {
+		t.Error("omitempty should skip rules for empty values")
+	}
+}
+
+func TestValidateStructInvalidTag(t *testing.T) {
+	type badForm struct {
+		Code string `form:"code" validate:"pattern=(unclosed"`
+		Kind string `form:"kind" validate:"bogus"`
+	}
+
+	errs := runtime.ValidateStruct(badForm{})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors for invalid tags, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Message, "invalid validation pattern") {
+		t.Errorf("unexpected message for bad pattern: %q", errs[0].Message)
+	}
+}
+
+func TestValidateStructQuotedOptions(t *testing.T) {
+	type taskForm struct {
+		State string `validate:"required,oneof='in progress' done 'a, b' 'it''s',max=11"`
+	}
+
+	for _, state := range []string{"in progress", "done", "a, b", "it's"} {
+		if errs := runtime.ValidateStruct(taskForm{State: state}); errs.HasErrors() {
+			t.Errorf("ValidateStruct(%q) = %v, want no errors", state, errs)
+		}
+	}
+	errs := runtime.ValidateStruct(taskForm{State: "in"})
+	if len(errs) != 1 || errs[0].Message != "must be one of: in progress, done, a, b, it's" {
+		t.Errorf("ValidateStruct(in) = %v, want a oneof error", errs)
+	}
+	if errs := runtime.ValidateStruct(taskForm{State: "in progress, done"}); len(errs) != 2 {
+		t.Errorf("rules after quoted options should apply, got %v", errs)
+	}
+
+	type badForm struct {
+		State string `validate:"oneof='open done"`
+	}
+	if errs := runtime.ValidateStruct(badForm{}); len(errs) != 1 || !strings.Contains(errs[0].Message, "unterminated quote") {
+		t.Errorf("ValidateStruct() = %v, want an unterminated quote error", errs)
+	}
+}
+
+func TestValidateStructNonStruct(t *testing.T) {
+	if errs := runtime.ValidateStruct(42); errs.HasErrors() {
+		t.Error("ValidateStruct() should ignore non-struct values")
+	}
+	var nilForm *signupForm
+	if errs := runtime.ValidateStruct(nilForm); errs.HasErrors() {
+		t.Error("ValidateStruct() should ignore nil pointers")
+	}
+}
+
+func TestValidatorRules(t *testing.T) {
+	v := runtime.NewValidator()
+	v.OneOf("status", "draft", "draft", "published").
+		OneOf("empty", "", "a").
+		Range("rating", 3, 1, 5).
+		Min("age", 18, 18).
+		Max("qty", 10, 10).
+		Date("day", "2024-02-29").
+		DateTime("at", "2024-02-29T10:30").
+		UUID("id", "123e4567-e89b-12d3-a456-426614174000").
+		MinItems("tags", 2, 1).
+		MaxItems("tags", 2, 3).
+		Check("terms", true, "must be accepted")
+	if !v.Valid() {
+		t.Errorf("expected no errors, got %v", v.Errors())
+	}
+
+	v = runtime.NewValidator()
+	v.OneOf("status", "deleted", "draft", "published").
+		Range("rating", 6, 1, 5).
+		Min("age", 17, 18).
+		Max("qty", 11, 10).
+		Date("day", "2024-02-30").
+		DateTime("at", "yesterday").
+		UUID("id", "123").
+		MinItems("tags", 0, 1).
+		MaxItems("tags", 4, 3).
+		Check("terms", false, "must be accepted")
+	if got := len(v.Errors()); got != 10 {
+		t.Errorf("expected 10 errors, got %d: %v", got, v.Errors())
+	}
+	if v.Errors()[0].Message != "must be one of: draft, published" {
+		t.Errorf("unexpected OneOf message: %q", v.Errors()[0].Message)
+	}
+}
+
+func TestValidatorCustom(t *testing.T) {
+	v := runtime.NewValidator()
+	v.Custom("email", nil).
+		Custom("email", errors.New("is already taken")).
+		Custom("name", runtime.ValidationError{Message: "is reserved"}).
+		Custom("password", runtime.ValidationErrors{
+			{Field: "password_confirmation", Message: "must match password"},
+			{Message: "is too common"},
+		})
+
+	errs := v.Errors()
+	want := []runtime.ValidationError{
+		{Field: "email", Message: "is already taken"},
+		{Field: "name", Message: "is reserved"},
+		{Field: "password_confirmation", Message: "must match password"},
+		{Field: "password", Message: "is too common"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Custom() recorded %v, want %v", errs, want)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("error %d = %v, want %v", i, errs[i], want[i])
+		}
+	}
+
+	missing := &runtime.MissingValidatorError{Name: "RegisterFormValidator"}
+	if missing.Error() != "RegisterFormValidator is not registered" {
+		t.Errorf("unexpected MissingValidatorError message: %q", missing.Error())
+	}
+}
+
+func TestNewID(t *testing.T) {
+	id := runtime.NewID()
+	if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		t.Errorf("NewID() = %q, want 32 hex digits", id)
+	}
+	if other := runtime.NewID(); other == id {
+		t.Errorf("NewID() returned %q twice", id)
+	}
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		name                     string
+		number, perPage, maxSize int
+		want                     runtime.Page
+	}{
+		{"defaults to max", 0, 0, 20, runtime.Page{Number: 1, PerPage: 20}},
+		{"within limit", 3, 10, 20, runtime.Page{Number: 3, PerPage: 10}},
+		{"clamped to max", 2, 500, 20, runtime.Page{Number: 2, PerPage: 20}},
+		{"no limit", 1, 500, 0, runtime.Page{Number: 1, PerPage: 500}},
+		{"no limit or size", -1, 0, 0, runtime.Page{Number: 1, PerPage: 1}},
+		{"huge page", math.MaxInt, 10, 20, runtime.Page{Number: math.MaxInt / 10, PerPage: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runtime.NewPage(tt.number, tt.perPage, tt.maxSize); got != tt.want {
+				t.Errorf("NewPage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	bounds := []struct {
+		page       runtime.Page
+		n          int
+		start, end int
+	}{
+		{runtime.Page{Number: 1, PerPage: 10}, 25, 0, 10},
+		{runtime.Page{Number: 3, PerPage: 10}, 25, 20, 25},
+		{runtime.Page{Number: 4, PerPage: 10}, 25, 25, 25},
+		{runtime.Page{Number: math.MaxInt, PerPage: 10}, 25, 25, 25},
+		{runtime.Page{}, 25, 0, 25},
+	}
+	for _, b := range bounds {
+		start, end := b.page.Bounds(b.n)
+		if start != b.start || end != b.end {
+			t.Errorf("%+v.Bounds(%d) = %d, %d, want %d, %d", b.page, b.n, start, end, b.start, b.end)
+		}
+	}
+	if offset := (runtime.Page{Number: 3, PerPage: 10}).Offset(); offset != 20 {
+		t.Errorf("Offset() = %d, want 20", offset)
+	}
+}
+
+func TestDialectRebind(t *testing.T) {
+	query := `SELECT "a?" FROM "t" WHERE "x" = ? AND "y" LIKE ? ESCAPE '\' AND "z" = '?'`
+	if got := runtime.SQLite.Rebind(query); got != query {
+		t.Errorf("SQLite.Rebind() = %q, want query unchanged", got)
+	}
+	want := `SELECT "a?" FROM "t" WHERE "x" = $1 AND "y" LIKE $2 ESCAPE '\' AND "z" = '?'`
+	if got := runtime.Postgres.Rebind(query); got != want {
+		t.Errorf("Postgres.Rebind() = %q, want %q", got, want)
+	}
+}
+
+func TestWhere(t *testing.T) {
+	if w := runtime.NewWhere(runtime.SQLite); w.String() != "" || len(w.Args()) != 0 {
+		t.Errorf("empty Where = %q %v, want no clause", w.String(), w.Args())
+	}
+
+	tests := []struct {
+		dialect runtime.Dialect
+		want    string
+	}{
+		{runtime.SQLite, ` WHERE ("title" LIKE ? ESCAPE '\' OR "body" LIKE ? ESCAPE '\') AND "views" = ?`},
+		{runtime.Postgres, ` WHERE ("title" ILIKE ? ESCAPE '\' OR "body" ILIKE ? ESCAPE '\') AND "views" = ?`},
+	}
+	for _, tt := range tests {
+		w := runtime.NewWhere(tt.dialect).
+			Search([]string{`"title"`, `"body"`}, "50%_off").
+			Search(nil, "ignored").
+			Equal(`"views"`, 3)
+		if got := w.String(); got != tt.want {
+			t.Errorf("%s Where = %q, want %q", tt.dialect, got, tt.want)
+		}
+		args := w.Args()
+		if len(args) != 3 || args[0] != `%50\%\_off%` || args[1] != args[0] || args[2] != 3 {
+			t.Errorf("%s Where args = %v", tt.dialect, args)
+		}
+	}
+}
+
+type rowsAffected int64
+
+func (r rowsAffected) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsAffected) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestCheckAffected(t *testing.T) {
+	if err := runtime.CheckAffected(rowsAffected(1), nil); err != nil {
+		t.Errorf("CheckAffected(1) = %v, want nil", err)
+	}
+	if err := runtime.CheckAffected(rowsAffected(0), nil); !errors.Is(err, runtime.ErrNotFound) {
+		t.Errorf("CheckAffected(0) = %v, want ErrNotFound", err)
+	}
+	failed := errors.New("failed")
+	if err := runtime.CheckAffected(nil, failed); err != failed {
+		t.Errorf("CheckAffected(err) = %v, want %v", err, failed)
+	}
+}
+
+func TestJSONColumn(t *testing.T) {
+	value, err := runtime.JSON([]string{"a", "b"}).Value()
+	if err != nil || value != `["a","b"]` {
+		t.Fatalf("Value() = %v, %v", value, err)
+	}
+
+	for _, src := range []any{`["a","b"]`, []byte(`["a","b"]`)} {
+		var got []string
+		if err := runtime.JSON(&got).Scan(src); err != nil || len(got) != 2 || got[1] != "b" {
+			t.Errorf("Scan(%T) = %v, %v", src, got, err)
+		}
+	}
+
+	got := map[string]int{"kept": 1}
+	if err := runtime.JSON(&got).Scan(nil); err != nil || got["kept"] != 1 {
+		t.Errorf("Scan(nil) = %v, %v, want value unchanged", got, err)
+	}
+	if err := runtime.JSON(&got).Scan(42); err == nil {
+		t.Error("Scan(int) should fail")
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	authorizer := runtime.AuthorizerFunc(func(r *http.Request, requirement, resource, action string) error {
+		if resource != "posts" || action != "edit" {
+			t.Errorf("Authorize() got %s/%s, want posts/edit", resource, action)
+		}
+		switch {
+		case r.Header.Get("User") == "":
+			return runtime.ErrUnauthenticated
+		case requirement == "admin" && r.Header.Get("User") != "admin":
+			return runtime.ErrForbidden
+		}
+		return nil
+	})
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name     string
+		policy   runtime.AuthPolicy
+		method   string
+		user     string
+		status   int
+		location string
+	}{
+		{"granted", runtime.AuthPolicy{}, "GET", "admin", http.StatusNoContent, ""},
+		{"forbidden", runtime.AuthPolicy{}, "GET", "bob", http.StatusForbidden, ""},
+		{"unauthenticated", runtime.AuthPolicy{}, "GET", "", http.StatusUnauthorized, ""},
+		{"login redirect", runtime.AuthPolicy{LoginURL: "/login"}, "GET", "", http.StatusSeeOther,
+			"/login?return_to=%2Fposts%2F1%2Fedit%3Fx%3D1"},
+		{"no redirect for posts", runtime.AuthPolicy{LoginURL: "/login"}, "POST", "", http.StatusUnauthorized, ""},
+		{"json problem", runtime.AuthPolicy{LoginURL: "/login"}, "GET", "json", http.StatusUnauthorized, ""},
+		{"custom denial", runtime.AuthPolicy{OnDenied: func(w http.ResponseWriter, r *http.Request, status int, err error) {
+			w.WriteHeader(status + 1)
+		}}, "GET", "bob", http.StatusForbidden + 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := runtime.RequireAuth(authorizer, tt.policy, "posts", "edit", []string{"authenticated", "admin"}, next)
+			req := httptest.NewRequest(tt.method, "/posts/1/edit?x=1", nil)
+			if tt.user == "json" {
+				req, tt.user = runtime.WithFormat(req, runtime.FormatJSON), ""
+			}
+			if tt.user != "" {
+				req.Header.Set("User", tt.user)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if location := rec.Header().Get("Location"); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+			if tt.name == "json problem" && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want a problem document", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareRegistry(t *testing.T) {
+	for _, name := range []string{"Logger", "logging", "recovery", "request_id", "RequestID", "gzip", "security-headers", "RealIP", "CSRF"} {
+		if _, ok := runtime.LookupMiddleware(name); !ok {
+			t.Errorf("LookupMiddleware(%q) should find a built-in", name)
+		}
+	}
+	if _, ok := runtime.LookupMiddleware("RateLimiter"); ok {
+		t.Error("LookupMiddleware(RateLimiter) should fail")
+	}
+
+	var order []string
+	tag := func(name string) runtime.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	custom := map[string]runtime.Middleware{"first": tag("first"), "second": tag("second")}
+	handler := runtime.Chain(http.NotFoundHandler(), runtime.ResolveMiddleware(custom, "first", "RequestID", "second")...)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("middleware ran in order %v, want first,second", order)
+	}
+	if rec.Header().Get(runtime.RequestIDHeader) == "" {
+		t.Error("built-in RequestID should be resolved")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("ResolveMiddleware should panic for unknown middleware")
+		}
+	}()
+	runtime.ResolveMiddleware(nil, "RateLimiter")
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	handler := runtime.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = runtime.RequestIDFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(runtime.RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got != "abc-123" || rec.Header().Get(runtime.RequestIDHeader) != "abc-123" {
+		t.Errorf("incoming request ID should be kept, got %q", got)
+	}
+
+	req.Header.Set(runtime.RequestIDHeader, "bad id\n")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if len(got) != 32 {
+		t.Errorf("malformed request ID should be replaced, got %q", got)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := runtime.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	var buf strings.Builder
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	handler := runtime.Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/posts", nil))
+	for _, want := range []string{"method=POST", "path=/posts", "status=418"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log %q should contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	body := strings.Repeat("hello gluey ", 100)
+	handler := runtime.Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != body {
+		t.Errorf("decompressed body = %q", got)
+	}
+	if rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type should be sniffed from the uncompressed body, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	for _, encoding := range []string{"", "gzip;q=0"} {
+		req.Header.Set("Accept-Encoding", encoding)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+			t.Errorf("Accept-Encoding %q should not be compressed", encoding)
+		}
+	}
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	handler := runtime.SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "DENY")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("X-Content-Type-Options should be nosniff")
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("handlers should be able to override headers")
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS should only be sent over TLS")
+	}
+}
+
+func TestRealIPMiddleware(t *testing.T) {
+	tests := []struct {
+		name, remote, realIP, forwarded, want string
+	}{
+		{"direct client", "203.0.113.9:1234", "", "198.51.100.1", "203.0.113.9:1234"},
+		{"X-Real-IP", "10.0.0.2:1234", "198.51.100.1", "", "198.51.100.1"},
+		{"X-Forwarded-For", "127.0.0.1:1234", "", "1.2.3.4, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"only proxies", "127.0.0.1:1234", "", "10.0.0.4, 10.0.0.3", "10.0.0.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := runtime.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	var token string
+	handler := runtime.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = runtime.CSRFToken(r)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// A first visit gets the token cookie
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/posts/new", nil))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusNoContent || len(cookies) != 1 || cookies[0].Name != runtime.CSRFCookie || !cookies[0].HttpOnly {
+		t.Fatalf("GET = %d with cookies %v, want a CSRF cookie", rec.Code, cookies)
+	}
+	cookie := cookies[0]
+	if token == "" || token == cookie.Value {
+		t.Fatalf("CSRFToken() = %q, want a masked token", token)
+	}
+	field := string(runtime.CSRFField(token))
+	if !strings.Contains(field, `name="_csrf"`) || !strings.Contains(field, token) {
+		t.Errorf("CSRFField() = %s", field)
+	}
+	if runtime.CSRFField("") != "" {
+		t.Error("CSRFField() without token should render nothing")
+	}
+
+	post := func(form url.Values, header http.Header) int {
+		req := httptest.NewRequest("POST", "/posts", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	valid := url.Values{"_csrf": {token}}
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		header http.Header
+		want   int
+	}{
+		{"form token", valid, nil, http.StatusNoContent},
+		{"header token", nil, http.Header{"X-Csrf-Token": {token}}, http.StatusNoContent},
+		{"same origin", valid, http.Header{"Origin": {"http://example.com"}, "Sec-Fetch-Site": {"same-origin"}}, http.StatusNoContent},
+		{"missing token", nil, nil, http.StatusForbidden},
+		{"unmasked token", url.Values{"_csrf": {cookie.Value}}, nil, http.StatusForbidden},
+		{"cross site", valid, http.Header{"Sec-Fetch-Site": {"cross-site"}}, http.StatusForbidden},
+		{"foreign origin", valid, http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(tt.form, tt.header); got != tt.want {
+				t.Errorf("POST status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// API clients posting JSON without cookies need no token, unlike
+	// browsers sending the session cookies along
+	for _, tt := range []struct {
+		name   string
+		cookie bool
+		header string
+		want   int
+	}{
+		{"api client", false, "", http.StatusNoContent},
+		{"with cookies", true, "", http.StatusForbidden},
+		{"cross site", false, "cross-site", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest("POST", "/posts", strings.NewReader(`{"title":"Hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.cookie {
+			req.AddCookie(cookie)
+		}
+		if tt.header != "" {
+			req.Header.Set("Sec-Fetch-Site", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("JSON POST %s = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	// Without OnFailure, clients preferring JSON get a problem document
+	req := httptest.NewRequest("POST", "/posts", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(rec.Body.String(), `"detail":"invalid CSRF token"`) {
+		t.Errorf("JSON failure = %d %q with %v, want a 403 problem", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	// OnFailure renders the application's error page
+	var failure error
+	protected := runtime.CSRFProtection{OnFailure: func(w http.ResponseWriter, r *http.Request, err error) {
+		failure = err
+		w.WriteHeader(http.StatusForbidden)
+	}}.Handler(http.NotFoundHandler())
+	req = httptest.NewRequest("DELETE", "/posts/1", nil)
+	req.Header.Set("Sec-Fetch-Site", "same-site")
+	protected.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(failure, runtime.ErrCSRFOrigin) {
+		t.Errorf("OnFailure error = %v, want ErrCSRFOrigin", failure)
+	}
+}
+
+func TestSkipCSRF(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/stripe", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /contact", func(w http.ResponseWriter, r *http.Request) {})
+	handler := runtime.SkipCSRF(mux, runtime.CSRF(mux), "POST /webhooks/stripe")
+
+	for path, want := range map[string]int{"/webhooks/stripe": http.StatusOK, "/contact": http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		if rec.Code != want {
+			t.Errorf("POST %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("destroyed " + r.PathValue("id")))
+	})
+	mux.HandleFunc("PATCH /posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("updated " + r.FormValue("title")))
+	})
+	mux.HandleFunc("POST /posts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("created"))
+	})
+	handler := runtime.MethodOverride(mux)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		form   url.Values
+		header string
+		want   string
+	}{
+		{"form delete", "POST", "/posts/1", url.Values{"_method": {"delete"}}, "", "destroyed 1"},
+		{"form patch keeps fields", "POST", "/posts/1", url.Values{"_method": {"PATCH"}, "title": {"Hi"}}, "", "updated Hi"},
+		{"header", "POST", "/posts/2", nil, "DELETE", "destroyed 2"},
+		{"plain post", "POST", "/posts", url.Values{"title": {"Hi"}}, "", "created"},
+		{"unsupported method", "POST", "/posts", url.Values{"_method": {"GET"}}, "", "created"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				req.Header.Set(runtime.MethodOverrideHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Body.String() != tt.want {
+				t.Errorf("response = %d %q, want %q", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+
+	// Only POST requests are overridden
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/posts/1?_method=DELETE", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET with _method = %d, want 405", rec.Code)
+	}
+}
+
+func TestFormHelpers(t *testing.T) {
+	funcs := runtime.DefaultFuncMap()
+	formFor := funcs["form_for"].(func(string, ...any) template.HTML)
+	buttonTo := funcs["button_to"].(func(string, string, string, string, ...string) template.HTML)
+
+	if got := formFor("posts"); got != `<form method="POST" action="/posts">` {
+		t.Errorf("form_for new = %s", got)
+	}
+	if got := string(formFor("posts", 7)); got != `<form method="POST" action="/posts/7"><input type="hidden" name="_method" value="PATCH">` {
+		t.Errorf("form_for edit = %s", got)
+	}
+
+	got := string(buttonTo("Delete", "/posts/7", "delete", "tok", `class="danger"`))
+	for _, want := range []string{
+		`<form method="POST" action="/posts/7" class="button_to">`,
+		`<input type="hidden" name="_method" value="DELETE">`,
+		`<input type="hidden" name="_csrf" value="tok">`,
+		`<button type="submit" class="danger">Delete</button></form>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("button_to = %s, want it to contain %s", got, want)
+		}
+	}
+	if got := string(buttonTo("Search", "/search", "GET", "")); strings.Contains(got, "_method") || !strings.Contains(got, `method="GET"`) {
+		t.Errorf("button_to GET = %s", got)
+	}
+}
+
+func TestFlash(t *testing.T) {
+	// A post adds messages, the page it redirects to reads them once
+	post := httptest.NewRecorder()
+	postReq := httptest.NewRequest("POST", "/posts", nil)
+	runtime.AddFlash(post, postReq, "success", "Post created; 100% done, café")
+	runtime.AddFlash(post, postReq, "success", "Second")
+	runtime.AddFlash(post, postReq, "error", "Oops")
+	cookies := post.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != runtime.FlashCookie || !cookies[0].HttpOnly {
+		t.Fatalf("AddFlash() cookies = %v, want one flash cookie", cookies)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/posts", nil)
+	req.AddCookie(cookies[0])
+	flash := runtime.Flashes(rec, req)
+	want := runtime.Flash{"success": {"Post created; 100% done, café", "Second"}, "error": {"Oops"}}
+	if !reflect.DeepEqual(flash, want) {
+		t.Errorf("Flashes() = %v, want %v", flash, want)
+	}
+	if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("Flashes() cookies = %v, want the flash cookie cleared", cleared)
+	}
+
+	// Tampered cookies are ignored
+	tampered := *cookies[0]
+	tampered.Value = "e30" + tampered.Value[strings.Index(tampered.Value, "."):]
+	req = httptest.NewRequest("GET", "/posts", nil)
+	req.AddCookie(&tampered)
+	if flash := runtime.Flashes(httptest.NewRecorder(), req); len(flash) != 0 {
+		t.Errorf("Flashes(tampered) = %v, want none", flash)
+	}
+}
+
+func TestFlashSession(t *testing.T) {
+	manager := session.NewManager(session.NewMemoryStore())
+	var flash runtime.Flash
+	handler := manager.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			runtime.AddFlash(w, r, "info", "Saved")
+			runtime.AddFlash(w, r, "info", "Mailed")
+			return
+		}
+		flash = runtime.Flashes(w, r)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" {
+		t.Fatalf("AddFlash() cookies = %v, want only the session cookie", cookies)
+	}
+
+	get := func() runtime.Flash {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(cookies[0])
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return flash
+	}
+	if got := get(); !reflect.DeepEqual(got, runtime.Flash{"info": {"Saved", "Mailed"}}) {
+		t.Errorf("Flashes() = %v, want both messages", got)
+	}
+	if got := get(); len(got) != 0 {
+		t.Errorf("second Flashes() = %v, want none", got)
+	}
+}
+
+func TestTemplateLayouts(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"layouts/application.html": `<title>{{block "title" .}}{{.Title}}{{end}}</title>{{block "head" .}}{{end}}<main>{{.Content}}</main>{{block "scripts" .}}{{end}}`,
+		"layouts/admin.html":       `<admin>{{.Content}}</admin>`,
+		"layouts/print.html":       `<print>{{.Content}}</print>`,
+		"shared/_flash.html":       `{{range $level, $messages := .}}{{range $messages}}<flash {{$level}}>{{.}}</flash>{{end}}{{end}}`,
+		"posts/_row.html":          `<li>{{.}}</li>`,
+		"posts/index.html":         `{{define "title"}}All posts{{end}}{{define "scripts"}}<script src="/posts.js"></script>{{end}}{{define "content"}}{{template "_flash.html" .Flash}}<ul>{{range .Posts}}{{template "posts/_row.html" .}}{{end}}</ul>{{end}}`,
+		"pages/about.html":         `<p>About {{.Title}}</p>`,
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(src), 0o644)
+	}
+
+	views := runtime.NewTemplateEngine()
+	if err := views.LoadTemplates(dir); err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+
+	data := map[string]any{
+		"Title": "Posts",
+		"Posts": []string{"a", "<b>"},
+		"Flash": runtime.Flash{"success": {"Saved"}},
+	}
+	var buf strings.Builder
+	if err := views.RenderLayout(&buf, "application", "posts/index", data); err != nil {
+		t.Fatalf("RenderLayout() failed: %v", err)
+	}
+	want := `<title>All posts</title><main><flash success>Saved</flash><ul><li>a</li><li>&lt;b&gt;</li></ul></main><script src="/posts.js"></script>`
+	if buf.String() != want {
+		t.Errorf("RenderLayout() = %s, want %s", buf.String(), want)
+	}
+
+	// Views without a content define are the content, and other views do
+	// not leak their blocks
+	buf.Reset()
+	if err := views.RenderLayout(&buf, "application", "pages/about.html", map[string]any{"Title": "Us"}); err != nil {
+		t.Fatalf("RenderLayout() failed: %v", err)
+	}
+	if want := `<title>Us</title><main><p>About Us</p></main>`; buf.String() != want {
+		t.Errorf("RenderLayout() = %s, want %s", buf.String(), want)
+	}
+
+	// The route layout replaces the default, and WithLayout the route's
+	render := func(r *http.Request) string {
+		var buf strings.Builder
+		if err := views.RenderRequest(&buf, r, "pages/about", map[string]any{"Title": "Us"}); err != nil {
+			t.Fatalf("RenderRequest() failed: %v", err)
+		}
+		return buf.String()
+	}
+	var got []string
+	handler := runtime.UseLayout("admin", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, render(r))
+		got = append(got, render(runtime.WithLayout(r, "print")))
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/about", nil))
+	if want := []string{"<admin><p>About Us</p></admin>", "<print><p>About Us</p></print>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderRequest() = %q, want %q", got, want)
+	}
+	if got := render(httptest.NewRequest("GET", "/about", nil)); !strings.HasPrefix(got, "<title>") {
+		t.Errorf("RenderRequest() without layout = %s, want the default layout", got)
+	}
+
+	views.DefaultLayout = "missing"
+	if got := render(httptest.NewRequest("GET", "/about", nil)); got != "<p>About Us</p>" {
+		t.Errorf("RenderRequest() with a missing default = %s, want the view alone", got)
+	}
+	if err := views.RenderLayout(io.Discard, "missing", "pages/about", nil); err == nil {
+		t.Error("RenderLayout() with a missing layout should fail")
+	}
+
+	os.WriteFile(filepath.Join(dir, "pages/broken.html"), []byte("<p>\n{{if}}"), 0o644)
+	if err := runtime.NewTemplateEngine().LoadTemplates(dir); err == nil || !strings.Contains(err.Error(), "pages/broken.html:2") {
+		t.Errorf("LoadTemplates() error = %v, want the file and line of the broken view", err)
+	}
+}
+
+func TestTemplateEngineFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/application.html": {Data: []byte(`<main>{{.Content}}</main>`)},
+		"shared/_nav.html":         {Data: []byte("<nav>\n{{index . 5}}</nav>")},
+		"posts/index.html":         {Data: []byte(`{{define "content"}}posts{{end}}`)},
+		"users/index.html":         {Data: []byte(`{{define "content"}}users{{end}}`)},
+		"pages/nav.html":           {Data: []byte(`{{template "_nav.html" .}}`)},
+	}
+	views := runtime.NewTemplateEngine()
+	if err := views.LoadFS(fsys); err != nil {
+		t.Fatalf("LoadFS() failed: %v", err)
+	}
+
+	// Views defining the same templates render concurrently
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view, want := "posts/index", "<main>posts</main>"
+			if i%2 == 1 {
+				view, want = "users/index", "<main>users</main>"
+			}
+			var buf strings.Builder
+			if err := views.RenderLayout(&buf, "application", view, nil); err != nil || buf.String() != want {
+				t.Errorf("RenderLayout(%s) = %q, %v, want %q", view, buf.String(), err, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Errors of shared partials name their file and line
+	err := views.Render(io.Discard, "pages/nav", []int{})
+	if err == nil || !strings.Contains(err.Error(), "shared/_nav.html:2:") {
+		t.Errorf("Render() error = %v, want the file and line of the partial", err)
+	}
+	if err := views.Render(io.Discard, "pages/missing", nil); err == nil || !strings.Contains(err.Error(), "pages/missing.html") {
+		t.Errorf("Render(missing) error = %v, want the missing view", err)
+	}
+}
+
+func TestTemplateEngineDev(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pages/about.html")
+	os.MkdirAll(filepath.Dir(file), 0o755)
+	write := func(src string, age time.Duration) {
+		os.WriteFile(file, []byte(src), 0o644)
+		mtime := time.Now().Add(-age)
+		os.Chtimes(file, mtime, mtime)
+	}
+	render := func(views *runtime.TemplateEngine) string {
+		var buf strings.Builder
+		if err := views.Render(&buf, "pages/about", nil); err != nil {
+			return err.Error()
+		}
+		return buf.String()
+	}
+
+	write("v1", time.Hour)
+	dev, prod := runtime.NewTemplateEngine(), runtime.NewTemplateEngine()
+	dev.Dev = true
+	for _, views := range []*runtime.TemplateEngine{dev, prod} {
+		if err := views.LoadTemplates(dir); err != nil {
+			t.Fatalf("LoadTemplates() failed: %v", err)
+		}
+	}
+
+	write("v2", time.Minute)
+	if got := render(dev); got != "v2" {
+		t.Errorf("dev Render() = %q, want the edited view", got)
+	}
+	if got := render(prod); got != "v1" {
+		t.Errorf("Render() = %q, want the loaded view", got)
+	}
+
+	write("{{end}}", 0)
+	if got := render(dev); !strings.Contains(got, "pages/about.html:1") {
+		t.Errorf("dev Render() of a broken view = %q, want its file and line", got)
+	}
+}
+
+func TestBaseControllerRender(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "layouts"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "posts"), 0o755)
+	os.WriteFile(filepath.Join(dir, "layouts/application.html"), []byte(`<main>{{.Content}}</main>{{range .Flash.notice}}{{.}}{{end}}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "posts/show.html"), []byte(`{{define "content"}}{{.Title}}{{end}}`), 0o644)
+	c := runtime.NewBaseController(dir)
+
+	post := httptest.NewRecorder()
+	runtime.AddFlash(post, httptest.NewRequest("POST", "/posts", nil), "notice", "Saved")
+
+	req := httptest.NewRequest("GET", "/posts/1", nil)
+	req.AddCookie(post.Result().Cookies()[0])
+	rec := httptest.NewRecorder()
+	if err := c.Render(rec, req, "posts/show", map[string]any{"Title": "Hello"}); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if rec.Body.String() != "<main>Hello</main>Saved" || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Render() = %q with %v", rec.Body.String(), rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := c.Render(rec, httptest.NewRequest("GET", "/", nil), "posts/missing", nil); err == nil || rec.Code != http.StatusInternalServerError {
+		t.Errorf("Render(missing) = %d, %v, want a 500", rec.Code, err)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		path        string
+		accept      string
+		contentType string
+		formats     []string
+		want        string
+	}{
+		{"/posts", "", "", []string{"html", "json"}, "html"},
+		{"/posts", "", "", []string{"json", "html"}, "json"},
+		{"/posts.json", "text/html", "", []string{"html", "json"}, "json"},
+		{"/posts.json", "", "", []string{"html"}, "html"},
+		{"/posts", "application/json", "", []string{"html", "json"}, "json"},
+		{"/posts", "application/vnd.api+json", "", []string{"html", "json"}, "json"},
+		{"/posts", "text/html,application/xhtml+xml,*/*;q=0.8", "", []string{"html", "json"}, "html"},
+		{"/posts", "text/html;q=0.5, application/json", "", []string{"html", "json"}, "json"},
+		{"/posts", "application/json", "", []string{"html"}, "html"},
+		{"/posts", "image/png", "", nil, "html"},
+		{"/posts", "", "application/json; charset=utf-8", []string{"html", "json"}, "json"},
+		{"/posts", "*/*", "application/json", []string{"html", "json"}, "json"},
+		{"/posts", "text/html", "application/json", []string{"html", "json"}, "html"},
+		{"/posts", "", "application/json", []string{"html"}, "html"},
+		{"/posts", "", "application/x-www-form-urlencoded", []string{"html", "json"}, "html"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		req.Header.Set("Content-Type", tt.contentType)
+		if got := runtime.NegotiateFormat(req, tt.formats...); got != tt.want {
+			t.Errorf("NegotiateFormat(%s, Accept %q, Content-Type %q, %v) = %q, want %q", tt.path, tt.accept, tt.contentType, tt.formats, got, tt.want)
+		}
+	}
+}
+
+func TestUseFormats(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /posts/{id}", runtime.UseFormats([]string{"html", "json"}, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, runtime.FormatFrom(r.Context())+" "+r.PathValue("id"))
+	}))
+
+	for path, want := range map[string]string{"/posts/1": "html 1", "/posts/1.json": "json 1"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Body.String() != want {
+			t.Errorf("GET %s = %q, want %q", path, rec.Body.String(), want)
+		}
+	}
+	if format := runtime.FormatFrom(httptest.NewRequest("GET", "/", nil).Context()); format != runtime.FormatHTML {
+		t.Errorf("FormatFrom() without a format = %q, want html", format)
+	}
+}
+
+func TestRespond(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "posts"), 0o755)
+	os.WriteFile(filepath.Join(dir, "posts/show.html"), []byte(`<h1>{{.Title}}</h1>`), 0o644)
+	c := runtime.NewBaseController(dir)
+	data := map[string]any{"Title": "Hello"}
+
+	rec := httptest.NewRecorder()
+	req := runtime.WithFormat(httptest.NewRequest("GET", "/posts/1.json", nil), runtime.FormatJSON)
+	if err := c.Respond(rec, req, "posts/show", data); err != nil {
+		t.Fatalf("Respond() failed: %v", err)
+	}
+	if rec.Body.String() != `{"Title":"Hello"}`+"\n" || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("JSON Respond() = %q with %v", rec.Body.String(), rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := c.Respond(rec, httptest.NewRequest("GET", "/posts/1", nil), "posts/show", data); err != nil {
+		t.Fatalf("Respond() failed: %v", err)
+	}
+	if rec.Body.String() != "<h1>Hello</h1>" {
+		t.Errorf("HTML Respond() = %q, want the view", rec.Body.String())
+	}
+
+	// Validation errors are a 422 problem in JSON, and the view otherwise
+	errs := runtime.ValidationErrors{{Field: "title", Message: "is required"}}
+	rec = httptest.NewRecorder()
+	if err := c.RespondInvalid(rec, req, "posts/show", data, errs); err != nil {
+		t.Fatalf("RespondInvalid() failed: %v", err)
+	}
+	want := `{"title":"Validation failed","status":422,"errors":[{"field":"title","message":"is required"}]}` + "\n"
+	if rec.Code != http.StatusUnprocessableEntity || rec.Body.String() != want || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("RespondInvalid() = %d %q with %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	// Handlers without BaseController render with the engine of UseViews
+	engine := runtime.NewTemplateEngine()
+	if err := engine.LoadTemplates(dir); err != nil {
+		t.Fatalf("LoadTemplates() failed: %v", err)
+	}
+	handler := runtime.UseViews(engine, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runtime.Respond(w, r, "posts/show", data)
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/posts/1", nil))
+	if rec.Body.String() != "<h1>Hello</h1>" {
+		t.Errorf("runtime.Respond() = %q, want the view", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	if err := runtime.Respond(rec, httptest.NewRequest("GET", "/posts/1", nil), "posts/show", data); err == nil || rec.Code != http.StatusInternalServerError {
+		t.Errorf("runtime.Respond() without views = %d, %v, want a 500 error", rec.Code, err)
+	}
+
+	rec = httptest.NewRecorder()
+	runtime.WriteProblem(rec, runtime.Problem{Status: http.StatusNotFound})
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"title":"Not Found"`) {
+		t.Errorf("WriteProblem() = %d %q, want the status text as title", rec.Code, rec.Body.String())
+	}
+}
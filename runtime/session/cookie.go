@@ -0,0 +1,110 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTooLarge is returned by CookieStore.Save when the encrypted session
+// does not fit in a cookie.
+var ErrTooLarge = errors.New("session too large for a cookie")
+
+// maxCookieSize is the largest cookie value browsers are known to accept,
+// leaving room for the cookie attributes.
+const maxCookieSize = 3800
+
+// cookieAAD binds ciphertexts to their use as session cookies.
+var cookieAAD = []byte("gluey session")
+
+// CookieStore keeps sessions in the cookie itself, encrypted and
+// authenticated with AES-GCM. Nothing is stored on the server, so Delete
+// cannot revoke a copied cookie before it expires.
+type CookieStore struct {
+	aeads []cipher.AEAD
+}
+
+// NewCookieStore returns a store using keys of 16, 24 or 32 bytes. The
+// first key encrypts new cookies; all keys decrypt, so keys can be rotated
+// by prepending the new one and dropping the oldest once its cookies have
+// expired.
+func NewCookieStore(keys ...[]byte) (*CookieStore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("session: cookie store requires at least one key")
+	}
+	s := &CookieStore{}
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %d: %w", i, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %d: %w", i, err)
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s, nil
+}
+
+// cookiePayload is the plaintext of a session cookie.
+type cookiePayload struct {
+	Values map[string]string `json:"v"`
+	Expiry int64             `json:"e"`
+}
+
+// Load decrypts the session in token.
+func (s *CookieStore) Load(_ context.Context, token string) (map[string]string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	for _, aead := range s.aeads {
+		if len(sealed) < aead.NonceSize() {
+			return nil, ErrNotFound
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, cookieAAD)
+		if err != nil {
+			continue
+		}
+		var p cookiePayload
+		if err := json.Unmarshal(plaintext, &p); err != nil {
+			return nil, ErrNotFound
+		}
+		if time.Now().Unix() >= p.Expiry {
+			return nil, ErrNotFound
+		}
+		return p.Values, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Save encrypts values with the first key and returns them as the token.
+func (s *CookieStore) Save(_ context.Context, _ string, values map[string]string, expiry time.Time) (string, error) {
+	plaintext, err := json.Marshal(cookiePayload{Values: values, Expiry: expiry.Unix()})
+	if err != nil {
+		return "", err
+	}
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, cookieAAD))
+	if len(token) > maxCookieSize {
+		return "", ErrTooLarge
+	}
+	return token, nil
+}
+
+// Delete does nothing: the manager clears the cookie.
+func (s *CookieStore) Delete(context.Context, string) error {
+	return nil
+}
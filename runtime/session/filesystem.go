@@ -0,0 +1,102 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FilesystemStore keeps each session in a file of a directory, so sessions
+// survive restarts. Expired files are removed when they are loaded.
+type FilesystemStore struct {
+	dir string
+}
+
+// NewFilesystemStore returns a store keeping sessions in dir, which is
+// created if needed.
+func NewFilesystemStore(dir string) (*FilesystemStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FilesystemStore{dir: dir}, nil
+}
+
+// fileSession is the content of a session file.
+type fileSession struct {
+	Values map[string]string `json:"values"`
+	Expiry time.Time         `json:"expiry"`
+}
+
+// Load reads the session file.
+func (s *FilesystemStore) Load(_ context.Context, token string) (map[string]string, error) {
+	// Tokens come from cookies, never use them as paths unchecked
+	if !validID(token) {
+		return nil, ErrNotFound
+	}
+	b, err := os.ReadFile(s.path(token))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored fileSession
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(stored.Expiry) {
+		os.Remove(s.path(token))
+		return nil, ErrNotFound
+	}
+	return stored.Values, nil
+}
+
+// Save writes the session file atomically.
+func (s *FilesystemStore) Save(_ context.Context, token string, values map[string]string, expiry time.Time) (string, error) {
+	if !validID(token) {
+		token = newID()
+	}
+	b, err := json.Marshal(fileSession{Values: values, Expiry: expiry})
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), s.path(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Delete removes the session file.
+func (s *FilesystemStore) Delete(_ context.Context, token string) error {
+	if !validID(token) {
+		return nil
+	}
+	err := os.Remove(s.path(token))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of the session.
+func (s *FilesystemStore) path(token string) string {
+	return filepath.Join(s.dir, token)
+}
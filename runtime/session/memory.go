@@ -0,0 +1,77 @@
+package session
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in memory. Sessions are lost on restart and
+// are not shared between processes. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	saves    int
+}
+
+// memorySession is a stored session.
+type memorySession struct {
+	values map[string]string
+	expiry time.Time
+}
+
+// sweepEvery is the number of saves between removals of expired sessions.
+const sweepEvery = 100
+
+// NewMemoryStore returns an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession)}
+}
+
+// Load returns a copy of the values of the session.
+func (s *MemoryStore) Load(_ context.Context, token string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !time.Now().Before(stored.expiry) {
+		delete(s.sessions, token)
+		return nil, ErrNotFound
+	}
+	return maps.Clone(stored.values), nil
+}
+
+// Save stores a copy of values.
+func (s *MemoryStore) Save(_ context.Context, token string, values map[string]string, expiry time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token == "" {
+		token = newID()
+	}
+	s.sessions[token] = memorySession{values: maps.Clone(values), expiry: expiry}
+
+	s.saves++
+	if s.saves%sweepEvery == 0 {
+		now := time.Now()
+		for id, stored := range s.sessions {
+			if !now.Before(stored.expiry) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+	return token, nil
+}
+
+// Delete removes the session.
+func (s *MemoryStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+	return nil
+}
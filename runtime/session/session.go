@@ -0,0 +1,332 @@
+// Package session loads and saves the sessions of HTTP requests.
+//
+// A Manager installs the session of each request in its context, and saves
+// it before the response headers are written. Sessions hold string values
+// and are kept by a Store: encrypted in the cookie itself, in memory or in
+// files.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is returned by stores when a session does not exist or has
+// expired.
+var ErrNotFound = errors.New("session not found")
+
+// Store keeps session values between requests. The token is the value of
+// the session cookie.
+type Store interface {
+	// Load returns the values of the session identified by token, or
+	// ErrNotFound.
+	Load(ctx context.Context, token string) (map[string]string, error)
+	// Save stores values until expiry and returns the token of the
+	// session. An empty token starts a new session.
+	Save(ctx context.Context, token string, values map[string]string, expiry time.Time) (string, error)
+	// Delete removes the session identified by token. Deleting a missing
+	// session is not an error.
+	Delete(ctx context.Context, token string) error
+}
+
+// Session is the session of a request. It is not safe for concurrent use.
+type Session struct {
+	token     string
+	values    map[string]string
+	changed   bool
+	renew     bool
+	destroyed bool
+}
+
+// Get returns the value of key, or an empty string.
+func (s *Session) Get(key string) string {
+	return s.values[key]
+}
+
+// Lookup returns the value of key and whether it is set.
+func (s *Session) Lookup(key string) (string, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set sets the value of key.
+func (s *Session) Set(key, value string) {
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[key] = value
+	s.changed = true
+	s.destroyed = false
+}
+
+// Delete removes key.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Pop returns the value of key and removes it.
+func (s *Session) Pop(key string) string {
+	v := s.values[key]
+	s.Delete(key)
+	return v
+}
+
+// Keys returns the sorted keys of the session.
+func (s *Session) Keys() []string {
+	return slices.Sorted(maps.Keys(s.values))
+}
+
+// RenewToken gives the session a new token when it is saved. Call it when
+// the user logs in or out to prevent session fixation.
+func (s *Session) RenewToken() {
+	s.renew = true
+	s.changed = true
+}
+
+// Destroy removes all values and deletes the session from the store.
+func (s *Session) Destroy() {
+	s.values = nil
+	s.destroyed = true
+	s.changed = true
+}
+
+// sessionKey is the context key of the session.
+type sessionKey struct{}
+
+// NewContext returns a copy of ctx carrying s.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session installed by a Manager, if any.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// Get returns the session of r. Without a Manager it returns an empty
+// session that is never saved.
+func Get(r *http.Request) *Session {
+	if s, ok := FromContext(r.Context()); ok {
+		return s
+	}
+	return &Session{}
+}
+
+// Manager loads and saves the sessions of requests.
+type Manager struct {
+	// Store keeps the sessions.
+	Store Store
+	// Cookie is the name of the session cookie. It defaults to "session".
+	Cookie string
+	// Path, Domain and SameSite set the attributes of the session cookie.
+	// Path defaults to "/" and SameSite to lax.
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+	// Secure marks the session cookie as HTTPS only.
+	Secure bool
+	// Lifetime is how long sessions live after their last change. It
+	// defaults to DefaultLifetime.
+	Lifetime time.Duration
+}
+
+// DefaultLifetime is the session lifetime used when Manager.Lifetime is
+// zero.
+const DefaultLifetime = 24 * time.Hour
+
+// NewManager returns a manager with the default cookie settings.
+func NewManager(store Store) *Manager {
+	return &Manager{Store: store}
+}
+
+// Handler loads the session of each request before calling next and saves
+// it with the response. Errors are logged, and answered with 500 if the
+// response has not started.
+func (m *Manager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.load(r)
+		sw := &sessionWriter{ResponseWriter: w, manager: m, session: s, request: r}
+		next.ServeHTTP(sw, r.WithContext(NewContext(r.Context(), s)))
+		if !sw.committed {
+			if err := sw.commit(); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+// load returns the session of the request cookie, or a new one.
+func (m *Manager) load(r *http.Request) *Session {
+	cookie, err := r.Cookie(m.cookieName())
+	if err != nil || cookie.Value == "" {
+		return &Session{}
+	}
+	values, err := m.Store.Load(r.Context(), cookie.Value)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			slog.ErrorContext(r.Context(), "loading session", slog.Any("error", err))
+		}
+		// Drop the stale cookie with the next save
+		return &Session{changed: true, destroyed: true}
+	}
+	return &Session{token: cookie.Value, values: values}
+}
+
+// save writes s to the store and sets or clears the cookie.
+func (m *Manager) save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	if !s.changed {
+		return nil
+	}
+	ctx := r.Context()
+
+	if s.destroyed || len(s.values) == 0 {
+		if s.token != "" {
+			if err := m.Store.Delete(ctx, s.token); err != nil {
+				return err
+			}
+		}
+		if _, err := r.Cookie(m.cookieName()); err == nil {
+			http.SetCookie(w, m.cookie("", -1))
+		}
+		return nil
+	}
+
+	token := s.token
+	if s.renew && token != "" {
+		if err := m.Store.Delete(ctx, token); err != nil {
+			return err
+		}
+		token = ""
+	}
+	lifetime := m.lifetime()
+	token, err := m.Store.Save(ctx, token, s.values, time.Now().Add(lifetime))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, m.cookie(token, int(lifetime/time.Second)))
+	return nil
+}
+
+// cookie returns the session cookie with the given value and max age.
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	path := m.Path
+	if path == "" {
+		path = "/"
+	}
+	sameSite := m.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     m.cookieName(),
+		Value:    value,
+		Path:     path,
+		Domain:   m.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+}
+
+// cookieName returns the name of the session cookie.
+func (m *Manager) cookieName() string {
+	if m.Cookie == "" {
+		return "session"
+	}
+	return m.Cookie
+}
+
+// lifetime returns the session lifetime.
+func (m *Manager) lifetime() time.Duration {
+	if m.Lifetime <= 0 {
+		return DefaultLifetime
+	}
+	return m.Lifetime
+}
+
+// sessionWriter saves the session just before the headers are written.
+type sessionWriter struct {
+	http.ResponseWriter
+	manager   *Manager
+	session   *Session
+	request   *http.Request
+	committed bool
+	// failed is set when the session could not be saved and the response
+	// was replaced with an error.
+	failed bool
+}
+
+// commit saves the session once.
+func (w *sessionWriter) commit() error {
+	w.committed = true
+	err := w.manager.save(w.ResponseWriter, w.request, w.session)
+	if err != nil {
+		slog.ErrorContext(w.request.Context(), "saving session", slog.Any("error", err))
+	}
+	return err
+}
+
+// WriteHeader saves the session before sending the headers.
+func (w *sessionWriter) WriteHeader(status int) {
+	if w.failed {
+		return
+	}
+	if !w.committed && status >= http.StatusOK {
+		if err := w.commit(); err != nil {
+			w.failed = true
+			http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write saves the session before the body. The body is discarded if the
+// session could not be saved.
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.committed {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// newID returns a random 256-bit session ID.
+func newID() string {
+	var b [32]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// validID reports whether id may have been returned by newID.
+func validID(id string) bool {
+	if len(id) != base64.RawURLEncoding.EncodedLen(32) {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
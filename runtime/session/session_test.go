@@ -0,0 +1,188 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobijan/gluey/runtime/session"
+)
+
+func TestStores(t *testing.T) {
+	cookieStore, err := session.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewCookieStore() failed: %v", err)
+	}
+	fileStore, err := session.NewFilesystemStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() failed: %v", err)
+	}
+
+	stores := map[string]session.Store{
+		"cookie":     cookieStore,
+		"memory":     session.NewMemoryStore(),
+		"filesystem": fileStore,
+	}
+	ctx := context.Background()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			token, err := store.Save(ctx, "", map[string]string{"user_id": "42"}, time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("Save() failed: %v", err)
+			}
+			values, err := store.Load(ctx, token)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if values["user_id"] != "42" {
+				t.Errorf("user_id = %q, want 42", values["user_id"])
+			}
+
+			if _, err := store.Load(ctx, "missing"); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Load(missing) error = %v, want ErrNotFound", err)
+			}
+			if _, err := store.Load(ctx, "../../etc/passwd"); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Load(path) error = %v, want ErrNotFound", err)
+			}
+
+			expired, err := store.Save(ctx, "", map[string]string{"a": "b"}, time.Now().Add(-time.Second))
+			if err != nil {
+				t.Fatalf("Save() failed: %v", err)
+			}
+			if _, err := store.Load(ctx, expired); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Load(expired) error = %v, want ErrNotFound", err)
+			}
+
+			if name == "cookie" {
+				return
+			}
+			if err := store.Delete(ctx, token); err != nil {
+				t.Fatalf("Delete() failed: %v", err)
+			}
+			if _, err := store.Load(ctx, token); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Load(deleted) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCookieStoreKeys(t *testing.T) {
+	oldKey := []byte("old-key-old-key-")
+	newKey := []byte("new-key-new-key-")
+	ctx := context.Background()
+
+	if _, err := session.NewCookieStore(); err == nil {
+		t.Error("NewCookieStore() without keys should fail")
+	}
+	if _, err := session.NewCookieStore([]byte("short")); err == nil {
+		t.Error("NewCookieStore() with a short key should fail")
+	}
+
+	old, _ := session.NewCookieStore(oldKey)
+	token, err := old.Save(ctx, "", map[string]string{"k": "v"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	rotated, _ := session.NewCookieStore(newKey, oldKey)
+	if values, err := rotated.Load(ctx, token); err != nil || values["k"] != "v" {
+		t.Errorf("rotated Load() = %v, %v, want the old session", values, err)
+	}
+	retired, _ := session.NewCookieStore(newKey)
+	if _, err := retired.Load(ctx, token); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Load() with a retired key error = %v, want ErrNotFound", err)
+	}
+
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := old.Load(ctx, string(tampered)); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Load(tampered) error = %v, want ErrNotFound", err)
+	}
+
+	big := map[string]string{"data": strings.Repeat("x", 5000)}
+	if _, err := old.Save(ctx, "", big, time.Now().Add(time.Hour)); !errors.Is(err, session.ErrTooLarge) {
+		t.Errorf("Save(big) error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	store := session.NewMemoryStore()
+	manager := session.NewManager(store)
+
+	handler := manager.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.Get(r)
+		switch r.URL.Path {
+		case "/login":
+			s.RenewToken()
+			s.Set("user_id", "42")
+		case "/logout":
+			s.Destroy()
+		}
+		w.Write([]byte(s.Get("user_id")))
+	}))
+
+	serve := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/", nil)
+	if len(rec.Result().Cookies()) != 0 {
+		t.Errorf("unchanged session should not set a cookie, got %v", rec.Result().Cookies())
+	}
+
+	rec = serve("/login", &http.Cookie{Name: "session", Value: "forged"})
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value == "forged" {
+		t.Fatalf("login cookies = %v, want a new session cookie", cookies)
+	}
+	cookie := cookies[0]
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge != int(session.DefaultLifetime/time.Second) {
+		t.Errorf("session cookie attributes = %+v", cookie)
+	}
+
+	if rec := serve("/", cookie); rec.Body.String() != "42" {
+		t.Errorf("session user_id = %q, want 42", rec.Body.String())
+	}
+
+	rec = serve("/logout", cookie)
+	cookies = rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("logout cookies = %v, want the session cookie cleared", cookies)
+	}
+	if _, err := store.Load(context.Background(), cookie.Value); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("logout should delete the session, Load() error = %v", err)
+	}
+}
+
+func TestManagerSaveError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store, err := session.NewFilesystemStore(dir)
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() failed: %v", err)
+	}
+	os.RemoveAll(dir)
+
+	handler := session.NewManager(store).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session.Get(r).Set("k", "v")
+		w.Write([]byte("secret"))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("failed save = %d %q, want a 500 without the body", rec.Code, rec.Body.String())
+	}
+}
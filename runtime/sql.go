@@ -0,0 +1,182 @@
+package runtime
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL flavor spoken by a database.
+type Dialect string
+
+// Supported SQL dialects.
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Placeholder returns the placeholder for the nth (1-based) query argument.
+func (d Dialect) Placeholder(n int) string {
+	if d == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Rebind replaces the ? placeholders of query with the dialect's
+// placeholders. Question marks inside quoted strings and identifiers are
+// left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// DBTX is the subset of *sql.DB and *sql.Tx used by generated SQL
+// repositories, so they can run inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Where builds a WHERE clause from conditions joined with AND. Arguments
+// are always passed as ? query parameters, never interpolated; Rebind the
+// final query for the dialect.
+type Where struct {
+	dialect Dialect
+	conds   []string
+	args    []any
+}
+
+// NewWhere returns an empty WHERE clause for the dialect.
+func NewWhere(dialect Dialect) *Where {
+	return &Where{dialect: dialect}
+}
+
+// Equal adds a condition that column equals value. column must be a quoted
+// identifier.
+func (w *Where) Equal(column string, value any) *Where {
+	w.conds = append(w.conds, column+" = ?")
+	w.args = append(w.args, value)
+	return w
+}
+
+// Search adds a condition that any of columns contains term, ignoring case.
+// columns must be quoted identifiers. LIKE wildcards in term match
+// literally.
+func (w *Where) Search(columns []string, term string) *Where {
+	if len(columns) == 0 {
+		return w
+	}
+
+	op := "LIKE"
+	if w.dialect == Postgres {
+		op = "ILIKE"
+	}
+	pattern := "%" + EscapeLike(term) + "%"
+
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf(`%s %s ? ESCAPE '\'`, column, op)
+		w.args = append(w.args, pattern)
+	}
+	w.conds = append(w.conds, "("+strings.Join(parts, " OR ")+")")
+	return w
+}
+
+// String returns the clause with a leading space, or an empty string if
+// there are no conditions.
+func (w *Where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// Args returns the query arguments in placeholder order.
+func (w *Where) Args() []any {
+	return w.args
+}
+
+// CheckAffected returns err, or ErrNotFound if the statement that produced
+// res affected no rows.
+func CheckAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EscapeLike escapes the LIKE wildcards in s using a backslash.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// JSON stores the value pointed to by v as a JSON text column. It is used
+// for array and map fields.
+func JSON(v any) interface {
+	sql.Scanner
+	driver.Valuer
+} {
+	return jsonColumn{v: v}
+}
+
+// jsonColumn implements sql.Scanner and driver.Valuer for JSON columns.
+type jsonColumn struct {
+	v any
+}
+
+// Value encodes the value as JSON.
+func (j jsonColumn) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan decodes a JSON column. NULL leaves the value unchanged.
+func (j jsonColumn) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), j.v)
+	case []byte:
+		return json.Unmarshal(src, j.v)
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON column", src)
+	}
+}
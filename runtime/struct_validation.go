@@ -0,0 +1,376 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ValidateStruct validates a struct based on its `validate` tags.
+//
+// Rules are separated by commas:
+//
+//	required          value must not be the zero value (or blank string)
+//	omitempty         skip the remaining rules when the value is empty
+//	min=N, max=N      length for strings, slices and maps; value for numbers
+//	email, url        string formats
+//	uuid, date        string formats (date is YYYY-MM-DD)
+//	datetime          RFC 3339 or datetime-local string
+//	oneof=a b c       value must be one of the space-separated options;
+//	                  options with spaces, commas or quotes are single-quoted,
+//	                  as in oneof='in progress' done, with quotes doubled
+//	pattern=REGEX     value must match the regular expression; must be last
+//
+// Errors are keyed by the field's form tag, then its json tag, then its Go
+// name. Rule plans are built once per struct type and cached.
+func ValidateStruct(s any) ValidationErrors {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ValidationErrors{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ValidationErrors{}
+	}
+
+	p := planFor(rv.Type())
+	errs := make(ValidationErrors, 0)
+
+	for _, f := range p.fields {
+		if f.err != nil {
+			errs = append(errs, ValidationError{
+				Field:   f.name,
+				Message: f.err.Error(),
+			})
+			continue
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		isNil := false
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				isNil = true
+				break
+			}
+			fv = fv.Elem()
+		}
+
+		empty := isNil || isEmptyValue(fv)
+		if f.required && empty {
+			errs = append(errs, ValidationError{Field: f.name, Message: "is required"})
+			continue
+		}
+		if isNil || (f.omitEmpty && empty) {
+			continue
+		}
+
+		for _, r := range f.rules {
+			if msg, ok := r(fv); !ok {
+				errs = append(errs, ValidationError{Field: f.name, Message: msg})
+			}
+		}
+	}
+
+	return errs
+}
+
+// structPlan is the cached set of validation rules for a struct type.
+type structPlan struct {
+	fields []fieldPlan
+}
+
+// fieldPlan holds the parsed rules for one struct field.
+type fieldPlan struct {
+	index     []int
+	name      string
+	required  bool
+	omitEmpty bool
+	rules     []fieldRule
+	err       error
+}
+
+// fieldRule checks a dereferenced field value. It returns the error message
+// and false when the value is invalid.
+type fieldRule func(v reflect.Value) (string, bool)
+
+// plans caches structPlan values by reflect.Type.
+var plans sync.Map
+
+// planFor returns the cached plan for a struct type, building it if needed.
+func planFor(t reflect.Type) *structPlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*structPlan)
+	}
+	p := buildPlan(t, nil)
+	actual, _ := plans.LoadOrStore(t, p)
+	return actual.(*structPlan)
+}
+
+// buildPlan parses the validate tags of a struct type. Embedded structs are
+// flattened into the parent plan.
+func buildPlan(t reflect.Type, parent []int) *structPlan {
+	p := &structPlan{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("validate") == "" {
+			p.fields = append(p.fields, buildPlan(field.Type, index).fields...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		fp := fieldPlan{
+			index: index,
+			name:  validationFieldName(field),
+		}
+		fp.err = fp.parse(tag, derefType(field.Type))
+		p.fields = append(p.fields, fp)
+	}
+
+	return p
+}
+
+// parse parses a validate tag into rules for a field of type t.
+func (fp *fieldPlan) parse(tag string, t reflect.Type) error {
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "pattern=") {
+			// Patterns may contain commas, so they consume the rest of the tag
+			rule, tag = tag, ""
+		} else if idx := ruleEnd(tag); idx >= 0 {
+			rule, tag = tag[:idx], tag[idx+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			fp.required = true
+		case "omitempty":
+			fp.omitEmpty = true
+		case "min", "max":
+			r, err := boundRule(name, arg, t)
+			if err != nil {
+				return err
+			}
+			fp.rules = append(fp.rules, r)
+		case "email":
+			fp.rules = append(fp.rules, stringRule(isEmail, "must be a valid email address"))
+		case "url":
+			fp.rules = append(fp.rules, stringRule(isURL, "must be a valid URL"))
+		case "uuid":
+			fp.rules = append(fp.rules, stringRule(isUUID, "must be a valid UUID"))
+		case "date":
+			fp.rules = append(fp.rules, stringRule(isDate, "must be a valid date (YYYY-MM-DD)"))
+		case "datetime":
+			fp.rules = append(fp.rules, stringRule(isDateTime, "must be a valid date and time"))
+		case "oneof":
+			options, err := splitOptions(arg)
+			if err != nil {
+				return err
+			}
+			fp.rules = append(fp.rules, oneOfRule(options))
+		case "pattern":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return fmt.Errorf("invalid validation pattern %q: %v", arg, err)
+			}
+			fp.rules = append(fp.rules, stringRule(re.MatchString, "format is invalid"))
+		default:
+			return fmt.Errorf("unknown validation rule %q", name)
+		}
+	}
+	return nil
+}
+
+// ruleEnd returns the index of the comma ending the first rule of tag, or
+// -1 if it is the last one. Commas within single quotes don't end a rule.
+func ruleEnd(tag string) int {
+	quoted := false
+	for i, c := range tag {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitOptions splits the options of a oneof rule at spaces. Single-quoted
+// options may contain spaces and commas, and two quotes stand for one.
+func splitOptions(arg string) ([]string, error) {
+	var options []string
+	for arg = strings.TrimSpace(arg); arg != ""; arg = strings.TrimSpace(arg) {
+		if arg[0] != '\'' {
+			end := strings.IndexFunc(arg, unicode.IsSpace)
+			if end < 0 {
+				end = len(arg)
+			}
+			options, arg = append(options, arg[:end]), arg[end:]
+			continue
+		}
+
+		var option strings.Builder
+		i := 1
+		for {
+			end := strings.IndexByte(arg[i:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in oneof options %q", arg)
+			}
+			option.WriteString(arg[i : i+end])
+			i += end + 1
+			if i < len(arg) && arg[i] == '\'' {
+				option.WriteByte('\'')
+				i++
+				continue
+			}
+			break
+		}
+		options, arg = append(options, option.String()), arg[i:]
+	}
+	return options, nil
+}
+
+// boundRule builds a min or max rule. Strings, slices and maps are bounded by
+// length; numbers by value.
+func boundRule(name, arg string, t reflect.Type) (fieldRule, error) {
+	isMin := name == "min"
+
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", name, arg)
+		}
+		unit := "items"
+		if t.Kind() == reflect.String {
+			unit = "characters"
+		}
+		return func(v reflect.Value) (string, bool) {
+			if isMin && v.Len() < n {
+				return fmt.Sprintf("must be at least %d %s", n, unit), false
+			}
+			if !isMin && v.Len() > n {
+				return fmt.Sprintf("must be at most %d %s", n, unit), false
+			}
+			return "", true
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", name, arg)
+		}
+		return func(v reflect.Value) (string, bool) {
+			f := numericValue(v)
+			if isMin && f < n {
+				return fmt.Sprintf("must be at least %s", arg), false
+			}
+			if !isMin && f > n {
+				return fmt.Sprintf("must be at most %s", arg), false
+			}
+			return "", true
+		}, nil
+	}
+	return nil, fmt.Errorf("%s is not supported for %s fields", name, t.Kind())
+}
+
+// stringRule builds a rule that checks non-empty string values.
+func stringRule(check func(string) bool, message string) fieldRule {
+	return func(v reflect.Value) (string, bool) {
+		if v.Kind() != reflect.String || v.String() == "" {
+			return "", true
+		}
+		if !check(v.String()) {
+			return message, false
+		}
+		return "", true
+	}
+}
+
+// oneOfRule builds a rule that checks a value is one of the options. Empty
+// strings and zero numbers are left to "required".
+func oneOfRule(options []string) fieldRule {
+	allowed := make(map[string]bool, len(options))
+	for _, o := range options {
+		allowed[o] = true
+	}
+	message := "must be one of: " + strings.Join(options, ", ")
+
+	return func(v reflect.Value) (string, bool) {
+		if v.IsZero() {
+			return "", true
+		}
+		if !allowed[fmt.Sprint(v.Interface())] {
+			return message, false
+		}
+		return "", true
+	}
+}
+
+// numericValue returns a numeric value as float64.
+func numericValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// isEmptyValue reports whether a value counts as missing for "required".
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// derefType returns the element type of pointer types.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// validationFieldName returns the error key for a field: its form tag, then
+// its json tag, then its Go name.
+func validationFieldName(field reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
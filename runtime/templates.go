@@ -1,11 +1,20 @@
 package runtime
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"html/template"
 	"io"
-	"path/filepath"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -13,59 +22,303 @@ import (
 
 var titleCaser = cases.Title(language.English)
 
-// TemplateEngine handles template rendering.
+// DefaultLayout is the layout of views rendered without one.
+const DefaultLayout = "application"
+
+// TemplateEngine renders views inside layouts. It is safe for concurrent
+// use.
+//
+// Views are named after their path in the views file system, such as
+// "posts/index.html", and layouts live in layouts/. Files whose name
+// starts with an underscore are partials, available to every view by path,
+// and those in shared/ also by file name, such as "_flash.html". Each view
+// is compiled with the partials into one template set per layout when the
+// templates are loaded, so views never clash over template names.
+//
+// The layout shows the view with {{.Content}}. A view may instead put its
+// content in {{define "content"}}, and may fill the blocks of the layout,
+// such as {{block "title" .}}, with a define of the same name. Errors name
+// the file and line of the failing template.
 type TemplateEngine struct {
-	templates *template.Template
-	funcMap   template.FuncMap
+	// DefaultLayout is the layout of views rendered without one. Views are
+	// rendered alone if it is missing.
+	DefaultLayout string
+	// Dev reloads the templates before a render if a file was added,
+	// removed or modified since they were loaded. It needs a file system
+	// reporting modification times, such as os.DirFS, unlike embed.FS.
+	Dev bool
+
+	funcMap template.FuncMap
+
+	mu       sync.Mutex
+	fsys     fs.FS
+	compiled *compiledViews
+}
+
+// compiledViews holds the template sets of the loaded views.
+type compiledViews struct {
+	// views holds the sets of each view by name
+	views map[string]*viewSets
+	// layouts holds the names of the layout files
+	layouts map[string]bool
+	// modTimes holds the modification time of each file
+	modTimes map[string]time.Time
+}
+
+// viewSets holds the template sets of a view.
+type viewSets struct {
+	// content is the template showing the view: the file, or the
+	// "content" template it defines
+	content string
+	// sets holds the set of the view inside each layout file, and alone
+	// under the empty name
+	sets map[string]*template.Template
 }
 
 // NewTemplateEngine creates a new template engine.
 func NewTemplateEngine() *TemplateEngine {
 	return &TemplateEngine{
-		funcMap: DefaultFuncMap(),
+		DefaultLayout: DefaultLayout,
+		funcMap:       DefaultFuncMap(),
 	}
 }
 
-// LoadTemplates loads templates from a directory.
+// LoadTemplates loads templates from a directory, see LoadFS.
 func (e *TemplateEngine) LoadTemplates(viewsPath string) error {
-	// Create template with function map
-	tmpl := template.New("").Funcs(e.funcMap)
+	return e.LoadFS(os.DirFS(viewsPath))
+}
 
-	// Load all templates
-	patterns := []string{
-		filepath.Join(viewsPath, "*.html"),
-		filepath.Join(viewsPath, "*/*.html"),
-		filepath.Join(viewsPath, "*/*/*.html"),
+// LoadFS loads the templates of fsys, such as an embed.FS of the views
+// directory, and compiles the template set of every view. It reports the
+// first template that does not parse.
+func (e *TemplateEngine) LoadFS(fsys fs.FS) error {
+	compiled, err := e.compile(fsys)
+	if err != nil {
+		return err
 	}
 
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(pattern)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fsys = fsys
+	e.compiled = compiled
+	return nil
+}
+
+// compile parses the templates of fsys.
+func (e *TemplateEngine) compile(fsys fs.FS) (*compiledViews, error) {
+	modTimes, err := templateFiles(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	// Partials first, as every other template may use them
+	partials := template.New("").Funcs(e.funcMap)
+	sources := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(modTimes)) {
+		b, err := fs.ReadFile(fsys, name)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to load templates: %w", err)
+		}
+		if !strings.HasPrefix(path.Base(name), "_") {
+			sources[name] = string(b)
+			continue
+		}
+		partial, err := partials.New(name).Parse(string(b))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(name, "shared/") {
+			// Share the tree so that errors still name the file
+			if _, err := partials.AddParseTree(path.Base(name), partial.Tree); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Then each layout, cloned for each of its views
+	layouts := map[string]*template.Template{"": partials}
+	for name, src := range sources {
+		if !strings.HasPrefix(name, "layouts/") {
+			continue
+		}
+		set, err := partials.Clone()
+		if err != nil {
+			return nil, err
 		}
+		if _, err := set.New(name).Parse(src); err != nil {
+			return nil, err
+		}
+		layouts[name] = set
+	}
 
-		for _, file := range matches {
-			// Get relative path for template name
-			name, err := filepath.Rel(viewsPath, file)
+	// The view is parsed last so that its defines replace the blocks of
+	// the layout
+	compiled := &compiledViews{
+		views:    make(map[string]*viewSets),
+		layouts:  make(map[string]bool),
+		modTimes: modTimes,
+	}
+	for name, src := range sources {
+		if strings.HasPrefix(name, "layouts/") {
+			compiled.layouts[name] = true
+			continue
+		}
+		view := &viewSets{content: name, sets: make(map[string]*template.Template, len(layouts))}
+		for layout, base := range layouts {
+			set, err := base.Clone()
 			if err != nil {
-				return err
+				return nil, err
 			}
-
-			// Read and parse template
-			_, err = tmpl.New(name).ParseFiles(file)
+			parsed, err := set.New(name).Parse(src)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if t := parsed.Lookup("content"); t != nil && t.Tree != nil && t.Tree.ParseName == name {
+				view.content = "content"
 			}
+			view.sets[layout] = set
 		}
+		compiled.views[name] = view
 	}
+	return compiled, nil
+}
 
-	e.templates = tmpl
-	return nil
+// templateFiles returns the modification times of the templates of fsys.
+func templateFiles(fsys fs.FS) (map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".html" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[name] = info.ModTime()
+		return nil
+	})
+	return files, err
 }
 
-// Render renders a template with data.
+// views returns the compiled views, reloading them first in dev mode if
+// the files changed.
+func (e *TemplateEngine) views() (*compiledViews, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.compiled == nil {
+		return nil, fmt.Errorf("templates not loaded")
+	}
+	if !e.Dev {
+		return e.compiled, nil
+	}
+
+	modTimes, err := templateFiles(e.fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+	if !maps.EqualFunc(modTimes, e.compiled.modTimes, time.Time.Equal) {
+		compiled, err := e.compile(e.fsys)
+		if err != nil {
+			return nil, err
+		}
+		e.compiled = compiled
+	}
+	return e.compiled, nil
+}
+
+// Render renders a view with data, without a layout.
 func (e *TemplateEngine) Render(w io.Writer, name string, data any) error {
-	return e.templates.ExecuteTemplate(w, name, data)
+	return e.RenderLayout(w, "", name, data)
+}
+
+// RenderLayout renders a view inside a layout, such as "admin" for
+// layouts/admin.html. An empty layout renders the view alone. Unless data is
+// a map[string]any, the layout gets it as .Data.
+func (e *TemplateEngine) RenderLayout(w io.Writer, layout, view string, data any) error {
+	compiled, err := e.views()
+	if err != nil {
+		return err
+	}
+	return compiled.render(w, layout, view, data)
+}
+
+// RenderRequest renders a view inside the layout of the request: the one
+// set by WithLayout or the route, else DefaultLayout if it exists.
+func (e *TemplateEngine) RenderRequest(w io.Writer, r *http.Request, view string, data any) error {
+	compiled, err := e.views()
+	if err != nil {
+		return err
+	}
+	layout := LayoutFrom(r.Context())
+	if layout == "" && e.DefaultLayout != "" && compiled.layouts[layoutFile(e.DefaultLayout)] {
+		layout = e.DefaultLayout
+	}
+	return compiled.render(w, layout, view, data)
+}
+
+// render renders a view inside a layout.
+func (c *compiledViews) render(w io.Writer, layout, view string, data any) error {
+	sets, ok := c.views[templateName(view)]
+	if !ok {
+		return fmt.Errorf("view %s not found", templateName(view))
+	}
+	if layout == "" {
+		return sets.sets[""].ExecuteTemplate(w, sets.content, data)
+	}
+	set, ok := sets.sets[layoutFile(layout)]
+	if !ok {
+		return fmt.Errorf("layout %s not found", layoutFile(layout))
+	}
+
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, sets.content, data); err != nil {
+		return err
+	}
+	layoutData := map[string]any{"Data": data}
+	if m, ok := data.(map[string]any); ok {
+		layoutData = maps.Clone(m)
+	}
+	layoutData["Content"] = template.HTML(buf.String())
+	return set.ExecuteTemplate(w, layoutFile(layout), layoutData)
+}
+
+// layoutFile returns the file of a layout.
+func layoutFile(layout string) string {
+	return "layouts/" + templateName(layout)
+}
+
+// templateName adds the .html extension to name if it has none.
+func templateName(name string) string {
+	if !strings.HasSuffix(name, ".html") {
+		name += ".html"
+	}
+	return name
+}
+
+// layoutKey is the context key of the layout of a request.
+type layoutKey struct{}
+
+// UseLayout wraps the handler of a route with its layout. The generated
+// router uses it for resources and pages with a Layout.
+func UseLayout(layout string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, WithLayout(r, layout))
+	}
+}
+
+// WithLayout returns a copy of r whose views render inside layout, such as
+// a print layout chosen by a query parameter. It overrides the layout of
+// the route.
+func WithLayout(r *http.Request, layout string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), layoutKey{}, layout))
+}
+
+// LayoutFrom returns the layout set by UseLayout or WithLayout, or an
+// empty string for the default layout.
+func LayoutFrom(ctx context.Context) string {
+	layout, _ := ctx.Value(layoutKey{}).(string)
+	return layout
 }
 
 // DefaultFuncMap returns the default template functions.
@@ -84,8 +337,10 @@ func DefaultFuncMap() template.FuncMap {
 
 		// Form helpers
 		"form_for":   formFor,
+		"button_to":  buttonTo,
 		"text_field": textField,
 		"submit":     submitButton,
+		"csrf_field": CSRFField,
 
 		// Formatting
 		"truncate":  truncate,
@@ -113,16 +368,43 @@ func pathFor(resource string, args ...any) string {
 }
 
 func formFor(resource string, args ...any) template.HTML {
-	method := "POST"
 	action := "/" + resource
+	if len(args) == 0 {
+		return template.HTML(`<form method="POST" action="` + template.HTMLEscapeString(action) + `">`)
+	}
+
+	// Edit forms update the record through the _method field
+	action = fmt.Sprintf("/%s/%v", resource, args[0])
+	return template.HTML(`<form method="POST" action="` + template.HTMLEscapeString(action) + `">` +
+		methodField(http.MethodPatch))
+}
+
+// buttonTo renders a single-button form sending method to path, with the
+// CSRF token if any. attrs are added to the button, as with text_field.
+func buttonTo(text, path, method, csrfToken string, attrs ...string) template.HTML {
+	method = strings.ToUpper(method)
+	formMethod := http.MethodPost
+	if method == http.MethodGet {
+		formMethod = http.MethodGet
+	}
 
-	if len(args) > 0 {
-		// Assume it's an edit form
-		action = fmt.Sprintf("/%s/%v", resource, args[0])
-		method = "POST" // Will add _method=PUT hidden field
+	var b strings.Builder
+	b.WriteString(`<form method="` + formMethod + `" action="` + template.HTMLEscapeString(path) + `" class="button_to">`)
+	if method != formMethod {
+		b.WriteString(methodField(method))
 	}
+	b.WriteString(string(CSRFField(csrfToken)))
+	b.WriteString(`<button type="submit"`)
+	for _, attr := range attrs {
+		b.WriteString(" " + attr)
+	}
+	b.WriteString(">" + template.HTMLEscapeString(text) + "</button></form>")
+	return template.HTML(b.String())
+}
 
-	return template.HTML(fmt.Sprintf(`<form method="%s" action="%s">`, method, action))
+// methodField renders the hidden _method field read by MethodOverride.
+func methodField(method string) string {
+	return `<input type="hidden" name="` + MethodOverrideField + `" value="` + template.HTMLEscapeString(method) + `">`
 }
 
 func textField(name, value string, attrs ...string) template.HTML {
@@ -1,17 +1,19 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 	"net/mail"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // ValidationError represents a validation error.
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error returns the error message.
@@ -36,6 +38,18 @@ func (v ValidationErrors) HasErrors() bool {
 	return len(v) > 0
 }
 
+// MissingValidatorError is returned by a generated Validate method when the
+// form declares custom validations but no implementation was registered.
+type MissingValidatorError struct {
+	// Name is the generated validator interface, e.g. "RegisterFormValidator".
+	Name string
+}
+
+// Error returns the error message.
+func (e *MissingValidatorError) Error() string {
+	return fmt.Sprintf("%s is not registered", e.Name)
+}
+
 // Validator provides validation functions.
 type Validator struct {
 	errors ValidationErrors
@@ -65,8 +79,7 @@ func (v *Validator) Email(field, value string) *Validator {
 		return v
 	}
 
-	_, err := mail.ParseAddress(value)
-	if err != nil {
+	if !isEmail(value) {
 		v.errors = append(v.errors, ValidationError{
 			Field:   field,
 			Message: "must be a valid email address",
@@ -81,8 +94,7 @@ func (v *Validator) URL(field, value string) *Validator {
 		return v
 	}
 
-	u, err := url.Parse(value)
-	if err != nil || u.Scheme == "" || u.Host == "" {
+	if !isURL(value) {
 		v.errors = append(v.errors, ValidationError{
 			Field:   field,
 			Message: "must be a valid URL",
@@ -129,6 +141,151 @@ func (v *Validator) Pattern(field, value, pattern string) *Validator {
 	return v
 }
 
+// Matches validates against a precompiled regular expression.
+func (v *Validator) Matches(field, value string, re *regexp.Regexp) *Validator {
+	if value == "" {
+		return v
+	}
+
+	if !re.MatchString(value) {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: "format is invalid",
+		})
+	}
+	return v
+}
+
+// OneOf validates that a value is one of the allowed options.
+func (v *Validator) OneOf(field, value string, options ...string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	for _, option := range options {
+		if value == option {
+			return v
+		}
+	}
+	v.errors = append(v.errors, ValidationError{
+		Field:   field,
+		Message: "must be one of: " + strings.Join(options, ", "),
+	})
+	return v
+}
+
+// Min validates a minimum numeric value.
+func (v *Validator) Min(field string, value, min float64) *Validator {
+	if value < min {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be at least %v", min),
+		})
+	}
+	return v
+}
+
+// Max validates a maximum numeric value.
+func (v *Validator) Max(field string, value, max float64) *Validator {
+	if value > max {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be at most %v", max),
+		})
+	}
+	return v
+}
+
+// Range validates that a numeric value is between min and max inclusive.
+func (v *Validator) Range(field string, value, min, max float64) *Validator {
+	if value < min || value > max {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be between %v and %v", min, max),
+		})
+	}
+	return v
+}
+
+// MinItems validates the minimum number of items in a list or map.
+func (v *Validator) MinItems(field string, count, min int) *Validator {
+	if count < min {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must have at least %d items", min),
+		})
+	}
+	return v
+}
+
+// MaxItems validates the maximum number of items in a list or map.
+func (v *Validator) MaxItems(field string, count, max int) *Validator {
+	if count > max {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must have at most %d items", max),
+		})
+	}
+	return v
+}
+
+// Date validates a calendar date (YYYY-MM-DD).
+func (v *Validator) Date(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	if !isDate(value) {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: "must be a valid date (YYYY-MM-DD)",
+		})
+	}
+	return v
+}
+
+// DateTime validates an RFC 3339 or datetime-local timestamp.
+func (v *Validator) DateTime(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	if !isDateTime(value) {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: "must be a valid date and time",
+		})
+	}
+	return v
+}
+
+// UUID validates a canonical hyphenated UUID.
+func (v *Validator) UUID(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+
+	if !isUUID(value) {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: "must be a valid UUID",
+		})
+	}
+	return v
+}
+
+// Check records message for field when ok is false. It covers rules that
+// have no dedicated method, such as required non-string fields.
+func (v *Validator) Check(field string, ok bool, message string) *Validator {
+	if !ok {
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: message,
+		})
+	}
+	return v
+}
+
 // Match validates that two fields match (e.g., password confirmation).
 func (v *Validator) Match(field1, value1, field2, value2 string) *Validator {
 	if value1 != value2 {
@@ -140,6 +297,38 @@ func (v *Validator) Match(field1, value1, field2, value2 string) *Validator {
 	return v
 }
 
+// Custom records the result of a custom validation hook for field. A nil
+// error is ignored, ValidationErrors and ValidationError values are kept as
+// they are, and any other error becomes a message on field.
+func (v *Validator) Custom(field string, err error) *Validator {
+	if err == nil {
+		return v
+	}
+
+	var verrs ValidationErrors
+	var verr ValidationError
+	switch {
+	case errors.As(err, &verrs):
+		for _, e := range verrs {
+			if e.Field == "" {
+				e.Field = field
+			}
+			v.errors = append(v.errors, e)
+		}
+	case errors.As(err, &verr):
+		if verr.Field == "" {
+			verr.Field = field
+		}
+		v.errors = append(v.errors, verr)
+	default:
+		v.errors = append(v.errors, ValidationError{
+			Field:   field,
+			Message: err.Error(),
+		})
+	}
+	return v
+}
+
 // Errors returns the validation errors.
 func (v *Validator) Errors() ValidationErrors {
 	return v.errors
@@ -150,11 +339,39 @@ func (v *Validator) Valid() bool {
 	return len(v.errors) == 0
 }
 
-// ValidateStruct validates a struct based on its tags.
-// This is a simple implementation - production apps should use
-// a proper validation library like go-playground/validator.
-func ValidateStruct(s any) ValidationErrors {
-	// TODO: Implement struct tag-based validation
-	// For now, return empty errors
-	return ValidationErrors{}
+// isEmail returns true if value is a valid email address.
+func isEmail(value string) bool {
+	_, err := mail.ParseAddress(value)
+	return err == nil
+}
+
+// isURL returns true if value is an absolute URL with a scheme and host.
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// uuidPattern matches canonical hyphenated UUIDs.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isUUID returns true if value is a canonical hyphenated UUID.
+func isUUID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+// isDate returns true if value is a calendar date (YYYY-MM-DD).
+func isDate(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
+
+// isDateTime returns true if value is an RFC 3339 timestamp or the
+// "YYYY-MM-DDTHH:MM" value submitted by datetime-local inputs.
+func isDateTime(value string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
 }